@@ -0,0 +1,17 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+func printConfig(ctx context.Context, app *app, args []string) error {
+	if len(args) != 1 || args[0] != "print" {
+		return errUsage
+	}
+
+	for _, line := range app.config.Redacted() {
+		fmt.Println(line)
+	}
+	return nil
+}
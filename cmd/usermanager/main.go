@@ -1,31 +1,175 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"git.foxminded.com.ua/3_REST_API/interal/config"
 	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/datastore"
-	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/router"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/logging"
 	"git.foxminded.com.ua/3_REST_API/interal/registry"
-	"github.com/labstack/echo/v4"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"gorm.io/gorm"
 )
 
+// errUsage makes main print the usage of the command.
+var errUsage = errors.New("wrong arguments")
+
+type command struct {
+	usage   string
+	summary string
+	run     func(ctx context.Context, app *app, args []string) error
+}
+
+var commands = map[string]command{
+	"serve":               {"serve", "start the API server (the default command)", serve},
+	"migrate":             {"migrate up|down|status", "manage the database schema", migrate},
+	"create-admin":        {"create-admin -username <name> -first-name <name> -last-name <name> [-password-stdin]", "create an admin user", createAdmin},
+	"reset-password":      {"reset-password [-password-stdin] <username>", "set a new password and sign the user out everywhere", resetPassword},
+	"list-users":          {"list-users [-page <n>] [-limit <n>]", "list users", listUsers},
+	"revoke-sessions":     {"revoke-sessions <username>", "sign the user out everywhere", revokeSessions},
+	"export-users":        {"export-users", "write every user as JSON to stdout", exportUsers},
+	"purge-deleted-users": {"purge-deleted-users [-days <n>]", "delete for good the users deleted more than DELETED_USER_RETENTION_DAYS (or -days) days ago", purgeDeletedUsers},
+	"config":              {"config print", "print the configuration with secrets redacted", printConfig},
+}
+
+// usermanager runs the command of the first argument, "serve" without one.
+// Commands which change users go through the same interactor as the API, and
+// their audit events carry the "cli:<command>" request ID.
 func main() {
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
 	config, err := config.InitConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	db, err := datastore.NewDB(config)
+	logs, err := logging.New(config, os.Stderr)
 	if err != nil {
 		log.Fatal(err)
 	}
+	// the standard log package writes through the app logger as well
+	logger := logs.Logger(logging.ComponentApp)
+	slog.SetDefault(logger)
 
-	r := registry.NewRegistry(db, config)
+	requestID := "cli:" + name
+	ctx := interactor.WithAuditActor(logging.WithRequestID(context.Background(), requestID), interactor.AuditActor{RequestID: requestID})
+	a := &app{config: config, logging: logs}
+	err = cmd.run(ctx, a, args)
+	if closeErr := a.close(); closeErr != nil {
+		logger.ErrorContext(ctx, "can't close the app", "error", closeErr.Error())
+	}
+	if err != nil {
+		if errors.Is(err, errUsage) {
+			fmt.Fprintln(os.Stderr, "usage: usermanager "+cmd.usage)
+			os.Exit(2)
+		}
+		logger.ErrorContext(ctx, "command failed", "command", name, "error", err.Error())
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	e := echo.New()
-	e = router.NewRouter(e, config, r.NewAppController())
+	lines := []string{"usage: usermanager <command>", "", "commands:"}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %s\n        %s", commands[name].usage, commands[name].summary))
+	}
+	fmt.Fprintln(os.Stderr, strings.Join(lines, "\n"))
+}
 
-	log.Println("Server listen at http://localhost" + ":" + config.Port)
-	log.Fatalln(e.Start(":" + config.Port))
+// app opens the database and builds the registry only for the commands
+// which need them.
+type app struct {
+	config   *config.Config
+	logging  *logging.Logging
+	db       *gorm.DB
+	registry registry.Registry
+}
+
+func (a *app) openDB() (*gorm.DB, error) {
+	if a.db != nil {
+		return a.db, nil
+	}
+	db, err := datastore.NewDB(a.config, logging.NewGormLogger(a.logging.Logger(logging.ComponentGORM)))
+	if err != nil {
+		return nil, err
+	}
+	a.db = db
+	return db, nil
+}
+
+// close exports the buffered spans and releases the connection pool, if the
+// command built the registry and opened one.
+func (a *app) close() error {
+	if a.registry != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(a.config.ShutdownTimeout))
+		defer cancel()
+		if err := a.registry.Tracing().Shutdown(ctx); err != nil {
+			slog.ErrorContext(ctx, "can't export the spans", "error", err.Error())
+		}
+	}
+	if a.db == nil {
+		return nil
+	}
+	sqlDB, err := a.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// newRegistry refuses to work with a schema which is behind, as the server
+// does.
+func (a *app) newRegistry(ctx context.Context) (registry.Registry, error) {
+	if a.registry != nil {
+		return a.registry, nil
+	}
+	db, err := a.openDB()
+	if err != nil {
+		return nil, err
+	}
+
+	migrator, err := datastore.NewMigrator(db)
+	if err != nil {
+		return nil, err
+	}
+	if err := migrator.CheckUpToDate(ctx); err != nil {
+		return nil, err
+	}
+
+	r, err := registry.NewRegistry(db, a.config)
+	if err != nil {
+		return nil, err
+	}
+	a.registry = r
+	return r, nil
+}
+
+func (a *app) userInteractor(ctx context.Context) (interactor.UserInteractor, error) {
+	r, err := a.newRegistry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.NewUserInteractor(), nil
 }
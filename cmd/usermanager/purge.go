@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/logging"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+)
+
+const (
+	purgeJobID       = "job:purge-deleted-users"
+	purgeJobInterval = time.Hour
+)
+
+// purgeDeletedUsers purges the users deleted more than -days days ago,
+// DELETED_USER_RETENTION_DAYS by default.
+func purgeDeletedUsers(ctx context.Context, app *app, args []string) error {
+	flags := flag.NewFlagSet("purge-deleted-users", flag.ContinueOnError)
+	days := flags.Int("days", app.config.DeletedUserDays, "purge the users deleted more than this many days ago")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 || *days < 1 {
+		return errUsage
+	}
+
+	userInteractor, err := app.userInteractor(ctx)
+	if err != nil {
+		return err
+	}
+	purged, err := userInteractor.PurgeDeletedSigners(ctx, retentionCutoff(*days))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("purged %d users deleted more than %d days ago\n", purged, *days)
+	return nil
+}
+
+// runRetentionJob purges the users deleted more than days days ago right
+// away and then every purgeJobInterval, until ctx is done. A failed run is
+// logged and retried on the next tick.
+func runRetentionJob(ctx context.Context, userInteractor interactor.UserInteractor, days int, logger *slog.Logger) {
+	ctx = interactor.WithAuditActor(logging.WithRequestID(ctx, purgeJobID), interactor.AuditActor{RequestID: purgeJobID})
+	ticker := time.NewTicker(purgeJobInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := userInteractor.PurgeDeletedSigners(ctx, retentionCutoff(days))
+		switch {
+		case err != nil:
+			logger.ErrorContext(ctx, "can't purge deleted users", "purged", purged, "error", err.Error())
+		case purged > 0:
+			logger.InfoContext(ctx, "purged deleted users", "purged", purged, "retention_days", days)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func retentionCutoff(days int) time.Time {
+	return time.Now().AddDate(0, 0, -days)
+}
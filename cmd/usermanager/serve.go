@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/logging"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/router"
+	"github.com/labstack/echo/v4"
+)
+
+// serve runs until SIGINT or SIGTERM, then stops accepting connections and
+// waits up to SHUTDOWN_TIMEOUT for the requests in flight. With
+// DELETED_USER_PURGE_JOB and DELETED_USER_RETENTION_DAYS it purges the users
+// deleted before then every hour.
+func serve(ctx context.Context, app *app, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	r, err := app.newRegistry(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := r.BootstrapAdmin(ctx); err != nil {
+		return err
+	}
+
+	logger := app.logging.Logger(logging.ComponentHTTP)
+	e := echo.New()
+	e.HideBanner = true
+	e.HidePort = true
+	e, err = router.NewRouter(e, app.config, r.NewAppController(), r.Metrics(), r.Tracing(), logger)
+	if err != nil {
+		return err
+	}
+	e.Server.ReadHeaderTimeout = time.Second * time.Duration(app.config.ReadHeaderTimeout)
+	e.Server.ReadTimeout = time.Second * time.Duration(app.config.ReadTimeout)
+	e.Server.WriteTimeout = time.Second * time.Duration(app.config.WriteTimeout)
+	e.Server.IdleTimeout = time.Second * time.Duration(app.config.IdleTimeout)
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if app.config.DeletedUserPurge && app.config.DeletedUserDays > 0 {
+		go runRetentionJob(ctx, r.NewUserInteractor(), app.config.DeletedUserDays, app.logging.Logger(logging.ComponentApp))
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		logger.InfoContext(ctx, "server listens", "address", "http://localhost:"+app.config.Port)
+		serveErr <- e.Start(":" + app.config.Port)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	stop()
+
+	logger.InfoContext(ctx, "shutting down the server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(app.config.ShutdownTimeout))
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/mappers"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
+	v "git.foxminded.com.ua/3_REST_API/interal/validator"
+)
+
+const (
+	passwordAlphabet = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789!#%+-=?@_"
+	passwordLength   = 16
+	exportPageSize   = 100
+)
+
+func createAdmin(ctx context.Context, app *app, args []string) error {
+	flags := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the admin")
+	firstName := flags.String("first-name", "", "first name of the admin")
+	lastName := flags.String("last-name", "", "last name of the admin")
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 {
+		return errUsage
+	}
+
+	password, generated, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+
+	signUp := &requests.SignUpRequest{UserName: *username, FirstName: *firstName, LastName: *lastName, Password: password}
+	if err := validate(signUp); err != nil {
+		return err
+	}
+
+	userInteractor, err := app.userInteractor(ctx)
+	if err != nil {
+		return err
+	}
+	admin, err := userInteractor.CreateAdmin(ctx, mappers.MapSignUpRequestToUser(signUp))
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created admin %s with ID %d\n", admin.UserName, admin.ID)
+	printGeneratedPassword(password, generated)
+	return nil
+}
+
+func resetPassword(ctx context.Context, app *app, args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	passwordStdin := flags.Bool("password-stdin", false, "read the password from stdin instead of generating one")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 1 {
+		return errUsage
+	}
+
+	password, generated, err := readPassword(*passwordStdin)
+	if err != nil {
+		return err
+	}
+	if err := validate(&requests.ResetPasswordRequest{Password: password}); err != nil {
+		return err
+	}
+
+	userInteractor, err := app.userInteractor(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := userInteractor.ResetPassword(ctx, flags.Arg(0), password)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("reset the password of %s and revoked the sessions\n", user.UserName)
+	printGeneratedPassword(password, generated)
+	return nil
+}
+
+func listUsers(ctx context.Context, app *app, args []string) error {
+	flags := flag.NewFlagSet("list-users", flag.ContinueOnError)
+	page := flags.Int("page", 1, "page to show")
+	limit := flags.Int("limit", 20, "users per page")
+	if err := flags.Parse(args); err != nil || flags.NArg() != 0 || *page < 1 || *limit < 1 {
+		return errUsage
+	}
+
+	userInteractor, err := app.userInteractor(ctx)
+	if err != nil {
+		return err
+	}
+	pagination, users, err := userInteractor.FindSigners(ctx, &models.UserFilter{}, &models.Pagination{Page: *page, Limit: *limit, Sort: "id"})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tROLE\tRATING\tFIRST NAME\tLAST NAME\tCREATED AT")
+	for _, user := range users {
+		createdAt := ""
+		if user.CreatedAt != nil {
+			createdAt = user.CreatedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\n", user.ID, user.UserName, user.Role, user.Rating, user.FirstName, user.LastName, createdAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Printf("page %d of %d, %d users\n", pagination.Page, pagination.TotalPages, pagination.TotalRows)
+	return nil
+}
+
+func revokeSessions(ctx context.Context, app *app, args []string) error {
+	if len(args) != 1 {
+		return errUsage
+	}
+
+	userInteractor, err := app.userInteractor(ctx)
+	if err != nil {
+		return err
+	}
+	user, err := userInteractor.FindOneSignerByUserName(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if err := userInteractor.RevokeUserSessions(ctx, user.ID); err != nil {
+		return err
+	}
+
+	fmt.Printf("revoked the sessions of %s\n", user.UserName)
+	return nil
+}
+
+// exportUsers writes a JSON array of users in the shape the API returns
+// them, without password hashes.
+func exportUsers(ctx context.Context, app *app, args []string) error {
+	if len(args) != 0 {
+		return errUsage
+	}
+
+	userInteractor, err := app.userInteractor(ctx)
+	if err != nil {
+		return err
+	}
+
+	export := []*requests.UserResponse{}
+	for page := 1; ; page++ {
+		pagination, users, err := userInteractor.FindSigners(ctx, &models.UserFilter{}, &models.Pagination{Page: page, Limit: exportPageSize, Sort: "id"})
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			export = append(export, mappers.MapUserToUserResponse(user))
+		}
+		if page >= pagination.TotalPages {
+			break
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(export)
+}
+
+func validate(i interface{}) error {
+	validator, err := v.NewCustomValidator(permissions.DefaultRoles())
+	if err != nil {
+		return err
+	}
+	return validator.Validate(i)
+}
+
+// readPassword reads the first line of stdin, or generates a password which
+// passes the password rule when fromStdin isn't set.
+func readPassword(fromStdin bool) (password string, generated bool, err error) {
+	if !fromStdin {
+		password, err = generatePassword()
+		return password, true, err
+	}
+
+	password, err = bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", false, err
+	}
+	return strings.TrimRight(password, "\r\n"), false, nil
+}
+
+func generatePassword() (string, error) {
+	alphabetSize := big.NewInt(int64(len(passwordAlphabet)))
+	for {
+		password := make([]byte, passwordLength)
+		for i := range password {
+			n, err := rand.Int(rand.Reader, alphabetSize)
+			if err != nil {
+				return "", err
+			}
+			password[i] = passwordAlphabet[n.Int64()]
+		}
+
+		if validate(&requests.ResetPasswordRequest{Password: string(password)}) == nil {
+			return string(password), nil
+		}
+	}
+}
+
+func printGeneratedPassword(password string, generated bool) {
+	if generated {
+		fmt.Printf("generated password: %s\n", password)
+	}
+}
@@ -7,6 +7,7 @@ package mocks
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	models "git.foxminded.com.ua/3_REST_API/interal/domain/models"
 	gomock "github.com/golang/mock/gomock"
@@ -35,6 +36,21 @@ func (m *MockUserRepository) EXPECT() *MockUserRepositoryMockRecorder {
 	return m.recorder
 }
 
+// CountUsersByRole mocks base method.
+func (m *MockUserRepository) CountUsersByRole(arg0 context.Context, arg1 string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsersByRole", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsersByRole indicates an expected call of CountUsersByRole.
+func (mr *MockUserRepositoryMockRecorder) CountUsersByRole(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsersByRole", reflect.TypeOf((*MockUserRepository)(nil).CountUsersByRole), arg0, arg1)
+}
+
 // CreateUser mocks base method.
 func (m *MockUserRepository) CreateUser(arg0 context.Context, arg1 *models.User) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -78,6 +94,22 @@ func (mr *MockUserRepositoryMockRecorder) DeleteUserByID(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserByID", reflect.TypeOf((*MockUserRepository)(nil).DeleteUserByID), arg0, arg1)
 }
 
+// FindDeletedUsers mocks base method.
+func (m *MockUserRepository) FindDeletedUsers(arg0 context.Context, arg1 *models.Pagination) (*models.Pagination, []*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindDeletedUsers", arg0, arg1)
+	ret0, _ := ret[0].(*models.Pagination)
+	ret1, _ := ret[1].([]*models.User)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// FindDeletedUsers indicates an expected call of FindDeletedUsers.
+func (mr *MockUserRepositoryMockRecorder) FindDeletedUsers(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindDeletedUsers", reflect.TypeOf((*MockUserRepository)(nil).FindDeletedUsers), arg0, arg1)
+}
+
 // FindOneUserByID mocks base method.
 func (m *MockUserRepository) FindOneUserByID(arg0 context.Context, arg1 uint) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -93,25 +125,40 @@ func (mr *MockUserRepositoryMockRecorder) FindOneUserByID(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOneUserByID", reflect.TypeOf((*MockUserRepository)(nil).FindOneUserByID), arg0, arg1)
 }
 
-// FindOneUserByUserNameAndPassword mocks base method.
-func (m *MockUserRepository) FindOneUserByUserNameAndPassword(arg0 context.Context, arg1, arg2 string) (*models.User, error) {
+// FindOneUserByUserName mocks base method.
+func (m *MockUserRepository) FindOneUserByUserName(arg0 context.Context, arg1 string) (*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindOneUserByUserNameAndPassword", arg0, arg1, arg2)
+	ret := m.ctrl.Call(m, "FindOneUserByUserName", arg0, arg1)
 	ret0, _ := ret[0].(*models.User)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// FindOneUserByUserNameAndPassword indicates an expected call of FindOneUserByUserNameAndPassword.
-func (mr *MockUserRepositoryMockRecorder) FindOneUserByUserNameAndPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
+// FindOneUserByUserName indicates an expected call of FindOneUserByUserName.
+func (mr *MockUserRepositoryMockRecorder) FindOneUserByUserName(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOneUserByUserNameAndPassword", reflect.TypeOf((*MockUserRepository)(nil).FindOneUserByUserNameAndPassword), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOneUserByUserName", reflect.TypeOf((*MockUserRepository)(nil).FindOneUserByUserName), arg0, arg1)
+}
+
+// FindUserIDsDeletedBefore mocks base method.
+func (m *MockUserRepository) FindUserIDsDeletedBefore(arg0 context.Context, arg1 time.Time, arg2 int) ([]uint, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserIDsDeletedBefore", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]uint)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserIDsDeletedBefore indicates an expected call of FindUserIDsDeletedBefore.
+func (mr *MockUserRepositoryMockRecorder) FindUserIDsDeletedBefore(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserIDsDeletedBefore", reflect.TypeOf((*MockUserRepository)(nil).FindUserIDsDeletedBefore), arg0, arg1, arg2)
 }
 
 // FindUsers mocks base method.
-func (m *MockUserRepository) FindUsers(arg0 context.Context, arg1 *models.Pagination) (*models.Pagination, []*models.User, error) {
+func (m *MockUserRepository) FindUsers(arg0 context.Context, arg1 *models.UserFilter, arg2 *models.Pagination) (*models.Pagination, []*models.User, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "FindUsers", arg0, arg1)
+	ret := m.ctrl.Call(m, "FindUsers", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*models.Pagination)
 	ret1, _ := ret[1].([]*models.User)
 	ret2, _ := ret[2].(error)
@@ -119,9 +166,23 @@ func (m *MockUserRepository) FindUsers(arg0 context.Context, arg1 *models.Pagina
 }
 
 // FindUsers indicates an expected call of FindUsers.
-func (mr *MockUserRepositoryMockRecorder) FindUsers(arg0, arg1 interface{}) *gomock.Call {
+func (mr *MockUserRepositoryMockRecorder) FindUsers(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUsers", reflect.TypeOf((*MockUserRepository)(nil).FindUsers), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUsers", reflect.TypeOf((*MockUserRepository)(nil).FindUsers), arg0, arg1, arg2)
+}
+
+// PurgeUser mocks base method.
+func (m *MockUserRepository) PurgeUser(arg0 context.Context, arg1 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PurgeUser", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// PurgeUser indicates an expected call of PurgeUser.
+func (mr *MockUserRepositoryMockRecorder) PurgeUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PurgeUser", reflect.TypeOf((*MockUserRepository)(nil).PurgeUser), arg0, arg1)
 }
 
 // RateUserByUsername mocks base method.
@@ -139,6 +200,51 @@ func (mr *MockUserRepositoryMockRecorder) RateUserByUsername(arg0, arg1, arg2, a
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RateUserByUsername", reflect.TypeOf((*MockUserRepository)(nil).RateUserByUsername), arg0, arg1, arg2, arg3)
 }
 
+// ReleaseUserName mocks base method.
+func (m *MockUserRepository) ReleaseUserName(arg0 context.Context, arg1 uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseUserName", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseUserName indicates an expected call of ReleaseUserName.
+func (mr *MockUserRepositoryMockRecorder) ReleaseUserName(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseUserName", reflect.TypeOf((*MockUserRepository)(nil).ReleaseUserName), arg0, arg1)
+}
+
+// RestoreUser mocks base method.
+func (m *MockUserRepository) RestoreUser(arg0 context.Context, arg1 uint) (*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreUser", arg0, arg1)
+	ret0, _ := ret[0].(*models.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RestoreUser indicates an expected call of RestoreUser.
+func (mr *MockUserRepositoryMockRecorder) RestoreUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreUser", reflect.TypeOf((*MockUserRepository)(nil).RestoreUser), arg0, arg1)
+}
+
+// SearchUsers mocks base method.
+func (m *MockUserRepository) SearchUsers(arg0 context.Context, arg1 string, arg2 *models.Pagination) (*models.Pagination, []*models.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchUsers", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*models.Pagination)
+	ret1, _ := ret[1].([]*models.User)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchUsers indicates an expected call of SearchUsers.
+func (mr *MockUserRepositoryMockRecorder) SearchUsers(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchUsers", reflect.TypeOf((*MockUserRepository)(nil).SearchUsers), arg0, arg1, arg2)
+}
+
 // UpdateOwnUser mocks base method.
 func (m *MockUserRepository) UpdateOwnUser(arg0 context.Context, arg1 int, arg2 *models.User) (*models.User, error) {
 	m.ctrl.T.Helper()
@@ -168,3 +274,31 @@ func (mr *MockUserRepositoryMockRecorder) UpdateUserByID(arg0, arg1, arg2 interf
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserByID", reflect.TypeOf((*MockUserRepository)(nil).UpdateUserByID), arg0, arg1, arg2)
 }
+
+// UpdateUserPassword mocks base method.
+func (m *MockUserRepository) UpdateUserPassword(arg0 context.Context, arg1 uint, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockUserRepositoryMockRecorder) UpdateUserPassword(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockUserRepository)(nil).UpdateUserPassword), arg0, arg1, arg2)
+}
+
+// UpdateUserRole mocks base method.
+func (m *MockUserRepository) UpdateUserRole(arg0 context.Context, arg1 uint, arg2 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserRole", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserRole indicates an expected call of UpdateUserRole.
+func (mr *MockUserRepositoryMockRecorder) UpdateUserRole(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserRole", reflect.TypeOf((*MockUserRepository)(nil).UpdateUserRole), arg0, arg1, arg2)
+}
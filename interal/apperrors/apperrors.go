@@ -1,17 +1,65 @@
 package apperrors
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// AppError is an error with a stable Code and a Message safe to show to
+// clients. The wrapped cause is kept for errors.Is/As and logs only.
+//
+// The variables below are sentinels: every copy made by AppendMessage or the
+// With* methods matches its sentinel with errors.Is, because AppErrors are
+// compared by Code.
 type AppError struct {
 	Message  string
 	Code     string
 	HTTPCode int
+	// RetryAfter tells the client when to retry a rate limited request.
+	RetryAfter time.Duration
+	// Fields lists the failed request fields of a validation error.
+	Fields []FieldError
+	// Details are optional structured details, e.g. the limit which was hit.
+	Details map[string]interface{}
+	cause   error
+}
+
+// FieldError is a request field which broke a validation rule.
+type FieldError struct {
+	Field   string
+	Rule    string
+	Param   string
+	Message string
 }
 
 var (
+	InternalErr = AppError{
+		Message:  "internal server error",
+		Code:     "INTERNAL_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	DatabaseErr = AppError{
+		Message:  "database error",
+		Code:     "DATABASE_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	RecordNotFoundErr = AppError{
+		Message:  "the requested record doesn't exist",
+		Code:     "RECORD_NOT_FOUND",
+		HTTPCode: http.StatusNotFound,
+	}
+
+	DuplicateKeyErr = AppError{
+		Message:  "the record already exists",
+		Code:     "DUPLICATE_KEY",
+		HTTPCode: http.StatusConflict,
+	}
+
 	ConfigUnmarshallErr = AppError{
 		Message:  "couldn't unmarshal a response",
 		Code:     "UNMARSHAL_ERR",
@@ -30,6 +78,18 @@ var (
 		HTTPCode: http.StatusBadRequest,
 	}
 
+	InvalidCredentialsErr = AppError{
+		Message:  "invalid username or password",
+		Code:     "INVALID_CREDENTIALS",
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	CanNotSignInErr = AppError{
+		Message:  "can't sign in",
+		Code:     "SIGN_IN_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
 	CanNotCreateTokenErr = AppError{
 		Message:  "can't create token",
 		Code:     "TOKEN_CREATE_ERR",
@@ -42,10 +102,52 @@ var (
 		HTTPCode: http.StatusInternalServerError,
 	}
 
+	InvalidTokenErr = AppError{
+		Message:  "the token is invalid or expired",
+		Code:     "INVALID_TOKEN_ERR",
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	InvalidRefreshTokenErr = AppError{
+		Message:  "refresh token is invalid or expired",
+		Code:     "REFRESH_TOKEN_ERR",
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	RefreshTokenReuseErr = AppError{
+		Message:  "refresh token was already used, all sessions of this sign in are revoked",
+		Code:     "REFRESH_TOKEN_REUSE_ERR",
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	TokenRevokedErr = AppError{
+		Message:  "token is revoked",
+		Code:     "TOKEN_REVOKED_ERR",
+		HTTPCode: http.StatusUnauthorized,
+	}
+
+	CanNotRevokeTokenErr = AppError{
+		Message:  "can't revoke token",
+		Code:     "TOKEN_REVOKE_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
 	CanNotCreateUserErr = AppError{
 		Message:  "can't create user",
 		Code:     "SING_UP_ERR",
-		HTTPCode: http.StatusBadRequest,
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	UsernameTakenErr = AppError{
+		Message:  "the username is already taken",
+		Code:     "USERNAME_TAKEN",
+		HTTPCode: http.StatusConflict,
+	}
+
+	CanNotResetPasswordErr = AppError{
+		Message:  "can't reset the password",
+		Code:     "RESET_PASSWORD_ERR",
+		HTTPCode: http.StatusInternalServerError,
 	}
 
 	HashingPasswordErr = AppError{
@@ -60,18 +162,84 @@ var (
 		HTTPCode: http.StatusInternalServerError,
 	}
 
+	CanNotRestoreUserErr = AppError{
+		Message:  "can't restore the user",
+		Code:     "RESTORE_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	CanNotPurgeUserErr = AppError{
+		Message:  "can't purge the user",
+		Code:     "PURGE_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
 	PaginationErr = AppError{
 		Message:  "there is pagination problem",
 		Code:     "PAGINATION_ERR",
 		HTTPCode: http.StatusInternalServerError,
 	}
 
+	InvalidCursorErr = AppError{
+		Message:  "the cursor is invalid",
+		Code:     "INVALID_CURSOR_ERR",
+		HTTPCode: http.StatusBadRequest,
+	}
+
 	CanNotInitializeDBSessionErr = AppError{
 		Message:  "can't initialize db session",
 		Code:     "DB_SESSION_INIT_ERR",
 		HTTPCode: http.StatusInternalServerError,
 	}
 
+	NotReadyErr = AppError{
+		Message:  "the service is not ready",
+		Code:     "NOT_READY",
+		HTTPCode: http.StatusServiceUnavailable,
+	}
+
+	MigrationErr = AppError{
+		Message:  "can't migrate the database",
+		Code:     "MIGRATION_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	SchemaOutdatedErr = AppError{
+		Message:  "the database schema is behind, run `usermanager migrate up`",
+		Code:     "SCHEMA_OUTDATED_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	HasherInitializeErr = AppError{
+		Message:  "can't initialize password hasher",
+		Code:     "HASHER_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	RevocationStoreInitializeErr = AppError{
+		Message:  "can't initialize token revocation store",
+		Code:     "REVOCATION_STORE_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	KeySetInitializeErr = AppError{
+		Message:  "can't initialize token signing keys",
+		Code:     "KEY_SET_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	RolesInitializeErr = AppError{
+		Message:  "can't initialize role permissions",
+		Code:     "ROLES_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	DeletionOptionsInitializeErr = AppError{
+		Message:  "can't initialize deleted users options",
+		Code:     "DELETION_OPTIONS_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
 	ValidatorErr = AppError{
 		Message:  "validation cannot be passed",
 		Code:     "VALIDATOR_ERR",
@@ -107,6 +275,90 @@ var (
 		HTTPCode: http.StatusInternalServerError,
 	}
 
+	CanNotChangeOwnRoleErr = AppError{
+		Message:  "U can't change your own role",
+		Code:     "OWN_ROLE_ERR",
+		HTTPCode: http.StatusForbidden,
+	}
+
+	UnknownRoleErr = AppError{
+		Message:  "unknown role",
+		Code:     "UNKNOWN_ROLE_ERR",
+		HTTPCode: http.StatusBadRequest,
+	}
+
+	CanNotAssignRoleErr = AppError{
+		Message:  "can't assign the role",
+		Code:     "ASSIGN_ROLE_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	CanNotBootstrapAdminErr = AppError{
+		Message:  "can't create the first admin",
+		Code:     "BOOTSTRAP_ADMIN_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	CanNotWriteAuditErr = AppError{
+		Message:  "can't write the audit event",
+		Code:     "AUDIT_WRITE_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	CanNotReadAuditErr = AppError{
+		Message:  "can't read audit events",
+		Code:     "AUDIT_READ_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	TooManyAttemptsErr = AppError{
+		Message:  "too many attempts, try again later",
+		Code:     "TOO_MANY_ATTEMPTS_ERR",
+		HTTPCode: http.StatusTooManyRequests,
+	}
+
+	RateLimitErr = AppError{
+		Message:  "can't check the attempts limit",
+		Code:     "RATE_LIMIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	CanNotUnlockUserErr = AppError{
+		Message:  "can't unlock the user",
+		Code:     "UNLOCK_USER_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	MetricsInitializeErr = AppError{
+		Message:  "can't initialize metrics",
+		Code:     "METRICS_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	TracingInitializeErr = AppError{
+		Message:  "can't initialize tracing",
+		Code:     "TRACING_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	LoggingInitializeErr = AppError{
+		Message:  "can't initialize logging",
+		Code:     "LOGGING_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	RateLimitStoreInitializeErr = AppError{
+		Message:  "can't initialize rate limit store",
+		Code:     "RATE_LIMIT_STORE_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
+	TrustedProxiesInitializeErr = AppError{
+		Message:  "can't initialize trusted proxies",
+		Code:     "TRUSTED_PROXIES_INIT_ERR",
+		HTTPCode: http.StatusInternalServerError,
+	}
+
 	CanNotRateYorself = AppError{
 		Message:  "U can't rate yourself",
 		Code:     "RATE_YORSELF_ERR",
@@ -145,22 +397,112 @@ var (
 )
 
 func (appError *AppError) Error() string {
-	return appError.Code + ": " + appError.Message
+	if appError.cause == nil {
+		return appError.Code + ": " + appError.Message
+	}
+	return appError.Code + ": " + appError.Message + " : " + appError.cause.Error()
+}
+
+// Unwrap returns the cause, so errors.Is/As see through the AppError.
+func (appError *AppError) Unwrap() error {
+	return appError.cause
 }
 
+// Is matches any AppError with the same Code.
+func (appError *AppError) Is(target error) bool {
+	targetErr, ok := target.(*AppError)
+	return ok && targetErr.Code == appError.Code
+}
+
+// Wrap returns a copy with the cause. The client facing Message stays the
+// same, the cause is only logged.
+func (appError *AppError) Wrap(cause error) *AppError {
+	err := *appError
+	if err.cause == nil {
+		err.cause = cause
+	} else {
+		err.cause = causes{err.cause, cause}
+	}
+	return &err
+}
+
+// AppendMessage wraps an error argument as the cause, other arguments are
+// formatted into a new error.
 func (appError *AppError) AppendMessage(anyErrs ...interface{}) *AppError {
-	return &AppError{
-		Message:  fmt.Sprintf("%v : %v", appError.Message, anyErrs),
-		Code:     appError.Code,
-		HTTPCode: appError.HTTPCode,
+	if len(anyErrs) == 1 {
+		if err, ok := anyErrs[0].(error); ok {
+			return appError.Wrap(err)
+		}
 	}
+	return appError.Wrap(fmt.Errorf("%v", anyErrs))
+}
+
+func (appError *AppError) WithRetryAfter(retryAfter time.Duration) *AppError {
+	err := *appError
+	err.RetryAfter = retryAfter
+	return &err
 }
 
-func Is(err1 error, err2 *AppError) bool {
-	err, ok := err1.(*AppError)
-	if !ok {
-		return false
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, as the
+// Retry-After header needs.
+func (appError *AppError) RetryAfterSeconds() int64 {
+	return int64((appError.RetryAfter + time.Second - 1) / time.Second)
+}
+
+func (appError *AppError) WithFields(fields ...FieldError) *AppError {
+	err := *appError
+	err.Fields = fields
+	return &err
+}
+
+func (appError *AppError) WithDetail(key string, value interface{}) *AppError {
+	err := *appError
+	err.Details = make(map[string]interface{}, len(appError.Details)+1)
+	for k, v := range appError.Details {
+		err.Details[k] = v
+	}
+	err.Details[key] = value
+	return &err
+}
+
+// Is reports whether any error in err's chain is the given AppError.
+func Is(err error, target *AppError) bool {
+	return errors.Is(err, target)
+}
+
+// As finds the first AppError in err's chain.
+func As(err error) (*AppError, bool) {
+	var appErr *AppError
+	ok := errors.As(err, &appErr)
+	return appErr, ok
+}
+
+// causes keeps every cause of an AppError wrapped several times, errors.Is
+// and errors.As match any of them.
+type causes []error
+
+func (c causes) Error() string {
+	messages := make([]string, len(c))
+	for i, err := range c {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, " : ")
+}
+
+func (c causes) Is(target error) bool {
+	for _, err := range c {
+		if errors.Is(err, target) {
+			return true
+		}
 	}
+	return false
+}
 
-	return err.Code == err2.Code
+func (c causes) As(target interface{}) bool {
+	for _, err := range c {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,73 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+var errConnection = errors.New("connection refused")
+
+type driverError struct{ number int }
+
+func (e *driverError) Error() string { return fmt.Sprintf("driver error %d", e.number) }
+
+func TestAppErrorKeepsSentinelIdentity(t *testing.T) {
+	err := UserNotFoundErr.AppendMessage(errConnection)
+
+	assert.Equal(t, errors.Is(err, &UserNotFoundErr), true)
+	assert.Equal(t, errors.Is(err, &WrongRoleErr), false)
+	assert.Equal(t, errors.Is(err, errConnection), true)
+	assert.Equal(t, errors.Is(fmt.Errorf("find user: %w", err), &UserNotFoundErr), true)
+	assert.Equal(t, Is(err, &UserNotFoundErr), true)
+	assert.Equal(t, err.Error(), "DATA_NOT_FOUND_ERR: can't find user : connection refused")
+	assert.Equal(t, err.Message, UserNotFoundErr.Message)
+}
+
+func TestAppErrorAs(t *testing.T) {
+	err := fmt.Errorf("sign in: %w", CanNotSignInErr.AppendMessage(&driverError{number: 1062}))
+
+	appErr, ok := As(err)
+	assert.Equal(t, ok, true)
+	assert.Equal(t, appErr.Code, CanNotSignInErr.Code)
+	assert.Equal(t, appErr.HTTPCode, http.StatusInternalServerError)
+
+	var driverErr *driverError
+	assert.Equal(t, errors.As(err, &driverErr), true)
+	assert.Equal(t, driverErr.number, 1062)
+
+	_, ok = As(errConnection)
+	assert.Equal(t, ok, false)
+}
+
+func TestAppErrorWrappedSeveralTimes(t *testing.T) {
+	err := CanNotUpdateErr.AppendMessage(&driverError{number: 1213}).AppendMessage(errConnection)
+
+	var driverErr *driverError
+	assert.Equal(t, errors.As(err, &driverErr), true)
+	assert.Equal(t, errors.Is(err, errConnection), true)
+	assert.Equal(t, err.Error(), "UPDATE_ERR: couldn't update the user : driver error 1213 : connection refused")
+}
+
+func TestAppErrorNestedSentinels(t *testing.T) {
+	err := CanNotDeleteUserErr.AppendMessage(WrongRoleErr.AppendMessage(errors.New("admin user not allowed to delete")))
+
+	appErr, _ := As(err)
+	assert.Equal(t, appErr.Code, CanNotDeleteUserErr.Code)
+	assert.Equal(t, errors.Is(err, &WrongRoleErr), true)
+}
+
+func TestAppErrorDetails(t *testing.T) {
+	err := TooManyAttemptsErr.WithRetryAfter(1500*time.Millisecond).WithDetail("limit", 20)
+	other := err.WithDetail("scope", "ip")
+
+	assert.Equal(t, err.Details, map[string]interface{}{"limit": 20})
+	assert.Equal(t, other.Details, map[string]interface{}{"limit": 20, "scope": "ip"})
+	assert.Equal(t, err.RetryAfterSeconds(), int64(2))
+	assert.Equal(t, TooManyAttemptsErr.Details == nil, true, "sentinels must not be changed")
+	assert.Equal(t, errors.Is(other, &TooManyAttemptsErr), true)
+}
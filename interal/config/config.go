@@ -1,22 +1,102 @@
 package config
 
 import (
+	"fmt"
+	"reflect"
+
 	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	DBUser     string `mapstructure:"DB_USER"`
-	DBPassword string `mapstructure:"DB_PASSWORD"`
-	DBName     string `mapstructure:"DB_NAME"`
-	DBHost     string `mapstructure:"DB_HOST"`
-	Port       string `mapstructure:"PORT"`
-	HashSalt   string `mapstructure:"HASH_SALT"`
-	SigningKey string `mapstructure:"SIGNING_KEY"`
-	TokenTtl   int    `mapstructure:"TOKEN_TTL"`
+	DBDriver           string  `mapstructure:"DB_DRIVER"`
+	DBDSN              string  `mapstructure:"DB_DSN"`
+	DBUser             string  `mapstructure:"DB_USER"`
+	DBPassword         string  `mapstructure:"DB_PASSWORD"`
+	DBName             string  `mapstructure:"DB_NAME"`
+	DBHost             string  `mapstructure:"DB_HOST"`
+	DBPort             string  `mapstructure:"DB_PORT"`
+	DBTLS              string  `mapstructure:"DB_TLS"`
+	DBMaxOpenConns     int     `mapstructure:"DB_MAX_OPEN_CONNS"`
+	DBMaxIdleConns     int     `mapstructure:"DB_MAX_IDLE_CONNS"`
+	DBConnMaxLifetime  int     `mapstructure:"DB_CONN_MAX_LIFETIME"`
+	DBConnMaxIdleTime  int     `mapstructure:"DB_CONN_MAX_IDLE_TIME"`
+	Port               string  `mapstructure:"PORT"`
+	ReadHeaderTimeout  int     `mapstructure:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout        int     `mapstructure:"HTTP_READ_TIMEOUT"`
+	WriteTimeout       int     `mapstructure:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout        int     `mapstructure:"HTTP_IDLE_TIMEOUT"`
+	ShutdownTimeout    int     `mapstructure:"SHUTDOWN_TIMEOUT"`
+	HashSalt           string  `mapstructure:"HASH_SALT"`
+	PasswordHasher     string  `mapstructure:"PASSWORD_HASHER"`
+	SigningKey         string  `mapstructure:"SIGNING_KEY"`
+	SigningMethod      string  `mapstructure:"TOKEN_SIGNING_METHOD"`
+	KeyID              string  `mapstructure:"TOKEN_KEY_ID"`
+	PrivateKeyFile     string  `mapstructure:"TOKEN_PRIVATE_KEY_FILE"`
+	VerifyKeys         string  `mapstructure:"TOKEN_VERIFICATION_KEYS"`
+	TokenIssuer        string  `mapstructure:"TOKEN_ISSUER"`
+	TokenAudience      string  `mapstructure:"TOKEN_AUDIENCE"`
+	TokenTtl           int     `mapstructure:"TOKEN_TTL"`
+	RefreshTokenTtl    int     `mapstructure:"REFRESH_TOKEN_TTL"`
+	RevocationStore    string  `mapstructure:"REVOCATION_STORE"`
+	UserCacheTtl       int     `mapstructure:"USER_CACHE_TTL"`
+	CursorSigningKey   string  `mapstructure:"CURSOR_SIGNING_KEY"`
+	PageDefaultLimit   int     `mapstructure:"PAGE_DEFAULT_LIMIT"`
+	PageMaxLimit       int     `mapstructure:"PAGE_MAX_LIMIT"`
+	RolePermissions    string  `mapstructure:"ROLE_PERMISSIONS"`
+	AdminUserName      string  `mapstructure:"ADMIN_USERNAME"`
+	AdminPassword      string  `mapstructure:"ADMIN_PASSWORD"`
+	DeletedUserNames   string  `mapstructure:"DELETED_USERNAMES"`
+	DeletedUserDays    int     `mapstructure:"DELETED_USER_RETENTION_DAYS"`
+	DeletedUserPurge   bool    `mapstructure:"DELETED_USER_PURGE_JOB"`
+	TrustedProxies     string  `mapstructure:"TRUSTED_PROXIES"`
+	RateLimitStore     string  `mapstructure:"RATE_LIMIT_STORE"`
+	SignInIPLimit      int     `mapstructure:"SIGN_IN_IP_LIMIT"`
+	SignInUserLimit    int     `mapstructure:"SIGN_IN_USER_LIMIT"`
+	LockoutThreshold   int     `mapstructure:"LOCKOUT_THRESHOLD"`
+	LockoutDuration    int     `mapstructure:"LOCKOUT_DURATION"`
+	LockoutMaxDuration int     `mapstructure:"LOCKOUT_MAX_DURATION"`
+	TraceExporter      string  `mapstructure:"TRACE_EXPORTER"`
+	TraceServiceName   string  `mapstructure:"TRACE_SERVICE_NAME"`
+	TraceSampleRatio   float64 `mapstructure:"TRACE_SAMPLE_RATIO"`
+	LogLevel           string  `mapstructure:"LOG_LEVEL"`
+	LogLevels          string  `mapstructure:"LOG_LEVELS"`
+}
+
+// defaults apply to the settings which are in neither the config file nor
+// the environment. Durations are in seconds.
+var defaults = map[string]interface{}{
+	"DB_MAX_OPEN_CONNS":           25,
+	"DB_MAX_IDLE_CONNS":           25,
+	"DB_CONN_MAX_LIFETIME":        300,
+	"DB_CONN_MAX_IDLE_TIME":       60,
+	"HTTP_READ_HEADER_TIMEOUT":    5,
+	"HTTP_READ_TIMEOUT":           15,
+	"HTTP_WRITE_TIMEOUT":          30,
+	"HTTP_IDLE_TIMEOUT":           120,
+	"SHUTDOWN_TIMEOUT":            30,
+	"PAGE_DEFAULT_LIMIT":          5,
+	"PAGE_MAX_LIMIT":              100,
+	"RATE_LIMIT_STORE":            "memory",
+	"SIGN_IN_IP_LIMIT":            20,
+	"SIGN_IN_USER_LIMIT":          10,
+	"LOCKOUT_THRESHOLD":           5,
+	"LOCKOUT_DURATION":            60,
+	"LOCKOUT_MAX_DURATION":        3600,
+	"DELETED_USERNAMES":           "reserve",
+	"DELETED_USER_RETENTION_DAYS": 0,
+	"DELETED_USER_PURGE_JOB":      false,
+	"TRACE_EXPORTER":              "none",
+	"TRACE_SERVICE_NAME":          "usermanager",
+	"TRACE_SAMPLE_RATIO":          1.0,
+	"LOG_LEVEL":                   "info",
+	"LOG_LEVELS":                  "gorm=warn",
 }
 
 func InitConfig() (config *Config, err error) {
+	for key, value := range defaults {
+		viper.SetDefault(key, value)
+	}
 	viper.AddConfigPath("./config")
 	viper.AddConfigPath("./build/package/config")
 	viper.SetConfigName("config")
@@ -31,3 +111,29 @@ func InitConfig() (config *Config, err error) {
 	}
 	return
 }
+
+// secretKeys are redacted by Redacted. DB_DSN may carry the password too.
+var secretKeys = map[string]bool{
+	"DB_PASSWORD":        true,
+	"DB_DSN":             true,
+	"HASH_SALT":          true,
+	"SIGNING_KEY":        true,
+	"CURSOR_SIGNING_KEY": true,
+	"ADMIN_PASSWORD":     true,
+}
+
+// Redacted returns the settings as KEY=value lines in the order of Config,
+// with the values of secrets replaced by "***" when they are set.
+func (c *Config) Redacted() []string {
+	value := reflect.ValueOf(c).Elem()
+	lines := make([]string, 0, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		key := value.Type().Field(i).Tag.Get("mapstructure")
+		setting := fmt.Sprint(value.Field(i).Interface())
+		if secretKeys[key] && setting != "" {
+			setting = "***"
+		}
+		lines = append(lines, key+"="+setting)
+	}
+	return lines
+}
@@ -0,0 +1,88 @@
+package mappers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
+	"github.com/labstack/echo/v4"
+)
+
+var auditSorts = []string{"id desc", "id asc", "created_at desc", "created_at asc"}
+
+// MapContextToAuditFilter reads the audit filters from the query:
+// actor_id, target_id, action and the RFC 3339 time range from/to. Invalid
+// ones are a ValidatorErr with every failing field.
+func MapContextToAuditFilter(c echo.Context) (*models.AuditFilter, error) {
+	filter := &models.AuditFilter{Action: c.QueryParam("action")}
+	var fields []apperrors.FieldError
+
+	ids := []struct {
+		param string
+		field *uint
+	}{{"actor_id", &filter.ActorID}, {"target_id", &filter.TargetID}}
+	for _, id := range ids {
+		value := c.QueryParam(id.param)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseUint(value, 10, 0)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: id.param, Rule: "numeric", Message: "must be a whole number"})
+			continue
+		}
+		*id.field = uint(n)
+	}
+
+	times := []struct {
+		param string
+		field **time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}}
+	for _, tm := range times {
+		value := c.QueryParam(tm.param)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			fields = append(fields, apperrors.FieldError{Field: tm.param, Rule: "datetime", Param: time.RFC3339, Message: "must be an RFC 3339 time"})
+			continue
+		}
+		*tm.field = &t
+	}
+
+	if len(fields) > 0 {
+		return nil, apperrors.ValidatorErr.WithFields(fields...)
+	}
+	return filter, nil
+}
+
+// MapContextToAuditPagination is MapContextToPagination which accepts only
+// sorting by id or created_at.
+func MapContextToAuditPagination(c echo.Context, policy PaginationPolicy) (*models.Pagination, error) {
+	pagination, err := MapContextToPagination(c, policy)
+	if err != nil {
+		return nil, err
+	}
+	for _, sort := range auditSorts {
+		if pagination.Sort == sort {
+			return pagination, nil
+		}
+	}
+	err = fmt.Errorf("unsupported sort %q", pagination.Sort)
+	return nil, apperrors.ValidatorErr.WithFields(apperrors.FieldError{Field: "sort", Rule: "sort", Message: "must be one of " + strings.Join(auditSorts, ", ")}).AppendMessage(err)
+}
+
+func MapAuditEventsToGetAuditEventsResponse(c echo.Context, events []*models.AuditEvent, pagination *models.Pagination) *requests.GetAuditEventsResponse {
+	MapPageLinks(c, pagination)
+
+	pagination.Rows = events
+	return &requests.GetAuditEventsResponse{
+		Message:        fmt.Sprintf("There are %d audit events", pagination.TotalRows),
+		EventsResponse: pagination,
+	}
+}
@@ -0,0 +1,48 @@
+package mappers
+
+import (
+	"net/http"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
+	"github.com/labstack/echo/v4"
+)
+
+// problemTypeBlank is the RFC 7807 type of problems without their own
+// documentation page, the code tells them apart.
+const problemTypeBlank = "about:blank"
+
+// MapAppErrorToErrorResponse shows only the code, the message, the field
+// errors and the details of the AppError, its causes are internal and never
+// leave the server.
+func MapAppErrorToErrorResponse(c echo.Context, appErr *apperrors.AppError) *requests.ErrorResponse {
+	response := &requests.ErrorResponse{
+		Type:      problemTypeBlank,
+		Title:     http.StatusText(appErr.HTTPCode),
+		Status:    appErr.HTTPCode,
+		Instance:  c.Request().URL.Path,
+		Code:      appErr.Code,
+		Message:   appErr.Message,
+		RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+	}
+
+	if len(appErr.Details) > 0 || appErr.RetryAfter > 0 {
+		response.Details = make(map[string]interface{}, len(appErr.Details)+1)
+		for key, value := range appErr.Details {
+			response.Details[key] = value
+		}
+		if appErr.RetryAfter > 0 {
+			response.Details["retry_after"] = appErr.RetryAfterSeconds()
+		}
+	}
+
+	for _, field := range appErr.Fields {
+		response.Fields = append(response.Fields, requests.FieldErrorResponse{
+			Field:   field.Field,
+			Rule:    field.Rule,
+			Param:   field.Param,
+			Message: field.Message,
+		})
+	}
+	return response
+}
@@ -1,23 +1,59 @@
 package mappers
 
 import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
 	"github.com/labstack/echo/v4"
-	"strconv"
 )
 
-func MapContextToPagination(c echo.Context) *models.Pagination {
+// PaginationPolicy bounds the pages clients may ask for. Zero fields take
+// the values of DefaultPaginationPolicy.
+type PaginationPolicy struct {
+	DefaultLimit int
+	MaxLimit     int
+}
+
+var DefaultPaginationPolicy = PaginationPolicy{DefaultLimit: 5, MaxLimit: 100}
 
-	limit, err := strconv.Atoi(c.QueryParam("limit"))
-	if err != nil {
-		limit = 5
-		c.Logger().Error(err)
+// RelevanceSort is the sort of search results, the best matches first.
+const RelevanceSort = "relevance"
+
+func (p PaginationPolicy) withDefaults() PaginationPolicy {
+	if p.MaxLimit <= 0 {
+		p.MaxLimit = DefaultPaginationPolicy.MaxLimit
+	}
+	if p.DefaultLimit <= 0 {
+		p.DefaultLimit = DefaultPaginationPolicy.DefaultLimit
 	}
+	if p.DefaultLimit > p.MaxLimit {
+		p.DefaultLimit = p.MaxLimit
+	}
+	return p
+}
 
-	page, err := strconv.Atoi(c.QueryParam("page"))
-	if err != nil {
-		page = 1
-		c.Logger().Error(err)
+// MapContextToPagination reads limit, page and sort. A missing limit or page
+// takes the default, invalid ones are a ValidatorErr with every failing
+// field. Pages past the end are valid, and empty.
+func MapContextToPagination(c echo.Context, policy PaginationPolicy) (*models.Pagination, error) {
+	policy = policy.withDefaults()
+	var fields []apperrors.FieldError
+
+	limit, field := intQueryParam(c, "limit", policy.DefaultLimit, 1, policy.MaxLimit)
+	if field != nil {
+		fields = append(fields, *field)
+	}
+	page, field := intQueryParam(c, "page", 1, 1, math.MaxInt32)
+	if field != nil {
+		fields = append(fields, *field)
+	}
+	if len(fields) > 0 {
+		return nil, apperrors.ValidatorErr.WithFields(fields...)
 	}
 
 	sort := c.QueryParam("sort")
@@ -25,5 +61,153 @@ func MapContextToPagination(c echo.Context) *models.Pagination {
 		sort = "id desc"
 	}
 
-	return &models.Pagination{Limit: limit, Page: page, Sort: sort}
+	return &models.Pagination{Limit: limit, Page: page, Sort: sort}, nil
+}
+
+func intQueryParam(c echo.Context, name string, fallback, min, max int) (int, *apperrors.FieldError) {
+	value := c.QueryParam(name)
+	if value == "" {
+		return fallback, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	switch {
+	case err != nil:
+		return 0, &apperrors.FieldError{Field: name, Rule: "numeric", Message: "must be a whole number"}
+	case n < min:
+		return 0, &apperrors.FieldError{Field: name, Rule: "min", Param: strconv.Itoa(min), Message: fmt.Sprintf("must be at least %d", min)}
+	case n > max:
+		return 0, &apperrors.FieldError{Field: name, Rule: "max", Param: strconv.Itoa(max), Message: fmt.Sprintf("must be at most %d", max)}
+	}
+	return n, nil
+}
+
+// MapContextToUserPagination is MapContextToPagination which accepts only
+// sorting by the UserSortFields, and writes the sort in its canonical form.
+// A cursor replaces the page, and its sort is used unless sort is given.
+func MapContextToUserPagination(c echo.Context, policy PaginationPolicy) (*models.Pagination, error) {
+	pagination, err := MapContextToPagination(c, policy)
+	if err != nil {
+		return nil, err
+	}
+	pagination.Cursor = c.QueryParam("cursor")
+	if pagination.Cursor != "" {
+		pagination.Page = 0
+		if c.QueryParam("sort") == "" {
+			pagination.Sort = ""
+		}
+	}
+
+	if withTotal := c.QueryParam("with_total"); withTotal != "" {
+		if pagination.WithTotal, err = strconv.ParseBool(withTotal); err != nil {
+			return nil, apperrors.ValidatorErr.WithFields(apperrors.FieldError{Field: "with_total", Rule: "boolean", Message: "must be true or false"}).AppendMessage(err)
+		}
+	}
+
+	keys, err := models.ParseSort(pagination.Sort, models.UserSortFields)
+	if err != nil {
+		return nil, apperrors.ValidatorErr.WithFields(apperrors.FieldError{Field: "sort", Rule: "sort", Message: err.Error()}).AppendMessage(err)
+	}
+	pagination.Sort = models.FormatSort(keys)
+	return pagination, nil
+}
+
+// MapContextToDeletedUserPagination is MapContextToPagination which accepts
+// only sorting by the DeletedUserSortFields, the latest deleted users first
+// unless sort is given.
+func MapContextToDeletedUserPagination(c echo.Context, policy PaginationPolicy) (*models.Pagination, error) {
+	pagination, err := MapContextToPagination(c, policy)
+	if err != nil {
+		return nil, err
+	}
+	if c.QueryParam("sort") == "" {
+		pagination.Sort = "deleted_at desc"
+	}
+
+	keys, err := models.ParseSort(pagination.Sort, models.DeletedUserSortFields)
+	if err != nil {
+		return nil, apperrors.ValidatorErr.WithFields(apperrors.FieldError{Field: "sort", Rule: "sort", Message: err.Error()}).AppendMessage(err)
+	}
+	pagination.Sort = models.FormatSort(keys)
+	return pagination, nil
+}
+
+// MapPageLinks sets the links to the first, last, previous and next pages,
+// and to the pages around the cursors. They keep the filters of the request.
+// A page read by a cursor has no number, its last page is known only when
+// the total is counted. The previous page of a page past the end is the last
+// one. The links are sent in the Link header (RFC 8288) as well, next and
+// prev follow the cursors of a page read by a cursor.
+func MapPageLinks(c echo.Context, pagination *models.Pagination) {
+	lastPage := pagination.TotalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	pagination.FirstPage = pageLink(c, pagination, 1)
+	if pagination.TotalPages > 0 || pagination.Cursor == "" {
+		pagination.LastPage = pageLink(c, pagination, lastPage)
+	}
+	if pagination.Cursor == "" {
+		if pagination.Page > 1 {
+			previous := pagination.Page - 1
+			if previous > lastPage {
+				previous = lastPage
+			}
+			pagination.PreviousPage = pageLink(c, pagination, previous)
+		}
+		if pagination.Page < pagination.TotalPages {
+			pagination.NextPage = pageLink(c, pagination, pagination.Page+1)
+		}
+	}
+
+	if pagination.PrevCursor != "" {
+		pagination.PreviousCursorPage = cursorLink(c, pagination, pagination.PrevCursor)
+	}
+	if pagination.NextCursor != "" {
+		pagination.NextCursorPage = cursorLink(c, pagination, pagination.NextCursor)
+	}
+
+	next, previous := pagination.NextPage, pagination.PreviousPage
+	if pagination.Cursor != "" {
+		next, previous = pagination.NextCursorPage, pagination.PreviousCursorPage
+	}
+	var links []string
+	for _, link := range []struct{ rel, target string }{
+		{"first", pagination.FirstPage},
+		{"prev", previous},
+		{"next", next},
+		{"last", pagination.LastPage},
+	} {
+		if link.target != "" {
+			links = append(links, fmt.Sprintf("<%s>; rel=%q", link.target, link.rel))
+		}
+	}
+	if len(links) > 0 {
+		c.Response().Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func pageLink(c echo.Context, pagination *models.Pagination, page int) string {
+	query := linkQuery(c, pagination)
+	query.Del("cursor")
+	query.Set("page", strconv.Itoa(page))
+	return fmt.Sprintf("%s?%s", c.Request().URL.Path, query.Encode())
+}
+
+func cursorLink(c echo.Context, pagination *models.Pagination, cursor string) string {
+	query := linkQuery(c, pagination)
+	query.Del("page")
+	query.Set("cursor", cursor)
+	return fmt.Sprintf("%s?%s", c.Request().URL.Path, query.Encode())
+}
+
+func linkQuery(c echo.Context, pagination *models.Pagination) url.Values {
+	query := url.Values{}
+	for key, values := range c.QueryParams() {
+		query[key] = values
+	}
+	query.Set("limit", strconv.Itoa(pagination.Limit))
+	query.Set("sort", pagination.Sort)
+	return query
 }
@@ -3,16 +3,14 @@ package mappers
 import (
 	"fmt"
 
-	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
-	"github.com/labstack/echo/v4"
 )
 
 func MapUserToUserResponse(u *models.User) *requests.UserResponse {
 	return &requests.UserResponse{
 		ID:        u.ID,
-		UserName:  u.UserName,
+		UserName:  u.DisplayName(),
 		Role:      u.Role,
 		Rating:    u.Rating,
 		FirstName: u.FirstName,
@@ -26,7 +24,6 @@ func MapUserToUserResponse(u *models.User) *requests.UserResponse {
 func MapSignUpRequestToUser(signUp *requests.SignUpRequest) *models.User {
 	return &models.User{
 		UserName:  signUp.UserName,
-		Role:      signUp.Role,
 		Rating:    1,
 		FirstName: signUp.FirstName,
 		LastName:  signUp.LastName,
@@ -37,7 +34,6 @@ func MapSignUpRequestToUser(signUp *requests.SignUpRequest) *models.User {
 func MapUpdateRequestToUser(signUp *requests.UpdateRequest) *models.User {
 	return &models.User{
 		UserName:  signUp.UserName,
-		Role:      signUp.Role,
 		FirstName: signUp.FirstName,
 		LastName:  signUp.LastName,
 	}
@@ -46,7 +42,6 @@ func MapUpdateRequestToUser(signUp *requests.UpdateRequest) *models.User {
 func MapUpdateOwnRequestToUser(signUp *requests.UpdateOwnRequest) *models.User {
 	return &models.User{
 		UserName:  signUp.UserName,
-		Role:      signUp.Role,
 		FirstName: signUp.FirstName,
 		LastName:  signUp.LastName,
 		Password:  signUp.Password,
@@ -61,11 +56,6 @@ func MapSignInRequestToUser(signIp *requests.SignInRequest) *models.User {
 
 }
 
-func MapAppErrorToHTTPError(err error) *echo.HTTPError {
-	appErr := err.(*apperrors.AppError)
-	return echo.NewHTTPError(appErr.HTTPCode, appErr.Error())
-}
-
 func MapUserToGetUserResponse(user *models.User) *requests.GetOneUserResponse {
 	return &requests.GetOneUserResponse{
 		Message:      fmt.Sprintf("There is user with ID %v", user.ID),
@@ -103,3 +93,14 @@ func MapUserToUpdateResponse(u *models.User) *requests.UpdateUserResponce {
 		},
 	}
 }
+
+func MapGetUsersRequestToUserFilter(r *requests.GetUsersRequest) *models.UserFilter {
+	return &models.UserFilter{
+		Role:          r.Role,
+		RatingGTE:     r.RatingGTE,
+		RatingLTE:     r.RatingLTE,
+		CreatedAfter:  r.CreatedAfter,
+		CreatedBefore: r.CreatedBefore,
+		Query:         r.Q,
+	}
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+const (
+	AuditActionUserUpdate     = "user.update"
+	AuditActionUserDelete     = "user.delete"
+	AuditActionOwnUpdate      = "user.update_own"
+	AuditActionOwnDelete      = "user.delete_own"
+	AuditActionUserRate       = "user.rate"
+	AuditActionRoleAssign     = "user.role_assign"
+	AuditActionUserUnlock     = "user.unlock"
+	AuditActionAdminBootstrap = "user.admin_bootstrap"
+	AuditActionAdminCreate    = "user.admin_create"
+	AuditActionPasswordReset  = "user.password_reset"
+	AuditActionUserRestore    = "user.restore"
+	AuditActionUserPurge      = "user.purge"
+)
+
+// AuditEvent records who did what to whom. It is written in the same
+// transaction as the change itself.
+type AuditEvent struct {
+	ID        uint         `json:"id"`
+	ActorID   uint         `json:"actor_id" gorm:"index"`
+	TargetID  uint         `json:"target_id" gorm:"index"`
+	Action    string       `json:"action" gorm:"size:64;index"`
+	Changes   AuditChanges `json:"changes" gorm:"type:text;serializer:json"`
+	ClientIP  string       `json:"client_ip" gorm:"size:64"`
+	RequestID string       `json:"request_id" gorm:"size:64"`
+	CreatedAt *time.Time   `json:"created_at" gorm:"index"`
+}
+
+// AuditChanges maps a changed field to its values before and after.
+type AuditChanges map[string]AuditChange
+
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditFilter narrows down audit events, zero fields don't filter.
+type AuditFilter struct {
+	ActorID  uint
+	TargetID uint
+	Action   string
+	From     *time.Time
+	To       *time.Time
+}
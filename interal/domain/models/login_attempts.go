@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LoginAttempts counts sign in attempts of a user. ConsecutiveFailures is
+// reset by a successful sign in or an unlock, and the account is locked
+// until LockedUntil once it reaches the lockout threshold.
+type LoginAttempts struct {
+	UserID              uint       `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	FailedAttempts      int64      `json:"failed_attempts"`
+	SuccessfulAttempts  int64      `json:"successful_attempts"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LockedUntil         *time.Time `json:"locked_until"`
+	LastFailedAt        *time.Time `json:"last_failed_at"`
+	LastSucceededAt     *time.Time `json:"last_succeeded_at"`
+	UpdatedAt           *time.Time `json:"updated_at"`
+}
@@ -1,16 +1,132 @@
 package models
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Pagination is either by pages, with Limit and Page, or, when Cursor is
+// set, by keys: the page follows or precedes the row of the cursor, and
+// TotalRows is counted only when WithTotal is set.
 type Pagination struct {
-	Limit        int         `json:"limit"`
-	Page         int         `json:"page"`
-	Sort         string      `json:"sort"`
-	TotalRows    int64       `json:"total_rows"`
-	TotalPages   int         `json:"total_pages"`
-	PreviousPage string      `json:"previous_page"`
-	NextPage     string      `json:"next_page"`
-	FirstPage    string      `json:"first_page"`
-	LastPage     string      `json:"last_page"`
-	FromRow      int         `json:"from_row"`
-	ToRow        int         `json:"to_row"`
-	Rows         interface{} `json:"rows"`
+	Limit              int         `json:"limit"`
+	Page               int         `json:"page"`
+	Sort               string      `json:"sort"`
+	TotalRows          int64       `json:"total_rows"`
+	TotalPages         int         `json:"total_pages"`
+	PreviousPage       string      `json:"previous_page"`
+	NextPage           string      `json:"next_page"`
+	FirstPage          string      `json:"first_page"`
+	LastPage           string      `json:"last_page"`
+	FromRow            int         `json:"from_row"`
+	ToRow              int         `json:"to_row"`
+	NextCursor         string      `json:"next_cursor,omitempty"`
+	PrevCursor         string      `json:"prev_cursor,omitempty"`
+	NextCursorPage     string      `json:"next_cursor_page,omitempty"`
+	PreviousCursorPage string      `json:"previous_cursor_page,omitempty"`
+	Rows               interface{} `json:"rows"`
+
+	Cursor    string `json:"-"`
+	WithTotal bool   `json:"-"`
+	// Keyset is the decoded Cursor, NextKeyset and PrevKeyset are the
+	// positions around the page found.
+	Keyset     *Cursor `json:"-"`
+	NextKeyset *Cursor `json:"-"`
+	PrevKeyset *Cursor `json:"-"`
+}
+
+// Validate rejects the limits and pages no query can be built from, the
+// page matters only without a Keyset.
+func (p *Pagination) Validate() error {
+	if p.Limit < 1 {
+		return fmt.Errorf("limit %d is less than 1", p.Limit)
+	}
+	if p.Keyset == nil && p.Page < 1 {
+		return fmt.Errorf("page %d is less than 1", p.Page)
+	}
+	return nil
+}
+
+// Offset is the number of rows before the page.
+func (p *Pagination) Offset() int {
+	return (p.Page - 1) * p.Limit
+}
+
+// Cursor is a position in a sorted list: the sort, the values of its keys and
+// the ID of a row. The page after it starts with the next row, the page
+// Before it ends with the previous one.
+type Cursor struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+	ID     uint     `json:"id"`
+	Before bool     `json:"b,omitempty"`
+}
+
+// SortKey is a field to sort by, ascending unless Desc is set.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort reads a comma separated list of "<field> [asc|desc]" keys, e.g.
+// "rating desc,user_name", accepting only the allowed fields, each once.
+func ParseSort(value string, allowed map[string]bool) ([]SortKey, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var keys []SortKey
+	seen := map[string]bool{}
+	for _, key := range strings.Split(value, ",") {
+		parts := strings.Fields(key)
+		if len(parts) == 0 || len(parts) > 2 {
+			return nil, fmt.Errorf("sort key %q isn't \"<field> [asc|desc]\"", strings.TrimSpace(key))
+		}
+
+		field := parts[0]
+		if !allowed[field] {
+			return nil, fmt.Errorf("can't sort by %q, the fields are %s", field, strings.Join(sortedFields(allowed), ", "))
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%q is sorted by twice", field)
+		}
+		seen[field] = true
+
+		sortKey := SortKey{Field: field}
+		if len(parts) == 2 {
+			switch strings.ToLower(parts[1]) {
+			case "asc":
+			case "desc":
+				sortKey.Desc = true
+			default:
+				return nil, fmt.Errorf("unknown direction %q of %q, it is asc or desc", parts[1], field)
+			}
+		}
+		keys = append(keys, sortKey)
+	}
+	return keys, nil
+}
+
+// FormatSort writes keys in the form ParseSort reads, with the directions
+// spelled out.
+func FormatSort(keys []SortKey) string {
+	formatted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		direction := "asc"
+		if key.Desc {
+			direction = "desc"
+		}
+		formatted = append(formatted, key.Field+" "+direction)
+	}
+	return strings.Join(formatted, ",")
+}
+
+func sortedFields(allowed map[string]bool) []string {
+	fields := make([]string, 0, len(allowed))
+	for field := range allowed {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
 }
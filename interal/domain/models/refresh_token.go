@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RefreshToken keeps only a hash of the issued token. Every token belongs to
+// a family which starts at sign in/up and continues through each rotation,
+// so a whole chain can be revoked once a rotated token is presented again.
+type RefreshToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id" gorm:"index"`
+	FamilyID  string     `json:"family_id" gorm:"size:64;index"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt *time.Time `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+}
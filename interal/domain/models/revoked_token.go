@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// RevokedToken is an access token (by its jti) which was revoked before its
+// expiration, e.g. on logout. It is kept only until the token expires.
+type RevokedToken struct {
+	ID        uint       `json:"id"`
+	JTI       string     `json:"jti" gorm:"size:64;uniqueIndex"`
+	UserID    uint       `json:"user_id"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"index"`
+	CreatedAt *time.Time `json:"created_at"`
+}
+
+// UserSessionRevocation rejects every access token of the user issued at or
+// before RevokedBefore.
+type UserSessionRevocation struct {
+	UserID        uint       `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	RevokedBefore time.Time  `json:"revoked_before"`
+	UpdatedAt     *time.Time `json:"updated_at"`
+}
@@ -18,6 +18,44 @@ type User struct {
 	CreatedAt    *time.Time     `json:"created_at"`
 	UpdatedAt    *time.Time     `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index"`
+	// DeletedUserName keeps the username of a deleted user whose username
+	// was freed, UserName is empty then.
+	DeletedUserName *string `json:"deleted_user_name,omitempty"`
+}
+
+// DisplayName is the username, or the freed username of a deleted user.
+func (u *User) DisplayName() string {
+	if u.UserName == "" && u.DeletedUserName != nil {
+		return *u.DeletedUserName
+	}
+	return u.UserName
+}
+
+// UserSortFields are the fields the user list may be sorted by.
+var UserSortFields = map[string]bool{
+	"id":         true,
+	"user_name":  true,
+	"rating":     true,
+	"created_at": true,
+}
+
+// DeletedUserSortFields are the fields the deleted users may be sorted by.
+var DeletedUserSortFields = map[string]bool{
+	"id":         true,
+	"user_name":  true,
+	"deleted_at": true,
+}
+
+// UserFilter narrows down users, zero fields don't filter.
+type UserFilter struct {
+	Role          string
+	RatingGTE     *int
+	RatingLTE     *int
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Query matches the beginning of the username, the first or the last
+	// name, ignoring case.
+	Query string
 }
 
 type RatedByUser struct {
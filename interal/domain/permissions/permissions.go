@@ -0,0 +1,135 @@
+package permissions
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type Permission string
+
+const (
+	UsersRead        Permission = "users:read"
+	UsersList        Permission = "users:list"
+	UsersUpdate      Permission = "users:update"
+	UsersDelete      Permission = "users:delete"
+	UsersRate        Permission = "users:rate"
+	RolesAssign      Permission = "roles:assign"
+	AuditRead        Permission = "audit:read"
+	SessionsRevoke   Permission = "sessions:revoke"
+	UsersUnlock      Permission = "users:unlock"
+	UsersRestore     Permission = "users:restore"
+	UsersPurge       Permission = "users:purge"
+	UsersReadDeleted Permission = "users:read-deleted"
+)
+
+// All lists every known permission, "*" in a role definition expands to it.
+var All = []Permission{UsersRead, UsersList, UsersUpdate, UsersDelete, UsersRate, RolesAssign, AuditRead, SessionsRevoke, UsersUnlock, UsersRestore, UsersPurge, UsersReadDeleted}
+
+const (
+	UserRole      = "user"
+	ModeratorRole = "moderator"
+	AdminRole     = "admin"
+)
+
+// DefaultRolePermissions is used when ROLE_PERMISSIONS isn't configured.
+const DefaultRolePermissions = "user=users:read,users:rate;moderator=users:read,users:list,users:rate;admin=*"
+
+// Roles maps every known role to its set of permissions.
+type Roles struct {
+	roles map[string]map[Permission]bool
+}
+
+// NewRoles parses role definitions like
+// "user=users:read,users:rate;admin=*". An empty definition gives the
+// default roles. Unknown permissions are rejected, so a typo in the config
+// doesn't silently lock a role out.
+func NewRoles(definition string) (*Roles, error) {
+	if strings.TrimSpace(definition) == "" {
+		definition = DefaultRolePermissions
+	}
+
+	known := map[Permission]bool{}
+	for _, permission := range All {
+		known[permission] = true
+	}
+
+	roles := &Roles{roles: map[string]map[Permission]bool{}}
+	for _, roleDefinition := range strings.Split(definition, ";") {
+		if strings.TrimSpace(roleDefinition) == "" {
+			continue
+		}
+
+		role, permissionList, ok := strings.Cut(roleDefinition, "=")
+		role = strings.TrimSpace(role)
+		if !ok || role == "" {
+			return nil, fmt.Errorf("role definition %q must look like role=permission,permission", roleDefinition)
+		}
+		if _, ok := roles.roles[role]; ok {
+			return nil, fmt.Errorf("duplicate role %q", role)
+		}
+
+		granted := map[Permission]bool{}
+		for _, name := range strings.Split(permissionList, ",") {
+			permission := Permission(strings.TrimSpace(name))
+			switch {
+			case permission == "":
+				continue
+			case permission == "*":
+				for _, p := range All {
+					granted[p] = true
+				}
+			case known[permission]:
+				granted[permission] = true
+			default:
+				return nil, fmt.Errorf("unknown permission %q of role %q", permission, role)
+			}
+		}
+		roles.roles[role] = granted
+	}
+
+	if !roles.Exists(UserRole) {
+		return nil, fmt.Errorf("default role %q must be defined", UserRole)
+	}
+
+	return roles, nil
+}
+
+// DefaultRoles returns the roles of DefaultRolePermissions.
+func DefaultRoles() *Roles {
+	roles, err := NewRoles(DefaultRolePermissions)
+	if err != nil {
+		panic(err)
+	}
+	return roles
+}
+
+func (r *Roles) Exists(role string) bool {
+	_, ok := r.roles[role]
+	return ok
+}
+
+// Has reports whether the role has all the permissions. Unknown roles have none.
+func (r *Roles) Has(role string, permissions ...Permission) bool {
+	granted, ok := r.roles[role]
+	if !ok {
+		return false
+	}
+
+	for _, permission := range permissions {
+		if !granted[permission] {
+			return false
+		}
+	}
+	return true
+}
+
+// Names returns all known role names in alphabetical order.
+func (r *Roles) Names() []string {
+	names := make([]string, 0, len(r.roles))
+	for role := range r.roles {
+		names = append(names, role)
+	}
+	sort.Strings(names)
+	return names
+}
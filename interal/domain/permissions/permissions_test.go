@@ -0,0 +1,63 @@
+package permissions
+
+import (
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func TestDefaultRoles(t *testing.T) {
+	roles := DefaultRoles()
+
+	testTable := []struct {
+		scenario    string
+		role        string
+		permissions []Permission
+		expected    bool
+	}{
+		{"user can rate", UserRole, []Permission{UsersRate}, true},
+		{"user can't list users", UserRole, []Permission{UsersList}, false},
+		{"moderator can list users", ModeratorRole, []Permission{UsersRead, UsersList}, true},
+		{"moderator can't delete users", ModeratorRole, []Permission{UsersDelete}, false},
+		{"moderator can't list deleted users", ModeratorRole, []Permission{UsersReadDeleted}, false},
+		{"admin can list deleted users", AdminRole, []Permission{UsersReadDeleted}, true},
+		{"admin has every permission", AdminRole, All, true},
+		{"unknown role has no permissions", "superadmin", []Permission{UsersRead}, false},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			assert.Equal(t, roles.Has(testCase.role, testCase.permissions...), testCase.expected)
+		})
+	}
+}
+
+func TestNewRoles(t *testing.T) {
+	testTable := []struct {
+		scenario      string
+		definition    string
+		expectedRoles []string
+		expectedError bool
+	}{
+		{"empty definition gives default roles", "", []string{AdminRole, ModeratorRole, UserRole}, false},
+		{"custom roles", "user=users:read; support = users:read, sessions:revoke", []string{"support", UserRole}, false},
+		{"unknown permission", "user=users:read,users:fly", nil, true},
+		{"duplicate role", "user=users:read;user=users:rate", nil, true},
+		{"malformed definition", "user", nil, true},
+		{"default role is missing", "admin=*", nil, true},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			roles, err := NewRoles(testCase.definition)
+			if testCase.expectedError {
+				assert.Equal(t, err != nil, true)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, roles.Names(), testCase.expectedRoles)
+		})
+	}
+}
@@ -1,8 +1,9 @@
 package requests
 
+import "time"
+
 type SignUpRequest struct {
 	UserName  string `json:"user_name" validate:"required,min=5"`
-	Role      string `json:"role" validate:"required,contains=user|contains=moderator|contains=admin"`
 	FirstName string `json:"first_name" validate:"required"`
 	LastName  string `json:"last_name" validate:"required"`
 	Password  string `json:"password" validate:"required,password,min=7"`
@@ -13,21 +14,48 @@ type SignInRequest struct {
 	Password string `json:"password"`
 }
 
+type ResetPasswordRequest struct {
+	Password string `json:"password" validate:"required,password,min=7"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type UpdateRequest struct {
 	UserName  string `json:"user_name" validate:"min=5"`
-	Role      string `json:"role" validate:"contains=user|contains=moderator|contains=admin"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 }
 
 type UpdateOwnRequest struct {
 	UserName  string `json:"user_name" validate:"min=5"`
-	Role      string `json:"role" validate:"contains=user|contains=moderator|contains=admin"`
 	Password  string `json:"password" validate:"omitempty,password,min=7"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 }
 
+type AssignRoleRequest struct {
+	Role string `json:"role" validate:"required,role"`
+}
+
+// GetUsersRequest is the query of the user list, limit and page are read
+// by MapContextToPagination.
+type GetUsersRequest struct {
+	Role          string     `query:"role" json:"role" validate:"omitempty,role"`
+	RatingGTE     *int       `query:"rating_gte" json:"rating_gte"`
+	RatingLTE     *int       `query:"rating_lte" json:"rating_lte"`
+	CreatedAfter  *time.Time `query:"created_after" json:"created_after"`
+	CreatedBefore *time.Time `query:"created_before" json:"created_before"`
+	Q             string     `query:"q" json:"q" validate:"omitempty,max=64"`
+}
+
+// SearchUsersRequest is the query of the user search, limit and page are read
+// by MapContextToPagination.
+type SearchUsersRequest struct {
+	Q string `query:"q" json:"q" validate:"required,max=64"`
+}
+
 type RateRequest struct {
 	Rate string `json:"rate"`
 }
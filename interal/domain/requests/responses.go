@@ -10,11 +10,23 @@ type SignUpInResponse struct {
 	Message string `json:"message"`
 }
 
+// HealthResponse lists the status of every readiness check, liveness has
+// none.
+type HealthResponse struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
 type GetUsersResponse struct {
 	Message       string             `json:"message"`
 	UsersResponse *models.Pagination `json:"users"`
 }
 
+type GetAuditEventsResponse struct {
+	Message        string             `json:"message"`
+	EventsResponse *models.Pagination `json:"audit_events"`
+}
+
 type UserResponse struct {
 	ID        uint       `json:"id"`
 	UserName  string     `json:"user_name"`
@@ -37,7 +49,23 @@ type UpdateUserResponce struct {
 	UserResponse *UserResponse `json:"user"`
 }
 
+// ErrorResponse is the body of every error response. It follows RFC 7807
+// problem details, with code, message, request_id and fields as extensions.
 type ErrorResponse struct {
-	Message  string `json:"message"`
-	HTTPCode int    `json:"err_code"`
+	Type      string                 `json:"type"`
+	Title     string                 `json:"title"`
+	Status    int                    `json:"status"`
+	Instance  string                 `json:"instance,omitempty"`
+	Code      string                 `json:"code"`
+	Message   string                 `json:"message"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Fields    []FieldErrorResponse   `json:"fields,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+type FieldErrorResponse struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 }
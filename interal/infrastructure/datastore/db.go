@@ -2,25 +2,137 @@ package datastore
 
 import (
 	"fmt"
+	"net/url"
+	"time"
 
 	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"git.foxminded.com.ua/3_REST_API/interal/config"
-	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
-func NewDB(c *config.Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:3306)/%s?charset=utf8mb4&parseTime=True&loc=Local", c.DBUser, c.DBPassword, c.DBHost, c.DBName)
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// NewDB opens the database, its schema is up to the Migrator. Zero pool
+// settings keep the defaults of database/sql.
+func NewDB(c *config.Config, gormLogger logger.Interface) (*gorm.DB, error) {
+	dialector, err := newDialector(c)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: gormLogger})
+	if err != nil {
+		return nil, apperrors.CanNotInitializeDBSessionErr.AppendMessage(err)
+	}
 
-	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, apperrors.CanNotInitializeDBSessionErr.AppendMessage(err)
 	}
-	db.AutoMigrate(&models.User{}, &models.RatedByUser{})
+	if db.Dialector.Name() == DriverSQLite {
+		// SQLite allows one writer at a time, and every connection to
+		// ":memory:" would open a database of its own, so the only
+		// connection is kept open
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetMaxIdleConns(1)
+		return db, nil
+	}
+
+	if c.DBMaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(c.DBMaxOpenConns)
+	}
+	if c.DBMaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(c.DBMaxIdleConns)
+	}
+	if c.DBConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(time.Second * time.Duration(c.DBConnMaxLifetime))
+	}
+	if c.DBConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(time.Second * time.Duration(c.DBConnMaxIdleTime))
+	}
 
 	return db, nil
 }
+
+// newDialector picks the driver of DB_DRIVER, MySQL when it isn't set.
+// DB_DSN is passed to the driver as is, otherwise the DSN is built from the
+// DB_* settings.
+func newDialector(c *config.Config) (gorm.Dialector, error) {
+	switch c.DBDriver {
+	case DriverMySQL, "":
+		dsn := c.DBDSN
+		if dsn == "" {
+			dsn = mysqlDSN(c)
+		}
+		return mysql.Open(dsn), nil
+	case DriverPostgres:
+		dsn := c.DBDSN
+		if dsn == "" {
+			dsn = postgresDSN(c)
+		}
+		return postgres.Open(dsn), nil
+	case DriverSQLite:
+		dsn := c.DBDSN
+		if dsn == "" {
+			dsn = sqliteDSN(c)
+		}
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, apperrors.CanNotInitializeDBSessionErr.AppendMessage(fmt.Errorf("unknown DB_DRIVER %q", c.DBDriver))
+	}
+}
+
+// mysqlDSN passes DB_TLS as the tls parameter: true, false, skip-verify,
+// preferred or the name of a registered TLS config.
+func mysqlDSN(c *config.Config) string {
+	port := c.DBPort
+	if port == "" {
+		port = "3306"
+	}
+
+	params := url.Values{}
+	params.Set("charset", "utf8mb4")
+	params.Set("parseTime", "True")
+	params.Set("loc", "Local")
+	if c.DBTLS != "" {
+		params.Set("tls", c.DBTLS)
+	}
+
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?%s", c.DBUser, c.DBPassword, c.DBHost, port, c.DBName, params.Encode())
+}
+
+// postgresDSN passes DB_TLS as the sslmode: disable, require, verify-ca or
+// verify-full.
+func postgresDSN(c *config.Config) string {
+	port := c.DBPort
+	if port == "" {
+		port = "5432"
+	}
+	sslMode := c.DBTLS
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	dsn := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     fmt.Sprintf("%s:%s", c.DBHost, port),
+		Path:     c.DBName,
+		RawQuery: url.Values{"sslmode": {sslMode}}.Encode(),
+	}
+	return dsn.String()
+}
+
+// sqliteDSN opens the DB_NAME file, or an in-memory database for
+// ":memory:", with foreign keys checked as the other databases do.
+func sqliteDSN(c *config.Config) string {
+	return fmt.Sprintf("file:%s?_foreign_keys=1", c.DBName)
+}
@@ -0,0 +1,125 @@
+package datastore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/logger"
+)
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDB(&config.Config{DBDriver: DriverSQLite, DBName: ":memory:"}, logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	latest := migrator.migrations[len(migrator.migrations)-1]
+
+	err = migrator.CheckUpToDate(ctx)
+	assert.True(t, errors.Is(err, &apperrors.SchemaOutdatedErr))
+
+	statuses, err := migrator.Status(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, statuses, len(migrator.migrations))
+	for _, status := range statuses {
+		assert.Nil(t, status.AppliedAt, status.Version)
+	}
+	assert.False(t, db.Migrator().HasTable(&schemaMigration{}), "status must not create schema_migrations")
+
+	applied, err := migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, migrator.migrations, applied)
+	assert.NoError(t, migrator.CheckUpToDate(ctx))
+	assert.True(t, db.Migrator().HasTable("users"))
+
+	applied, err = migrator.Up(ctx)
+	assert.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := migrator.Down(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, latest.Version, reverted.Version)
+
+	statuses, err = migrator.Status(ctx)
+	assert.NoError(t, err)
+	for _, status := range statuses {
+		assert.Equal(t, status.Version == latest.Version, status.AppliedAt == nil, status.Version)
+	}
+
+	for range migrator.migrations {
+		_, err := migrator.Down(ctx)
+		assert.NoError(t, err)
+	}
+	assert.False(t, db.Migrator().HasTable("users"))
+
+	reverted, err = migrator.Down(ctx)
+	assert.NoError(t, err)
+	assert.Nil(t, reverted)
+}
+
+func TestRatedByUsersUniqueRaterRecomputesRating(t *testing.T) {
+	ctx := context.Background()
+	db, err := NewDB(&config.Config{DBDriver: DriverSQLite, DBName: ":memory:"}, logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrations := migrator.migrations
+
+	migrator.migrations = migrations[:1]
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// user 1 was rated up twice by user 3 and down by user 4, user 2 has no
+	// duplicates and keeps its rating
+	for _, statement := range []string{
+		"INSERT INTO users (id, user_name, rating) VALUES (1, 'alice', 3), (2, 'bob', 7)",
+		"INSERT INTO rated_by_users (user_id, rated_by_user_id, rate) VALUES (1, 3, 'up'), (1, 3, 'up'), (1, 4, 'down'), (2, 3, 'up')",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrator.migrations = migrations[:2]
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var ratings []int
+	assert.NoError(t, db.Table("users").Order("id").Pluck("rating", &ratings).Error)
+	assert.Equal(t, []int{1, 7}, ratings)
+
+	var count int64
+	assert.NoError(t, db.Table("rated_by_users").Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestMigrationsOfEveryDriver(t *testing.T) {
+	for _, driver := range []string{DriverMySQL, DriverPostgres, DriverSQLite} {
+		migrations, err := loadMigrations(driver)
+		assert.NoError(t, err, driver)
+		assert.NotEmpty(t, migrations, driver)
+		for i, migration := range migrations {
+			assert.Equal(t, uint(i+1), migration.Version, driver)
+			assert.NotEmpty(t, splitStatements(migration.Up), driver)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	sql := "-- a comment; with a semicolon\nCREATE TABLE a (\n    id integer\n);\n\nDROP TABLE b;\nDROP TABLE c"
+	assert.Equal(t, []string{"CREATE TABLE a (\n    id integer\n);", "DROP TABLE b;", "DROP TABLE c"}, splitStatements(sql))
+}
@@ -0,0 +1,92 @@
+-- The tables as AutoMigrate used to create them, so a database which was
+-- set up by AutoMigrate is adopted as is.
+CREATE TABLE IF NOT EXISTS users (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    role longtext,
+    rating bigint,
+    user_name varchar(191),
+    first_name longtext,
+    last_name longtext,
+    password longtext,
+    created_at datetime(3) NULL,
+    updated_at datetime(3) NULL,
+    deleted_at datetime(3) NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY user_name (user_name),
+    KEY idx_users_deleted_at (deleted_at)
+);
+
+CREATE TABLE IF NOT EXISTS rated_by_users (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    user_id bigint unsigned,
+    rated_by_user_id bigint unsigned,
+    rate longtext,
+    created_at datetime(3) NULL,
+    updated_at datetime(3) NULL,
+    deleted_at datetime(3) NULL,
+    PRIMARY KEY (id),
+    KEY idx_rated_by_users_user_id (user_id),
+    KEY idx_rated_by_users_deleted_at (deleted_at),
+    CONSTRAINT fk_users_rated_by_users FOREIGN KEY (user_id) REFERENCES users (id)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    user_id bigint unsigned,
+    family_id varchar(64),
+    token_hash varchar(64),
+    expires_at datetime(3) NULL,
+    revoked_at datetime(3) NULL,
+    created_at datetime(3) NULL,
+    updated_at datetime(3) NULL,
+    PRIMARY KEY (id),
+    KEY idx_refresh_tokens_user_id (user_id),
+    KEY idx_refresh_tokens_family_id (family_id),
+    UNIQUE KEY idx_refresh_tokens_token_hash (token_hash)
+);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    jti varchar(64),
+    user_id bigint unsigned,
+    expires_at datetime(3) NULL,
+    created_at datetime(3) NULL,
+    PRIMARY KEY (id),
+    UNIQUE KEY idx_revoked_tokens_jti (jti),
+    KEY idx_revoked_tokens_expires_at (expires_at)
+);
+
+CREATE TABLE IF NOT EXISTS user_session_revocations (
+    user_id bigint unsigned NOT NULL,
+    revoked_before datetime(3) NULL,
+    updated_at datetime(3) NULL,
+    PRIMARY KEY (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id bigint unsigned NOT NULL AUTO_INCREMENT,
+    actor_id bigint unsigned,
+    target_id bigint unsigned,
+    action varchar(64),
+    changes text,
+    client_ip varchar(64),
+    request_id varchar(64),
+    created_at datetime(3) NULL,
+    PRIMARY KEY (id),
+    KEY idx_audit_events_actor_id (actor_id),
+    KEY idx_audit_events_target_id (target_id),
+    KEY idx_audit_events_action (action),
+    KEY idx_audit_events_created_at (created_at)
+);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    user_id bigint unsigned NOT NULL,
+    failed_attempts bigint,
+    successful_attempts bigint,
+    consecutive_failures bigint,
+    locked_until datetime(3) NULL,
+    last_failed_at datetime(3) NULL,
+    last_succeeded_at datetime(3) NULL,
+    updated_at datetime(3) NULL,
+    PRIMARY KEY (user_id)
+);
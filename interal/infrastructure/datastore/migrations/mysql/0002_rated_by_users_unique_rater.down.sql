@@ -0,0 +1 @@
+ALTER TABLE rated_by_users DROP INDEX idx_rated_by_users_user_rater;
@@ -0,0 +1 @@
+ALTER TABLE users ADD FULLTEXT INDEX idx_users_search (user_name, first_name, last_name);
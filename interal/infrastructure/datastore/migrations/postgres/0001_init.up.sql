@@ -0,0 +1,85 @@
+-- The tables as AutoMigrate used to create them, so a database which was
+-- set up by AutoMigrate is adopted as is.
+CREATE TABLE IF NOT EXISTS users (
+    id bigserial PRIMARY KEY,
+    role text,
+    rating bigint,
+    user_name text UNIQUE,
+    first_name text,
+    last_name text,
+    password text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+-- text comparison is case-sensitive, unlike the MySQL collation
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_user_name_lower ON users (LOWER(user_name));
+
+CREATE TABLE IF NOT EXISTS rated_by_users (
+    id bigserial PRIMARY KEY,
+    user_id bigint,
+    rated_by_user_id bigint,
+    rate text,
+    created_at timestamptz,
+    updated_at timestamptz,
+    deleted_at timestamptz,
+    CONSTRAINT fk_users_rated_by_users FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_rated_by_users_deleted_at ON rated_by_users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id bigserial PRIMARY KEY,
+    user_id bigint,
+    family_id varchar(64),
+    token_hash varchar(64),
+    expires_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz,
+    updated_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id bigserial PRIMARY KEY,
+    jti varchar(64),
+    user_id bigint,
+    expires_at timestamptz,
+    created_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_revoked_tokens_jti ON revoked_tokens (jti);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_session_revocations (
+    user_id bigint PRIMARY KEY,
+    revoked_before timestamptz,
+    updated_at timestamptz
+);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id bigserial PRIMARY KEY,
+    actor_id bigint,
+    target_id bigint,
+    action varchar(64),
+    changes text,
+    client_ip varchar(64),
+    request_id varchar(64),
+    created_at timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target_id ON audit_events (target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    user_id bigint PRIMARY KEY,
+    failed_attempts bigint,
+    successful_attempts bigint,
+    consecutive_failures bigint,
+    locked_until timestamptz,
+    last_failed_at timestamptz,
+    last_succeeded_at timestamptz,
+    updated_at timestamptz
+);
@@ -0,0 +1,29 @@
+-- a user rates another one once, later ratings update that row, so only
+-- the latest of any duplicates made by concurrent requests is kept. The
+-- rating of the rated users counts every duplicate, so it is recomputed from
+-- the kept rows first: every user starts with 1, "up" adds 1, "down" takes 1
+UPDATE users SET rating = 1 + (
+    SELECT COALESCE(SUM(CASE kept.rate WHEN 'up' THEN 1 WHEN 'down' THEN -1 ELSE 0 END), 0)
+    FROM rated_by_users kept
+    WHERE kept.user_id = users.id
+        AND kept.deleted_at IS NULL
+        AND NOT EXISTS (
+            SELECT 1 FROM rated_by_users newer
+            WHERE newer.user_id = kept.user_id
+                AND newer.rated_by_user_id = kept.rated_by_user_id
+                AND newer.id > kept.id
+        )
+)
+WHERE id IN (
+    SELECT user_id FROM rated_by_users
+    GROUP BY user_id, rated_by_user_id
+    HAVING COUNT(*) > 1
+);
+
+DELETE FROM rated_by_users older
+USING rated_by_users newer
+WHERE newer.user_id = older.user_id
+    AND newer.rated_by_user_id = older.rated_by_user_id
+    AND newer.id > older.id;
+
+CREATE UNIQUE INDEX idx_rated_by_users_user_rater ON rated_by_users (user_id, rated_by_user_id);
@@ -0,0 +1,2 @@
+-- the user search matches this very expression, see postgresSearchDocument
+CREATE INDEX idx_users_search ON users USING GIN (to_tsvector('simple', coalesce(user_name, '') || ' ' || coalesce(first_name, '') || ' ' || coalesce(last_name, '')));
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS user_session_revocations;
+DROP TABLE IF EXISTS revoked_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS rated_by_users;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,85 @@
+-- The tables as AutoMigrate used to create them, so a database which was
+-- set up by AutoMigrate is adopted as is.
+CREATE TABLE IF NOT EXISTS users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    role text,
+    rating integer,
+    user_name text UNIQUE,
+    first_name text,
+    last_name text,
+    password text,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+-- text comparison is case-sensitive, unlike the MySQL collation
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_user_name_lower ON users (LOWER(user_name));
+
+CREATE TABLE IF NOT EXISTS rated_by_users (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer,
+    rated_by_user_id integer,
+    rate text,
+    created_at datetime,
+    updated_at datetime,
+    deleted_at datetime,
+    CONSTRAINT fk_users_rated_by_users FOREIGN KEY (user_id) REFERENCES users (id)
+);
+CREATE INDEX IF NOT EXISTS idx_rated_by_users_deleted_at ON rated_by_users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    user_id integer,
+    family_id text,
+    token_hash text,
+    expires_at datetime,
+    revoked_at datetime,
+    created_at datetime,
+    updated_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    jti text,
+    user_id integer,
+    expires_at datetime,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_revoked_tokens_jti ON revoked_tokens (jti);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
+
+CREATE TABLE IF NOT EXISTS user_session_revocations (
+    user_id integer PRIMARY KEY,
+    revoked_before datetime,
+    updated_at datetime
+);
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id integer PRIMARY KEY AUTOINCREMENT,
+    actor_id integer,
+    target_id integer,
+    action text,
+    changes text,
+    client_ip text,
+    request_id text,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor_id ON audit_events (actor_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_target_id ON audit_events (target_id);
+CREATE INDEX IF NOT EXISTS idx_audit_events_action ON audit_events (action);
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events (created_at);
+
+CREATE TABLE IF NOT EXISTS login_attempts (
+    user_id integer PRIMARY KEY,
+    failed_attempts integer,
+    successful_attempts integer,
+    consecutive_failures integer,
+    locked_until datetime,
+    last_failed_at datetime,
+    last_succeeded_at datetime,
+    updated_at datetime
+);
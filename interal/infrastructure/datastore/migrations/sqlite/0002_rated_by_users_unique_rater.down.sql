@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_rated_by_users_user_rater;
@@ -0,0 +1,3 @@
+-- the user search uses LIKE on SQLite, no index helps it, the migration only
+-- keeps the versions of the drivers the same
+SELECT 1;
@@ -0,0 +1,2 @@
+-- a deleted user whose username is freed keeps it here, user_name is NULL
+ALTER TABLE users ADD COLUMN deleted_user_name text;
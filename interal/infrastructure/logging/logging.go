@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+)
+
+const (
+	ComponentApp  = "app"
+	ComponentHTTP = "http"
+	ComponentGORM = "gorm"
+)
+
+var components = map[string]bool{
+	ComponentApp:  true,
+	ComponentHTTP: true,
+	ComponentGORM: true,
+}
+
+// Logging writes JSON lines, one logger per component, each with its own
+// level.
+type Logging struct {
+	writer io.Writer
+	level  slog.Level
+	levels map[string]slog.Level
+}
+
+// New parses LOG_LEVEL, the level of every component, and LOG_LEVELS, e.g.
+// "gorm=warn,http=info", which overrides it per component.
+func New(c *config.Config, w io.Writer) (*Logging, error) {
+	l := &Logging{writer: w, levels: map[string]slog.Level{}}
+	if c.LogLevel != "" {
+		if err := l.level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+			return nil, apperrors.LoggingInitializeErr.AppendMessage(fmt.Errorf("LOG_LEVEL: %w", err))
+		}
+	}
+
+	for _, setting := range strings.Split(c.LogLevels, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		component, level, ok := strings.Cut(setting, "=")
+		if !ok || !components[component] {
+			return nil, apperrors.LoggingInitializeErr.AppendMessage(fmt.Errorf("LOG_LEVELS: %q isn't <component>=<level> of a known component", setting))
+		}
+		var componentLevel slog.Level
+		if err := componentLevel.UnmarshalText([]byte(level)); err != nil {
+			return nil, apperrors.LoggingInitializeErr.AppendMessage(fmt.Errorf("LOG_LEVELS: %w", err))
+		}
+		l.levels[component] = componentLevel
+	}
+	return l, nil
+}
+
+// Logger adds the component, the request ID and the trace ID of the context
+// to every line, and redacts secrets.
+func (l *Logging) Logger(component string) *slog.Logger {
+	level, ok := l.levels[component]
+	if !ok {
+		level = l.level
+	}
+	handler := slog.NewJSONHandler(l.writer, &slog.HandlerOptions{Level: level, ReplaceAttr: redact})
+	return slog.New(contextHandler{handler}).With("component", component)
+}
@@ -0,0 +1,154 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/datastore"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// lines decodes the JSON lines written to buf.
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var decoded []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		decoded = append(decoded, entry)
+	}
+	return decoded
+}
+
+func TestNew(t *testing.T) {
+	testTable := []struct {
+		scenario  string
+		logLevel  string
+		logLevels string
+		err       error
+	}{
+		{"defaults", "", "", nil},
+		{"levels of components", "debug", "gorm=warn, http=error", nil},
+		{"unknown level", "verbose", "", &apperrors.LoggingInitializeErr},
+		{"unknown component", "info", "db=warn", &apperrors.LoggingInitializeErr},
+		{"unknown level of a component", "info", "gorm=loud", &apperrors.LoggingInitializeErr},
+		{"setting without a level", "info", "gorm", &apperrors.LoggingInitializeErr},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			_, err := New(&config.Config{LogLevel: tc.logLevel, LogLevels: tc.logLevels}, &bytes.Buffer{})
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err))
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logs, err := New(&config.Config{LogLevel: "info", LogLevels: "gorm=warn,http=debug"}, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("levels are per component", func(t *testing.T) {
+		buf.Reset()
+		logs.Logger(ComponentApp).Debug("hidden")
+		logs.Logger(ComponentApp).Info("shown")
+		logs.Logger(ComponentGORM).Info("hidden")
+		logs.Logger(ComponentHTTP).Debug("shown")
+
+		entries := lines(t, buf)
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, "app", entries[0]["component"])
+			assert.Equal(t, "INFO", entries[0]["level"])
+			assert.Equal(t, "http", entries[1]["component"])
+			assert.Equal(t, "DEBUG", entries[1]["level"])
+		}
+	})
+
+	t.Run("secrets are redacted", func(t *testing.T) {
+		buf.Reset()
+		logs.Logger(ComponentApp).Info("sign in",
+			"user_name", "JohnHall",
+			"password", "Secret1!",
+			"refresh_token", "eyJhbGciOi",
+			"Set-Cookie", "Authorization=eyJhbGciOi",
+			"headers", map[string]string{"Accept": "*/*"},
+		)
+		logs.Logger(ComponentApp).WithGroup("cookies").Info("group", "session", "abc")
+
+		entries := lines(t, buf)
+		assert.Equal(t, "JohnHall", entries[0]["user_name"])
+		assert.Equal(t, redacted, entries[0]["password"])
+		assert.Equal(t, redacted, entries[0]["refresh_token"])
+		assert.Equal(t, redacted, entries[0]["Set-Cookie"])
+		assert.Equal(t, map[string]interface{}{"session": redacted}, entries[1]["cookies"])
+		assert.NotContains(t, buf.String(), "Secret1!")
+		assert.NotContains(t, buf.String(), "eyJhbGciOi")
+	})
+
+	t.Run("request and trace IDs come from the context", func(t *testing.T) {
+		buf.Reset()
+		span := trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID: trace.TraceID{1, 2, 3},
+			SpanID:  trace.SpanID{4, 5, 6},
+		})
+		ctx := trace.ContextWithSpanContext(WithRequestID(context.Background(), "request-id"), span)
+		logs.Logger(ComponentApp).InfoContext(ctx, "with IDs")
+		logs.Logger(ComponentApp).Info("without IDs")
+
+		entries := lines(t, buf)
+		assert.Equal(t, "request-id", entries[0]["request_id"])
+		assert.Equal(t, span.TraceID().String(), entries[0]["trace_id"])
+		assert.Equal(t, span.SpanID().String(), entries[0]["span_id"])
+		assert.NotContains(t, entries[1], "request_id")
+		assert.NotContains(t, entries[1], "trace_id")
+	})
+}
+
+func TestGormLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logs, err := New(&config.Config{LogLevels: "gorm=debug"}, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := datastore.NewDB(&config.Config{DBDriver: datastore.DriverSQLite, DBName: ":memory:"}, NewGormLogger(logs.Logger(ComponentGORM)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, db.Exec("CREATE TABLE users (id integer PRIMARY KEY, user_name text, password text)").Error)
+	buf.Reset()
+
+	ctx := WithRequestID(context.Background(), "request-id")
+	assert.NoError(t, db.WithContext(ctx).Exec("INSERT INTO users (user_name, password) VALUES (?, ?)", "JohnHall", "$2a$10$hash").Error)
+	assert.Error(t, db.WithContext(ctx).Find(&[]models.User{}, "no_such_column = ?", "JohnHall").Error)
+
+	entries := lines(t, buf)
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "DEBUG", entries[0]["level"])
+		assert.Equal(t, "INSERT INTO users (user_name, password) VALUES (?, ?)", entries[0]["sql"])
+		assert.Equal(t, "request-id", entries[0]["request_id"])
+		assert.Equal(t, "ERROR", entries[1]["level"])
+		assert.Equal(t, "query failed", entries[1]["msg"])
+		assert.Contains(t, entries[1]["error"], "no_such_column")
+	}
+	assert.NotContains(t, buf.String(), "$2a$10$hash")
+	assert.NotContains(t, buf.String(), "JohnHall")
+}
@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"log/slog"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys redact every attribute whose key, or the key of a group it
+// is in, contains one of them: password, password_hash, refresh_token,
+// Set-Cookie, Authorization, ...
+var sensitiveKeys = []string{"password", "token", "cookie", "authorization", "secret"}
+
+func redact(groups []string, attr slog.Attr) slog.Attr {
+	if isSensitive(attr.Key) {
+		return slog.String(attr.Key, redacted)
+	}
+	for _, group := range groups {
+		if isSensitive(group) {
+			return slog.String(attr.Key, redacted)
+		}
+	}
+	return attr
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, sensitive := range sensitiveKeys {
+		if strings.Contains(key, sensitive) {
+			return true
+		}
+	}
+	return false
+}
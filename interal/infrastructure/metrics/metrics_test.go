@@ -0,0 +1,129 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/datastore"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm/logger"
+)
+
+// stubUserInteractor fails the calls with err. The methods it doesn't
+// override panic on the nil interface.
+type stubUserInteractor struct {
+	interactor.UserInteractor
+	err error
+}
+
+func (s *stubUserInteractor) SignIn(ctx context.Context, name, password string) (*interactor.AuthTokens, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &interactor.AuthTokens{}, nil
+}
+
+func (s *stubUserInteractor) SignUp(ctx context.Context, user *models.User) (*interactor.AuthTokens, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &interactor.AuthTokens{}, nil
+}
+
+func (s *stubUserInteractor) RefreshTokens(ctx context.Context, refreshToken string) (*interactor.AuthTokens, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &interactor.AuthTokens{}, nil
+}
+
+func (s *stubUserInteractor) RateUser(ctx context.Context, myID uint, username, rate string) (*models.User, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &models.User{}, nil
+}
+
+func TestInstrumentUserInteractor(t *testing.T) {
+	ctx := context.Background()
+	m := New()
+	succeeding := InstrumentUserInteractor(&stubUserInteractor{}, m)
+	failing := InstrumentUserInteractor(&stubUserInteractor{err: apperrors.InvalidCredentialsErr.AppendMessage(errors.New("wrong password"))}, m)
+	broken := InstrumentUserInteractor(&stubUserInteractor{err: errors.New("db is down")}, m)
+
+	succeeding.SignIn(ctx, "JohnHall", "password")
+	failing.SignIn(ctx, "JohnHall", "password")
+	failing.SignIn(ctx, "JohnHall", "password")
+	broken.SignIn(ctx, "JohnHall", "password")
+	succeeding.SignUp(ctx, &models.User{})
+	succeeding.RefreshTokens(ctx, "token")
+	failing.RefreshTokens(ctx, "token")
+	succeeding.RateUser(ctx, 1, "JohnHall", "up")
+	failing.RateUser(ctx, 1, "JohnHall", "down")
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.SignIns.WithLabelValues("success", "")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.SignIns.WithLabelValues("failure", apperrors.InvalidCredentialsErr.Code)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.SignIns.WithLabelValues("failure", apperrors.InternalErr.Code)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.SignUps.WithLabelValues("success", "")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.TokensIssued.WithLabelValues(GrantSignIn)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.TokensIssued.WithLabelValues(GrantSignUp)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.TokensIssued.WithLabelValues(GrantRefresh)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Ratings.WithLabelValues("up", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.Ratings.WithLabelValues("down", "failure")))
+}
+
+func TestGormPlugin(t *testing.T) {
+	m := New()
+	db, err := datastore.NewDB(&config.Config{DBDriver: datastore.DriverSQLite, DBName: ":memory:"}, logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := datastore.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Use(GormPlugin{Metrics: m, DBName: "test"}); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NoError(t, db.Exec("UPDATE users SET rating = 0").Error)
+	assert.NoError(t, db.Create(&models.User{UserName: "JohnHall"}).Error)
+	assert.Error(t, db.Table("missing").Create(map[string]interface{}{"id": 1}).Error)
+
+	count := func(labels ...string) uint64 {
+		metric := &dto.Metric{}
+		if err := m.DBQueryDuration.WithLabelValues(labels...).(prometheus.Histogram).Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.GetHistogram().GetSampleCount()
+	}
+	assert.Equal(t, uint64(1), count("create", "users", "success"))
+	assert.Equal(t, uint64(1), count("create", "missing", "error"))
+	assert.Equal(t, uint64(1), count("raw", "", "success"))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.SignIns.WithLabelValues("success", "").Inc()
+
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), `usermanager_sign_ins_total{code="",result="success"} 1`))
+	assert.True(t, strings.Contains(rec.Body.String(), "go_goroutines"))
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+)
+
+const (
+	resultSuccess = "success"
+	resultFailure = "failure"
+
+	GrantSignIn  = "sign_in"
+	GrantSignUp  = "sign_up"
+	GrantRefresh = "refresh"
+)
+
+// userInteractor counts the auth and rating outcomes of the wrapped
+// interactor, every other method is passed through as is.
+type userInteractor struct {
+	interactor.UserInteractor
+	metrics *Metrics
+}
+
+func InstrumentUserInteractor(uI interactor.UserInteractor, m *Metrics) interactor.UserInteractor {
+	return &userInteractor{uI, m}
+}
+
+func (uI *userInteractor) SignUp(ctx context.Context, user *models.User) (*interactor.AuthTokens, error) {
+	tokens, err := uI.UserInteractor.SignUp(ctx, user)
+	uI.metrics.SignUps.WithLabelValues(result(err), errorCode(err)).Inc()
+	if err == nil {
+		uI.metrics.TokensIssued.WithLabelValues(GrantSignUp).Inc()
+	}
+	return tokens, err
+}
+
+func (uI *userInteractor) SignIn(ctx context.Context, name, password string) (*interactor.AuthTokens, error) {
+	tokens, err := uI.UserInteractor.SignIn(ctx, name, password)
+	uI.metrics.SignIns.WithLabelValues(result(err), errorCode(err)).Inc()
+	if err == nil {
+		uI.metrics.TokensIssued.WithLabelValues(GrantSignIn).Inc()
+	}
+	return tokens, err
+}
+
+func (uI *userInteractor) RefreshTokens(ctx context.Context, refreshToken string) (*interactor.AuthTokens, error) {
+	tokens, err := uI.UserInteractor.RefreshTokens(ctx, refreshToken)
+	if err == nil {
+		uI.metrics.TokensIssued.WithLabelValues(GrantRefresh).Inc()
+	}
+	return tokens, err
+}
+
+// RateUser labels ratings by the rate of the request, which is validated
+// before, so the label set stays bounded.
+func (uI *userInteractor) RateUser(ctx context.Context, myID uint, username, rate string) (*models.User, error) {
+	user, err := uI.UserInteractor.RateUser(ctx, myID, username, rate)
+	uI.metrics.Ratings.WithLabelValues(rate, result(err)).Inc()
+	return user, err
+}
+
+func result(err error) string {
+	if err != nil {
+		return resultFailure
+	}
+	return resultSuccess
+}
+
+// errorCode is the code of an app error, "" on success and "INTERNAL_ERR"
+// for other errors, as the error handler reports them.
+func errorCode(err error) string {
+	if err == nil {
+		return ""
+	}
+	if appErr, ok := apperrors.As(err); ok {
+		return appErr.Code
+	}
+	return apperrors.InternalErr.Code
+}
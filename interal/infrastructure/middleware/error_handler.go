@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/mappers"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+)
+
+const mimeApplicationProblemJSON = "application/problem+json"
+
+// ErrorHandler is the echo HTTPErrorHandler. Handlers and middlewares only
+// return errors: it logs every error once and writes it as an error response.
+func ErrorHandler(logger *slog.Logger) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		if c.Response().Committed {
+			return
+		}
+
+		appErr := toAppError(err)
+		logError(logger, c, appErr, err)
+
+		if appErr.RetryAfter > 0 {
+			c.Response().Header().Set(echo.HeaderRetryAfter, strconv.FormatInt(appErr.RetryAfterSeconds(), 10))
+		}
+
+		if c.Request().Method == http.MethodHead {
+			err = c.NoContent(appErr.HTTPCode)
+		} else {
+			err = writeErrorResponse(c, appErr)
+		}
+		if err != nil {
+			logger.ErrorContext(c.Request().Context(), "can't write the error response", "error", err.Error())
+		}
+	}
+}
+
+// toAppError maps any error to an AppError. Errors which aren't known are
+// internal and get a generic 500, so their text never reaches the client.
+func toAppError(err error) *apperrors.AppError {
+	if appErr, ok := apperrors.As(err); ok {
+		return appErr
+	}
+
+	var httpErr *echo.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErrorToAppError(httpErr)
+	}
+
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperrors.RecordNotFoundErr.AppendMessage(err)
+	case repository.IsUniqueViolation(err):
+		return apperrors.DuplicateKeyErr.AppendMessage(err)
+	default:
+		return apperrors.InternalErr.AppendMessage(err)
+	}
+}
+
+// httpErrorToAppError maps errors of echo and its middlewares (unknown
+// routes, malformed JWT, ...), the code is made of the status text.
+func httpErrorToAppError(httpErr *echo.HTTPError) *apperrors.AppError {
+	if httpErr.Code >= http.StatusInternalServerError {
+		return apperrors.InternalErr.AppendMessage(httpErr)
+	}
+
+	message, ok := httpErr.Message.(string)
+	if !ok {
+		message = http.StatusText(httpErr.Code)
+	}
+
+	appErr := &apperrors.AppError{
+		Message:  message,
+		Code:     strings.ToUpper(strings.ReplaceAll(http.StatusText(httpErr.Code), " ", "_")),
+		HTTPCode: httpErr.Code,
+	}
+	if httpErr.Internal != nil {
+		return appErr.AppendMessage(httpErr.Internal)
+	}
+	return appErr
+}
+
+// logError logs server errors as errors and client errors as warnings, the
+// request ID comes from the context.
+func logError(logger *slog.Logger, c echo.Context, appErr *apperrors.AppError, err error) {
+	level := slog.LevelWarn
+	if appErr.HTTPCode >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	logger.LogAttrs(c.Request().Context(), level, "request failed",
+		slog.String("method", c.Request().Method),
+		slog.String("path", c.Request().URL.Path),
+		slog.Int("status", appErr.HTTPCode),
+		slog.String("code", appErr.Code),
+		slog.String("error", err.Error()),
+	)
+}
+
+func writeErrorResponse(c echo.Context, appErr *apperrors.AppError) error {
+	c.Response().Header().Set(echo.HeaderContentType, mimeApplicationProblemJSON)
+	c.Response().WriteHeader(appErr.HTTPCode)
+	return c.Echo().JSONSerializer.Serialize(c, mappers.MapAppErrorToErrorResponse(c, appErr), "")
+}
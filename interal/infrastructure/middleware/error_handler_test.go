@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
+	"github.com/go-sql-driver/mysql"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestErrorHandler(t *testing.T) {
+
+	testTable := []struct {
+		scenario         string
+		method           string
+		err              error
+		expectedResponse requests.ErrorResponse
+		retryAfter       string
+	}{
+		{
+			"app error keeps its code and fields but not the cause",
+			http.MethodPost,
+			apperrors.ValidatorErr.WithFields(apperrors.FieldError{Field: "first_name", Rule: "required", Message: "the field is required"}).
+				AppendMessage(errors.New("Key: 'SignUpRequest.FirstName' failed")),
+			requests.ErrorResponse{
+				Type:    "about:blank",
+				Title:   "Bad Request",
+				Status:  http.StatusBadRequest,
+				Code:    apperrors.ValidatorErr.Code,
+				Message: apperrors.ValidatorErr.Message,
+				Fields:  []requests.FieldErrorResponse{{Field: "first_name", Rule: "required", Message: "the field is required"}},
+			},
+			"",
+		},
+		{
+			"rate limited app error sets Retry-After",
+			http.MethodPost,
+			apperrors.TooManyAttemptsErr.WithRetryAfter(1500*time.Millisecond).WithDetail("limit", 20),
+			requests.ErrorResponse{
+				Type:    "about:blank",
+				Title:   "Too Many Requests",
+				Status:  http.StatusTooManyRequests,
+				Code:    apperrors.TooManyAttemptsErr.Code,
+				Message: apperrors.TooManyAttemptsErr.Message,
+				Details: map[string]interface{}{"limit": float64(20), "retry_after": float64(2)},
+			},
+			"2",
+		},
+		{
+			"wrapped app error",
+			http.MethodGet,
+			fmt.Errorf("find user: %w", &apperrors.UserNotFoundErr),
+			requests.ErrorResponse{
+				Type:    "about:blank",
+				Title:   "Bad Request",
+				Status:  http.StatusBadRequest,
+				Code:    apperrors.UserNotFoundErr.Code,
+				Message: apperrors.UserNotFoundErr.Message,
+			},
+			"",
+		},
+		{
+			"echo error",
+			http.MethodGet,
+			echo.ErrNotFound,
+			requests.ErrorResponse{
+				Type:    "about:blank",
+				Title:   "Not Found",
+				Status:  http.StatusNotFound,
+				Code:    "NOT_FOUND",
+				Message: "Not Found",
+			},
+			"",
+		},
+		{
+			"echo error with an internal error",
+			http.MethodGet,
+			echo.NewHTTPError(http.StatusUnauthorized, "invalid or expired jwt").SetInternal(errors.New("token is expired")),
+			requests.ErrorResponse{
+				Type:    "about:blank",
+				Title:   "Unauthorized",
+				Status:  http.StatusUnauthorized,
+				Code:    "UNAUTHORIZED",
+				Message: "invalid or expired jwt",
+			},
+			"",
+		},
+		{
+			"gorm record not found",
+			http.MethodGet,
+			gorm.ErrRecordNotFound,
+			requests.ErrorResponse{
+				Type:    "about:blank",
+				Title:   "Not Found",
+				Status:  http.StatusNotFound,
+				Code:    apperrors.RecordNotFoundErr.Code,
+				Message: apperrors.RecordNotFoundErr.Message,
+			},
+			"",
+		},
+		{
+			"duplicate key",
+			http.MethodPost,
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'JohnHall' for key 'users.user_name'"},
+			requests.ErrorResponse{
+				Type:    "about:blank",
+				Title:   "Conflict",
+				Status:  http.StatusConflict,
+				Code:    apperrors.DuplicateKeyErr.Code,
+				Message: apperrors.DuplicateKeyErr.Message,
+			},
+			"",
+		},
+		{
+			"unknown error doesn't leak",
+			http.MethodGet,
+			errors.New("dial tcp 10.0.0.1:3306: connect: connection refused"),
+			requests.ErrorResponse{
+				Type:    "about:blank",
+				Title:   "Internal Server Error",
+				Status:  http.StatusInternalServerError,
+				Code:    apperrors.InternalErr.Code,
+				Message: apperrors.InternalErr.Message,
+			},
+			"",
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(tc.method, "/api/v1/users", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Response().Header().Set(echo.HeaderXRequestID, "request-id")
+
+			ErrorHandler(slog.Default())(tc.err, c)
+
+			assert.Equal(t, tc.expectedResponse.Status, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get(echo.HeaderContentType))
+			assert.Equal(t, tc.retryAfter, rec.Header().Get(echo.HeaderRetryAfter))
+
+			var response requests.ErrorResponse
+			if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response)) {
+				tc.expectedResponse.Instance = "/api/v1/users"
+				tc.expectedResponse.RequestID = "request-id"
+				assert.Equal(t, tc.expectedResponse, response)
+			}
+		})
+	}
+}
+
+func TestErrorHandlerHeadRequest(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodHead, "/api/v1/users", nil)
+	rec := httptest.NewRecorder()
+
+	ErrorHandler(slog.Default())(echo.ErrNotFound, e.NewContext(req, rec))
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Empty(t, rec.Body.String())
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// maxRequestIDLength bounds the X-Request-ID a client may send, longer IDs
+// are replaced by a generated one.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware keeps the X-Request-ID of the request or generates
+// one, sends it back in the response header and puts it into the request
+// context, so every log line of the request carries it.
+func RequestIDMiddleware() echo.MiddlewareFunc {
+	return middleware.RequestIDWithConfig(middleware.RequestIDConfig{
+		RequestIDHandler: func(c echo.Context, requestID string) {
+			if len(requestID) > maxRequestIDLength {
+				requestID = middleware.DefaultRequestIDConfig.Generator()
+				c.Response().Header().Set(echo.HeaderXRequestID, requestID)
+			}
+			c.SetRequest(c.Request().WithContext(logging.WithRequestID(c.Request().Context(), requestID)))
+		},
+	})
+}
+
+// AccessLogMiddleware logs a line per request, at error level for server
+// errors. Like echo's Logger it lets the error handler write the error
+// first, to see the status sent.
+func AccessLogMiddleware(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			// echo sets the request path when no route matches
+			route := c.Path()
+			if route == "" || errors.Is(err, echo.ErrNotFound) {
+				route = unmatchedRoute
+			}
+			request, response := c.Request(), c.Response()
+			level := slog.LevelInfo
+			if response.Status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			logger.LogAttrs(request.Context(), level, "request",
+				slog.String("method", request.Method),
+				slog.String("route", route),
+				slog.String("path", request.URL.Path),
+				slog.Int("status", response.Status),
+				slog.Float64("latency_ms", milliseconds(time.Since(start))),
+				slog.Int64("bytes_out", response.Size),
+				slog.String("remote_ip", c.RealIP()),
+				slog.String("user_agent", request.UserAgent()),
+			)
+
+			return err
+		}
+	}
+}
+
+// LogPanic logs a panic which the Recover middleware caught, with its stack.
+// The error handler answers it with a 500.
+func LogPanic(logger *slog.Logger) middleware.LogErrorFunc {
+	return func(c echo.Context, err error, stack []byte) error {
+		logger.ErrorContext(c.Request().Context(), "panic recovered", "error", err.Error(), "stack", string(stack))
+		return err
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/logging"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggingMiddlewares(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logs, err := logging.New(&config.Config{LogLevel: "info"}, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := logs.Logger(logging.ComponentHTTP)
+
+	var handlerRequestID string
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(logger)
+	e.Use(RequestIDMiddleware())
+	e.Use(AccessLogMiddleware(logger))
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{LogErrorFunc: LogPanic(logger)}))
+	e.GET("/user/:id", func(c echo.Context) error {
+		handlerRequestID = logging.RequestID(c.Request().Context())
+		switch c.Param("id") {
+		case "500":
+			return errors.New("db is down")
+		case "panic":
+			panic("nil map")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve := func(path, requestID string) (*httptest.ResponseRecorder, []map[string]interface{}) {
+		buf.Reset()
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		if requestID != "" {
+			request.Header.Set(echo.HeaderXRequestID, requestID)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, request)
+
+		var entries []map[string]interface{}
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			entry := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(line), &entry); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry)
+		}
+		return rec, entries
+	}
+
+	t.Run("request ID of the client is kept", func(t *testing.T) {
+		rec, entries := serve("/user/1", "client-request-id")
+
+		assert.Equal(t, "client-request-id", rec.Header().Get(echo.HeaderXRequestID))
+		assert.Equal(t, "client-request-id", handlerRequestID)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "client-request-id", entries[0]["request_id"])
+			assert.Equal(t, "/user/:id", entries[0]["route"])
+			assert.Equal(t, float64(http.StatusOK), entries[0]["status"])
+			assert.Equal(t, "INFO", entries[0]["level"])
+		}
+	})
+
+	t.Run("request ID is generated", func(t *testing.T) {
+		rec, entries := serve("/user/1", "")
+
+		requestID := rec.Header().Get(echo.HeaderXRequestID)
+		assert.NotEmpty(t, requestID)
+		assert.Equal(t, requestID, entries[0]["request_id"])
+	})
+
+	t.Run("too long request ID is replaced", func(t *testing.T) {
+		rec, entries := serve("/user/1", strings.Repeat("x", maxRequestIDLength+1))
+
+		requestID := rec.Header().Get(echo.HeaderXRequestID)
+		assert.NotEmpty(t, requestID)
+		assert.LessOrEqual(t, len(requestID), maxRequestIDLength)
+		assert.Equal(t, requestID, entries[0]["request_id"])
+	})
+
+	t.Run("server errors are logged with the request ID", func(t *testing.T) {
+		_, entries := serve("/user/500", "request-id")
+
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, "request failed", entries[0]["msg"])
+			assert.Equal(t, "db is down", entries[0]["error"])
+			assert.Equal(t, "request-id", entries[0]["request_id"])
+			assert.Equal(t, "ERROR", entries[1]["level"])
+			assert.Equal(t, float64(http.StatusInternalServerError), entries[1]["status"])
+		}
+	})
+
+	t.Run("panics are logged with the stack", func(t *testing.T) {
+		rec, entries := serve("/user/panic", "request-id")
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.Equal(t, "panic recovered", entries[0]["msg"])
+		assert.Equal(t, "request-id", entries[0]["request_id"])
+		assert.Contains(t, entries[0]["stack"], "goroutine")
+	})
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/metrics"
+	"github.com/labstack/echo/v4"
+)
+
+// unmatchedRoute labels requests which match no route, so paths sent by
+// clients never become label values.
+const unmatchedRoute = "unmatched"
+
+// MetricsMiddleware counts requests and times them by route template, e.g.
+// /api/v1/restricted/user/:id, and status. Like echo's Logger it lets the
+// error handler write the error first, to see the status sent.
+func MetricsMiddleware(m *metrics.Metrics) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			// echo sets the request path when no route matches
+			route := c.Path()
+			if route == "" || errors.Is(err, echo.ErrNotFound) {
+				route = unmatchedRoute
+			}
+			status := strconv.Itoa(c.Response().Status)
+			m.HTTPRequests.WithLabelValues(c.Request().Method, route, status).Inc()
+			m.HTTPRequestDuration.WithLabelValues(c.Request().Method, route, status).Observe(time.Since(start).Seconds())
+
+			return err
+		}
+	}
+}
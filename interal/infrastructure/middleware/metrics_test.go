@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/metrics"
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	m := metrics.New()
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(slog.Default())
+	e.Use(MetricsMiddleware(m))
+	e.GET("/user/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return &apperrors.UserNotFoundErr
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/user/1", "/user/2", "/user/0", "/no/such/path/42"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues(http.MethodGet, "/user/:id", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues(http.MethodGet, "/user/:id", "400")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.HTTPRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.HTTPRequests))
+}
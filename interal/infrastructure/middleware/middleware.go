@@ -1,42 +1,59 @@
 package middleware
 
 import (
-	"errors"
+	"fmt"
 
 	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
-	"git.foxminded.com.ua/3_REST_API/interal/domain/mappers"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
 	"git.foxminded.com.ua/3_REST_API/interal/interface/controller"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
 	"github.com/labstack/echo/v4"
 )
 
-func AdminRoleMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		claims := controller.FetchUserClaim(c)
+// RequirePermission lets the request through only when the role of the
+// authenticated user has all the given permissions.
+func RequirePermission(roles *permissions.Roles, required ...permissions.Permission) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims := controller.FetchUserClaim(c)
 
-		switch {
-		case claims.User.Role == "user":
-			appErr := apperrors.WrongRoleErr.AppendMessage(errors.New("you are have a role: 'user'"))
-			c.Logger().Error(appErr.Error())
-			return mappers.MapAppErrorToHTTPError(appErr)
-		case claims.User.Role == "moderator":
-			appErr := apperrors.WrongRoleErr.AppendMessage(errors.New("you are have a role: 'moderator'"))
-			c.Logger().Error(appErr.Error())
-			return mappers.MapAppErrorToHTTPError(appErr)
+			if !roles.Has(claims.User.Role, required...) {
+				return apperrors.WrongRoleErr.AppendMessage(fmt.Errorf("you are have a role: '%s' without permissions %v", claims.User.Role, required))
+			}
+			return next(c)
 		}
-		return next(c)
 	}
 }
 
-func ModeratorRoleMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+// AuditActorMiddleware puts the authenticated user, the client IP and the
+// request ID into the request context, so mutations record who made them.
+func AuditActorMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		claims := controller.FetchUserClaim(c)
-
-		switch {
-		case claims.User.Role == "user":
-			appErr := apperrors.WrongRoleErr.AppendMessage(errors.New("you are have a role: 'user'"))
-			c.Logger().Error(appErr.Error())
-			return mappers.MapAppErrorToHTTPError(appErr)
+		actor := interactor.AuditActor{
+			UserID:    controller.FetchUserClaim(c).User.ID,
+			ClientIP:  c.RealIP(),
+			RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
 		}
+		c.SetRequest(c.Request().WithContext(interactor.WithAuditActor(c.Request().Context(), actor)))
+
 		return next(c)
 	}
 }
+
+// RateLimitMiddleware limits requests per client IP and answers 429 with a
+// Retry-After header when the limit is exceeded.
+func RateLimitMiddleware(limiter *ratelimit.Limiter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			wait, err := limiter.Allow(c.Request().Context(), c.RealIP())
+			if err != nil {
+				return apperrors.RateLimitErr.AppendMessage(err)
+			}
+			if wait > 0 {
+				return apperrors.TooManyAttemptsErr.WithRetryAfter(wait).WithDetail("limit", limiter.Limit())
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/tracing"
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts a server span named after the method and route
+// template, e.g. GET /api/v1/restricted/user/:id, continuing the trace of an
+// incoming traceparent header. The span is put in the request context, so
+// the interactor, repository and GORM spans become its children.
+func TracingMiddleware(t *tracing.Tracing) echo.MiddlewareFunc {
+	tracer := t.Tracer()
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			ctx := t.Propagator.Extract(request.Context(), propagation.HeaderCarrier(request.Header))
+			ctx, span := tracer.Start(ctx, request.Method, trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPMethod(request.Method)))
+			defer span.End()
+			c.SetRequest(request.WithContext(ctx))
+
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			// echo sets the request path when no route matches
+			route := c.Path()
+			if route == "" || errors.Is(err, echo.ErrNotFound) {
+				route = unmatchedRoute
+			}
+			status := c.Response().Status
+			span.SetName(fmt.Sprintf("%s %s", request.Method, route))
+			span.SetAttributes(semconv.HTTPRoute(route), semconv.HTTPStatusCode(status))
+			// client errors are the client's, only 5xx fail the server span
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+				if err != nil {
+					span.RecordError(err)
+				}
+			}
+
+			return err
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/tracing"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracingMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tr := tracing.NewWithProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	var handlerSpan trace.SpanContext
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler(slog.Default())
+	e.Use(TracingMiddleware(tr))
+	e.GET("/user/:id", func(c echo.Context) error {
+		handlerSpan = trace.SpanContextFromContext(c.Request().Context())
+		switch c.Param("id") {
+		case "0":
+			return &apperrors.UserNotFoundErr
+		case "500":
+			return errors.New("db is down")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	serve := func(path, traceparent string) sdktrace.ReadOnlySpan {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		if traceparent != "" {
+			request.Header.Set("traceparent", traceparent)
+		}
+		e.ServeHTTP(httptest.NewRecorder(), request)
+		spans := recorder.Ended()
+		return spans[len(spans)-1]
+	}
+
+	t.Run("continues the trace of traceparent", func(t *testing.T) {
+		span := serve("/user/1", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+		assert.Equal(t, "GET /user/:id", span.Name())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+		assert.True(t, span.Parent().IsRemote())
+		assert.Equal(t, span.SpanContext(), handlerSpan, "the handler sees the span in the request context")
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	})
+
+	t.Run("starts a trace without traceparent", func(t *testing.T) {
+		span := serve("/user/1", "")
+
+		assert.False(t, span.Parent().IsValid())
+		assert.True(t, span.SpanContext().IsValid())
+	})
+
+	t.Run("client errors don't fail the span", func(t *testing.T) {
+		span := serve("/user/0", "")
+
+		assert.Contains(t, span.Attributes(), semconv.HTTPStatusCode(http.StatusBadRequest))
+		assert.Equal(t, codes.Unset, span.Status().Code)
+	})
+
+	t.Run("server errors fail the span", func(t *testing.T) {
+		span := serve("/user/500", "")
+
+		assert.Contains(t, span.Attributes(), semconv.HTTPStatusCode(http.StatusInternalServerError))
+		assert.Equal(t, codes.Error, span.Status().Code)
+	})
+
+	t.Run("unmatched paths aren't span names", func(t *testing.T) {
+		span := serve("/no/such/path/42", "")
+
+		assert.Equal(t, "GET "+unmatchedRoute, span.Name())
+	})
+}
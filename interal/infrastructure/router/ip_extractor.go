@@ -0,0 +1,47 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"github.com/labstack/echo/v4"
+)
+
+// newIPExtractor decides which address is the client's, for the sign in
+// rate limit and the audit log. Without trusted proxies it is the address of
+// the connection, X-Forwarded-For is ignored as any client can send it.
+// trustedProxies is a comma separated list of IPs and CIDRs, the
+// X-Forwarded-For entries they add are followed back to the first address
+// which isn't one of them.
+func newIPExtractor(trustedProxies string) (echo.IPExtractor, error) {
+	if strings.TrimSpace(trustedProxies) == "" {
+		return echo.ExtractIPDirect(), nil
+	}
+
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, apperrors.TrustedProxiesInitializeErr.AppendMessage(fmt.Errorf("%q is neither an IP nor a CIDR", proxy))
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, apperrors.TrustedProxiesInitializeErr.AppendMessage(err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
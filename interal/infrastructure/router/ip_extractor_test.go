@@ -0,0 +1,45 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewIPExtractor(t *testing.T) {
+	testTable := []struct {
+		scenario       string
+		trustedProxies string
+		remoteAddr     string
+		forwardedFor   string
+		expectedIP     string
+	}{
+		{"no proxies, X-Forwarded-For is ignored", "", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+		{"no proxies, private address", "", "10.0.0.2:5000", "198.51.100.1", "10.0.0.2"},
+		{"trusted proxy", "10.0.0.2", "10.0.0.2:5000", "198.51.100.1", "198.51.100.1"},
+		{"trusted proxy range", "10.0.0.0/8, 192.0.2.1", "10.0.0.2:5000", "198.51.100.1, 10.1.1.1", "198.51.100.1"},
+		{"entries added by the client aren't followed", "10.0.0.2", "10.0.0.2:5000", "198.51.100.1, 203.0.113.9", "203.0.113.9"},
+		{"untrusted peer", "10.0.0.2", "203.0.113.7:5000", "198.51.100.1", "203.0.113.7"},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			extractor, err := newIPExtractor(tc.trustedProxies)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/sing-in", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			assert.Equal(t, tc.expectedIP, extractor(req))
+		})
+	}
+
+	t.Run("invalid proxy", func(t *testing.T) {
+		_, err := newIPExtractor("10.0.0.2, proxy.local")
+		assert.Error(t, err)
+	})
+}
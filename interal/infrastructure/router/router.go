@@ -1,44 +1,82 @@
 package router
 
 import (
+	"log/slog"
+
 	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/metrics"
 	appMiddleware "git.foxminded.com.ua/3_REST_API/interal/infrastructure/middleware"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/tracing"
 	"git.foxminded.com.ua/3_REST_API/interal/interface/controller"
-	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
 	v "git.foxminded.com.ua/3_REST_API/interal/validator"
-	"github.com/go-playground/validator/v10"
-	"github.com/golang-jwt/jwt/v4"
 	echojwt "github.com/labstack/echo-jwt"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
-func NewRouter(e *echo.Echo, config *config.Config, appController *controller.AppController) *echo.Echo {
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
+func NewRouter(e *echo.Echo, config *config.Config, appController *controller.AppController, m *metrics.Metrics, t *tracing.Tracing, logger *slog.Logger) (*echo.Echo, error) {
+	ipExtractor, err := newIPExtractor(config.TrustedProxies)
+	if err != nil {
+		return nil, err
+	}
+	e.IPExtractor = ipExtractor
+	e.HTTPErrorHandler = appMiddleware.ErrorHandler(logger)
+
+	e.Use(appMiddleware.RequestIDMiddleware())
+	e.Use(appMiddleware.TracingMiddleware(t))
+	e.Use(appMiddleware.AccessLogMiddleware(logger))
+	e.Use(middleware.RecoverWithConfig(middleware.RecoverConfig{LogErrorFunc: appMiddleware.LogPanic(logger)}))
+	e.Use(appMiddleware.MetricsMiddleware(m))
 
-	e.Validator = &v.CustomValidator{Validator: validator.New()}
+	e.Validator, err = v.NewCustomValidator(appController.Roles)
+	if err != nil {
+		return nil, err
+	}
+
+	e.GET("/.well-known/jwks.json", appController.JWKSHandler)
+	e.GET("/healthz", appController.LivenessHandler)
+	e.GET("/readyz", appController.ReadinessHandler)
+	e.GET("/metrics", echo.WrapHandler(m.Handler()))
 
 	apiGroup := e.Group("/api/v1")
 	apiGroup.POST("/sing-up", appController.SignUpHandler)
-	apiGroup.POST("/sing-in", appController.SignInHandler)
+	apiGroup.POST("/sing-in", appController.SignInHandler, appMiddleware.RateLimitMiddleware(appController.SignInIPLimiter))
+
+	jwtMiddleware := echojwt.WithConfig(echojwt.Config{
+		ParseTokenFunc: appController.ParseAuthToken,
+		TokenLookup:    "cookie:Authorization",
+	})
+
+	// the refresh token cookie is scoped to /auth, so logout is there too
+	authGroup := apiGroup.Group("/auth")
+	authGroup.POST("/refresh", appController.RefreshTokenHandler)
+	authGroup.POST("/logout", appController.SignOutHandler, jwtMiddleware, appMiddleware.AuditActorMiddleware)
 
 	restrictedGroup := apiGroup.Group("/restricted")
-	restrictedGroup.Use(echojwt.WithConfig(echojwt.Config{
-		NewClaimsFunc: func(c echo.Context) jwt.Claims {
-			return new(interactor.AuthClaims)
-		},
-		SigningKey:  []byte(config.SigningKey),
-		TokenLookup: "cookie:Authorization",
-	}))
-
-	restrictedGroup.GET("/user/:id", appController.GetOneUserHandler)
-	restrictedGroup.GET("/users", appController.GetUsersHandler, appMiddleware.ModeratorRoleMiddleware)
-	restrictedGroup.DELETE("/user/:id", appController.DeleteUserHandler, appMiddleware.AdminRoleMiddleware)
-	restrictedGroup.PUT("/user/:id", appController.UpdateUserHandler, appMiddleware.AdminRoleMiddleware)
+	restrictedGroup.Use(jwtMiddleware)
+	restrictedGroup.Use(appMiddleware.AuditActorMiddleware)
+
+	roles := appController.Roles
+	// kept for the clients which send the refresh token in the body
+	restrictedGroup.POST("/logout", appController.SignOutHandler)
+	restrictedGroup.POST("/user/:id/revoke-sessions", appController.RevokeUserSessionsHandler, appMiddleware.RequirePermission(roles, permissions.SessionsRevoke))
+
+	restrictedGroup.GET("/user/:id", appController.GetOneUserHandler, appMiddleware.RequirePermission(roles, permissions.UsersRead))
+	restrictedGroup.GET("/users", appController.GetUsersHandler, appMiddleware.RequirePermission(roles, permissions.UsersList))
+	restrictedGroup.GET("/users/search", appController.SearchUsersHandler, appMiddleware.RequirePermission(roles, permissions.UsersList))
+	restrictedGroup.GET("/users/deleted", appController.GetDeletedUsersHandler, appMiddleware.RequirePermission(roles, permissions.UsersReadDeleted))
+	restrictedGroup.DELETE("/user/:id", appController.DeleteUserHandler, appMiddleware.RequirePermission(roles, permissions.UsersDelete))
+	restrictedGroup.PUT("/user/:id", appController.UpdateUserHandler, appMiddleware.RequirePermission(roles, permissions.UsersUpdate))
+	restrictedGroup.POST("/user/:id/restore", appController.RestoreUserHandler, appMiddleware.RequirePermission(roles, permissions.UsersRestore))
+	restrictedGroup.DELETE("/user/:id/purge", appController.PurgeUserHandler, appMiddleware.RequirePermission(roles, permissions.UsersPurge))
+	restrictedGroup.POST("/user/:id/unlock", appController.UnlockUserHandler, appMiddleware.RequirePermission(roles, permissions.UsersUnlock))
+	restrictedGroup.PUT("/user/:id/role", appController.AssignRoleHandler, appMiddleware.RequirePermission(roles, permissions.RolesAssign))
 	restrictedGroup.DELETE("/user/profile", appController.DeleteOwnerProfileHandler)
 	restrictedGroup.PUT("/user/profile", appController.UpdateOwnerProfileHandler)
-	restrictedGroup.PATCH("/user/:username", appController.RateUserHandler)
+	restrictedGroup.PATCH("/user/:username", appController.RateUserHandler, appMiddleware.RequirePermission(roles, permissions.UsersRate))
+
+	restrictedGroup.GET("/audit", appController.GetAuditEventsHandler, appMiddleware.RequirePermission(roles, permissions.AuditRead))
 
-	return e
+	return e, nil
 }
@@ -0,0 +1,303 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/gen/mocks"
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/mappers"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/metrics"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/tracing"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/controller"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/hasher"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/signer"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+)
+
+// TestRestrictedRoutesRejectInvalidTokens goes through the JWT middleware and
+// the error handler, which echojwt wraps the errors of ParseToken for.
+func TestRestrictedRoutesRejectInvalidTokens(t *testing.T) {
+	const (
+		userID        = 124
+		deletedUserID = 125
+	)
+	now := time.Now()
+
+	testTable := []struct {
+		scenario     string
+		userID       uint
+		issuedAt     time.Time
+		expiresAt    time.Time
+		expectedCode int
+		expectedErr  *apperrors.AppError
+	}{
+		{"valid token", userID, now, now.Add(time.Minute), http.StatusOK, nil},
+		{"expired token", userID, now.Add(-time.Hour), now.Add(-time.Minute), http.StatusUnauthorized, &apperrors.InvalidTokenErr},
+		{"token of a deleted user", deletedUserID, now, now.Add(time.Minute), http.StatusUnauthorized, &apperrors.InvalidTokenErr},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	keySet, err := signer.NewKeySet(signer.NewHMACKey("test", []byte("signing_key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), uint(userID)).Return(&models.User{ID: userID, UserName: "JohnHall", Role: permissions.AdminRole}, nil).AnyTimes()
+	userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), uint(deletedUserID)).Return(nil, gorm.ErrRecordNotFound).AnyTimes()
+	uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), mocks.NewMockAuditRepository(ctrl), mocks.NewMockLoginAttemptRepository(ctrl), mocks.NewMockTransactor(ctrl),
+		repository.NewInMemoryRevocationStore(), nil, permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: keySet, TokenTTL: 60, RefreshTokenTTL: 60}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+	e := newTestRouter(t, ctrl, uInteractor, &config.Config{})
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			token, err := keySet.Sign(&interactor.AuthClaims{RegisteredClaims: jwt.RegisteredClaims{
+				ID:        "jti",
+				Subject:   strconv.FormatUint(uint64(tc.userID), 10),
+				IssuedAt:  jwt.NewNumericDate(tc.issuedAt),
+				ExpiresAt: jwt.NewNumericDate(tc.expiresAt),
+			}})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/restricted/user/124", nil)
+			req.AddCookie(&http.Cookie{Name: "Authorization", Value: token})
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			assert.Equal(t, tc.expectedCode, rec.Code, rec.Body.String())
+			if tc.expectedErr != nil {
+				var response requests.ErrorResponse
+				if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response)) {
+					assert.Equal(t, tc.expectedErr.Code, response.Code)
+				}
+			}
+		})
+	}
+}
+
+// TestSignOutWithBrowserCookies signs in and out with a cookie jar, which
+// sends every cookie only to the paths it is scoped to, as browsers do.
+func TestSignOutWithBrowserCookies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, keySet, passwordHasher := newBrowserTestUser(t)
+
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	userRepoMock.EXPECT().FindOneUserByUserName(gomock.Any(), user.UserName).Return(user, nil)
+	userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), user.ID).Return(user, nil).AnyTimes()
+	loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
+	loginAttemptRepoMock.EXPECT().RecordSuccessfulLogin(gomock.Any(), user.ID).Return(nil)
+
+	var issued *models.RefreshToken
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	refreshTokenRepoMock.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, token *models.RefreshToken) error {
+			issued = token
+			return nil
+		})
+	refreshTokenRepoMock.EXPECT().FindRefreshTokenByHash(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, hash string) (*models.RefreshToken, error) {
+			assert.Equal(t, issued.TokenHash, hash)
+			return issued, nil
+		})
+	// expected once, so the test fails when the jar doesn't send the refresh
+	// token to logout
+	refreshTokenRepoMock.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, familyID string) error {
+			assert.Equal(t, issued.FamilyID, familyID)
+			return nil
+		})
+
+	uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, mocks.NewMockAuditRepository(ctrl), loginAttemptRepoMock, mocks.NewMockTransactor(ctrl),
+		repository.NewInMemoryRevocationStore(), passwordHasher, permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: keySet, TokenTTL: 60, RefreshTokenTTL: 60}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+	server := httptest.NewServer(newTestRouter(t, ctrl, uInteractor, &config.Config{}))
+	defer server.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Jar: jar}
+
+	if !signInWithBrowserCookies(t, client, server.URL) {
+		return
+	}
+
+	response, err := client.Post(server.URL+"/api/v1/auth/logout", echo.MIMEApplicationJSON, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var expiredCookies []string
+	for _, cookie := range response.Cookies() {
+		assert.Equal(t, -1, cookie.MaxAge, cookie.Name)
+		expiredCookies = append(expiredCookies, cookie.Name+" "+cookie.Path)
+	}
+	assert.ElementsMatch(t, []string{"Authorization /api/v1", "RefreshToken /api/v1/auth"}, expiredCookies)
+
+	// the browser keeps no auth cookie for any route
+	for _, path := range []string{"/api/v1/restricted/users", "/api/v1/auth/refresh"} {
+		address, err := url.Parse(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Empty(t, jar.Cookies(address), path)
+	}
+}
+
+// TestRefreshWithBrowserCookies checks that the access token of a refresh
+// reaches the restricted routes and replaces the one of the sign in.
+func TestRefreshWithBrowserCookies(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	user, keySet, passwordHasher := newBrowserTestUser(t)
+
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	userRepoMock.EXPECT().FindOneUserByUserName(gomock.Any(), user.UserName).Return(user, nil)
+	userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), user.ID).Return(user, nil).AnyTimes()
+	loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
+	loginAttemptRepoMock.EXPECT().RecordSuccessfulLogin(gomock.Any(), user.ID).Return(nil)
+
+	var issued *models.RefreshToken
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	refreshTokenRepoMock.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, token *models.RefreshToken) error {
+			issued = token
+			return nil
+		})
+	refreshTokenRepoMock.EXPECT().FindRefreshTokenByHash(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, hash string) (*models.RefreshToken, error) {
+			assert.Equal(t, issued.TokenHash, hash)
+			return issued, nil
+		})
+	refreshTokenRepoMock.EXPECT().RotateRefreshToken(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+
+	uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, mocks.NewMockAuditRepository(ctrl), loginAttemptRepoMock, mocks.NewMockTransactor(ctrl),
+		repository.NewInMemoryRevocationStore(), passwordHasher, permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: keySet, TokenTTL: 60, RefreshTokenTTL: 60}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+	server := httptest.NewServer(newTestRouter(t, ctrl, uInteractor, &config.Config{}))
+	defer server.Close()
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Jar: jar}
+
+	if !signInWithBrowserCookies(t, client, server.URL) {
+		return
+	}
+
+	response, err := client.Post(server.URL+"/api/v1/auth/refresh", echo.MIMEApplicationJSON, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if !assert.Equal(t, http.StatusOK, response.StatusCode) {
+		return
+	}
+	var refreshedToken string
+	for _, cookie := range response.Cookies() {
+		if cookie.Name == "Authorization" {
+			assert.Equal(t, "/api/v1", cookie.Path)
+			refreshedToken = cookie.Value
+		}
+	}
+
+	restrictedURL := server.URL + "/api/v1/restricted/user/" + strconv.Itoa(int(user.ID))
+	restricted, err := url.Parse(restrictedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sentTokens []string
+	for _, cookie := range jar.Cookies(restricted) {
+		if cookie.Name == "Authorization" {
+			sentTokens = append(sentTokens, cookie.Value)
+		}
+	}
+	assert.Equal(t, []string{refreshedToken}, sentTokens)
+
+	response, err = client.Get(restrictedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+const browserTestPassword = "very12difficult()Password"
+
+func newBrowserTestUser(t *testing.T) (*models.User, *signer.KeySet, hasher.PasswordHasher) {
+	keySet, err := signer.NewKeySet(signer.NewHMACKey("test", []byte("signing_key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordHasher, err := hasher.NewPasswordHasher(hasher.BcryptAlgorithm, "hash_salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordHash, err := passwordHasher.Hash(browserTestPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &models.User{ID: 124, UserName: "JohnHall", Role: permissions.UserRole, Password: passwordHash}, keySet, passwordHasher
+}
+
+func signInWithBrowserCookies(t *testing.T, client *http.Client, serverURL string) bool {
+	response, err := client.Post(serverURL+"/api/v1/sing-in", echo.MIMEApplicationJSON,
+		strings.NewReader(`{"user_name":"JohnHall","password":"`+browserTestPassword+`"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	return assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func newTestRouter(t *testing.T, ctrl *gomock.Controller, uInteractor interactor.UserInteractor, config *config.Config) *echo.Echo {
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	appController := &controller.AppController{
+		UserController:   controller.NewUserController(uInteractor, mappers.DefaultPaginationPolicy),
+		AuditController:  controller.NewAuditController(interactor.NewAuditInteractor(auditRepoMock), mappers.DefaultPaginationPolicy),
+		HealthController: controller.NewHealthController(interactor.NewHealthInteractor(nil)),
+		Roles:            permissions.DefaultRoles(),
+		SignInIPLimiter:  ratelimit.NewLimiter(repository.NewInMemoryRateLimitStore(), "ip", ratelimit.PerMinute(0)),
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	e, err := NewRouter(echo.New(), config, appController, metrics.New(), tracing.NewWithProvider(trace.NewNoopTracerProvider()), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+
+	instrumentationName = "git.foxminded.com.ua/3_REST_API"
+)
+
+// errorCodeKey carries the code of an app error on failed spans.
+var errorCodeKey = attribute.Key("app.error_code")
+
+// Tracing is the tracer provider and the propagator of the trace context.
+// The provider is a field rather than the global one, so tests can record
+// spans in memory.
+type Tracing struct {
+	Provider   trace.TracerProvider
+	Propagator propagation.TextMapPropagator
+	shutdown   func(ctx context.Context) error
+}
+
+// New builds the exporter of TRACE_EXPORTER, "none" when it isn't set. The
+// OTLP exporter sends to the OTEL_EXPORTER_OTLP_* endpoint over HTTP.
+func New(c *config.Config) (*Tracing, error) {
+	var (
+		exporter sdktrace.SpanExporter
+		err      error
+	)
+	switch c.TraceExporter {
+	case ExporterNone, "":
+		return NewWithProvider(trace.NewNoopTracerProvider()), nil
+	case ExporterStdout:
+		// stderr keeps stdout of the usermanager commands clean
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+	case ExporterOTLP:
+		exporter, err = otlptracehttp.New(context.Background())
+	default:
+		err = fmt.Errorf("unknown TRACE_EXPORTER %q", c.TraceExporter)
+	}
+	if err != nil {
+		return nil, apperrors.TracingInitializeErr.AppendMessage(err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.TraceSampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(c.TraceServiceName))),
+	)
+	t := NewWithProvider(provider)
+	t.shutdown = provider.Shutdown
+	return t, nil
+}
+
+// NewWithProvider propagates the W3C traceparent and baggage headers.
+func NewWithProvider(provider trace.TracerProvider) *Tracing {
+	return &Tracing{
+		Provider:   provider,
+		Propagator: propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}),
+	}
+}
+
+func (t *Tracing) Tracer() trace.Tracer {
+	return t.Provider.Tracer(instrumentationName)
+}
+
+// Shutdown exports the spans which are still buffered.
+func (t *Tracing) Shutdown(ctx context.Context) error {
+	if t.shutdown == nil {
+		return nil
+	}
+	return t.shutdown(ctx)
+}
+
+// End marks the span failed when err is set and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		if appErr, ok := apperrors.As(err); ok {
+			span.SetAttributes(errorCodeKey.String(appErr.Code))
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,145 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/datastore"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gorm.io/gorm/logger"
+)
+
+// stubUserInteractor looks users up in the repository, as the interactor
+// does. The methods it doesn't override panic on the nil interface.
+type stubUserInteractor struct {
+	interactor.UserInteractor
+	userRepo repository.UserRepository
+}
+
+func (s *stubUserInteractor) FindOneSigner(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.userRepo.FindOneUserByID(ctx, id)
+	if err != nil {
+		return nil, apperrors.UserNotFoundErr.AppendMessage(err)
+	}
+	return user, nil
+}
+
+func newRecorder() (*tracetest.SpanRecorder, *Tracing) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, NewWithProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	values := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestSpanStructure(t *testing.T) {
+	recorder, tracing := newRecorder()
+	db, err := datastore.NewDB(&config.Config{DBDriver: datastore.DriverSQLite, DBName: ":memory:"}, logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := datastore.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	created, err := repository.NewUserRepository(db).CreateUser(context.Background(), &models.User{UserName: "JohnHall", Role: "user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the plugin is added after the setup, so only the spans of the test
+	// are recorded
+	if err := db.Use(GormPlugin{Tracer: tracing.Tracer()}); err != nil {
+		t.Fatal(err)
+	}
+	userRepo := TraceUserRepository(repository.NewUserRepository(db), tracing.Tracer())
+
+	uI := TraceUserInteractor(&stubUserInteractor{userRepo: userRepo}, tracing.Tracer())
+	ctx, root := tracing.Tracer().Start(context.Background(), "GET /api/v1/restricted/user/:id")
+	_, err = uI.FindOneSigner(ctx, created.ID)
+	assert.NoError(t, err)
+	_, err = uI.FindOneSigner(ctx, created.ID+1)
+	assert.Error(t, err)
+	root.End()
+
+	spans := recorder.Ended()
+	names := make([]string, 0, len(spans))
+	for _, span := range spans {
+		names = append(names, span.Name())
+	}
+	assert.Equal(t, []string{
+		"gorm.query", "UserRepository.FindOneUserByID", "UserInteractor.FindOneSigner",
+		"gorm.query", "UserRepository.FindOneUserByID", "UserInteractor.FindOneSigner",
+		"GET /api/v1/restricted/user/:id",
+	}, names)
+
+	t.Run("interactor, repository and GORM spans are nested", func(t *testing.T) {
+		// the index of the parent of every span but the root
+		parents := []int{1, 2, 6, 4, 5, 6}
+		for i, parent := range parents {
+			assert.Equal(t, spans[parent].SpanContext().SpanID(), spans[i].Parent().SpanID(), spans[i].Name())
+		}
+		for _, span := range spans {
+			assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext().TraceID())
+		}
+	})
+
+	t.Run("GORM spans carry the SQL without the values", func(t *testing.T) {
+		values := attributes(spans[0])
+		assert.Equal(t, "sqlite", values["db.system"].AsString())
+		assert.Equal(t, "users", values["db.sql.table"].AsString())
+		assert.Contains(t, values["db.statement"].AsString(), "SELECT")
+		assert.NotContains(t, values["db.statement"].AsString(), "JohnHall")
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	})
+
+	t.Run("failed calls are marked with the error and its code", func(t *testing.T) {
+		assert.Equal(t, codes.Unset, spans[2].Status().Code)
+		assert.Equal(t, codes.Error, spans[4].Status().Code)
+		assert.Equal(t, codes.Error, spans[5].Status().Code)
+		assert.Equal(t, apperrors.UserNotFoundErr.Code, attributes(spans[5])["app.error_code"].AsString())
+		assert.Equal(t, int64(created.ID+1), attributes(spans[5])["user.id"].AsInt64())
+	})
+}
+
+func TestNew(t *testing.T) {
+	testTable := []struct {
+		scenario string
+		exporter string
+		err      error
+	}{
+		{"no exporter by default", "", nil},
+		{"none", ExporterNone, nil},
+		{"stdout", ExporterStdout, nil},
+		{"unknown exporter", "zipkin", &apperrors.TracingInitializeErr},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			tracing, err := New(&config.Config{TraceExporter: tc.exporter, TraceServiceName: "usermanager", TraceSampleRatio: 1})
+			if tc.err != nil {
+				assert.True(t, errors.Is(err, tc.err))
+				return
+			}
+			assert.NoError(t, err)
+			assert.NoError(t, tracing.Shutdown(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/signer"
+	"github.com/golang-jwt/jwt/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// userIDKey carries the ID a method is called for. Usernames and other
+// personal data are kept out of the spans.
+var userIDKey = attribute.Key("user.id")
+
+// userInteractor starts a "UserInteractor.<method>" span around every method
+// of the wrapped interactor which takes a context. The interactor isn't
+// embedded, so a new method doesn't build until it is traced.
+type userInteractor struct {
+	next   interactor.UserInteractor
+	tracer trace.Tracer
+}
+
+func TraceUserInteractor(uI interactor.UserInteractor, tracer trace.Tracer) interactor.UserInteractor {
+	return &userInteractor{uI, tracer}
+}
+
+func (uI *userInteractor) start(ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return uI.tracer.Start(ctx, "UserInteractor."+method, trace.WithAttributes(attributes...))
+}
+
+func (uI *userInteractor) SignUp(ctx context.Context, user *models.User) (_ *interactor.AuthTokens, err error) {
+	ctx, span := uI.start(ctx, "SignUp")
+	defer func() { End(span, err) }()
+	return uI.next.SignUp(ctx, user)
+}
+
+func (uI *userInteractor) SignIn(ctx context.Context, name, password string) (_ *interactor.AuthTokens, err error) {
+	ctx, span := uI.start(ctx, "SignIn")
+	defer func() { End(span, err) }()
+	return uI.next.SignIn(ctx, name, password)
+}
+
+func (uI *userInteractor) RefreshTokens(ctx context.Context, refreshToken string) (_ *interactor.AuthTokens, err error) {
+	ctx, span := uI.start(ctx, "RefreshTokens")
+	defer func() { End(span, err) }()
+	return uI.next.RefreshTokens(ctx, refreshToken)
+}
+
+func (uI *userInteractor) ParseToken(ctx context.Context, tokenString string) (_ *jwt.Token, err error) {
+	ctx, span := uI.start(ctx, "ParseToken")
+	defer func() { End(span, err) }()
+	return uI.next.ParseToken(ctx, tokenString)
+}
+
+func (uI *userInteractor) SignOut(ctx context.Context, claims *interactor.AuthClaims, refreshToken string) (err error) {
+	ctx, span := uI.start(ctx, "SignOut")
+	defer func() { End(span, err) }()
+	return uI.next.SignOut(ctx, claims, refreshToken)
+}
+
+func (uI *userInteractor) RevokeUserSessions(ctx context.Context, userID uint) (err error) {
+	ctx, span := uI.start(ctx, "RevokeUserSessions", userIDKey.Int64(int64(userID)))
+	defer func() { End(span, err) }()
+	return uI.next.RevokeUserSessions(ctx, userID)
+}
+
+func (uI *userInteractor) JWKS() signer.JWKS {
+	return uI.next.JWKS()
+}
+
+func (uI *userInteractor) FindOneSigner(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "FindOneSigner", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return uI.next.FindOneSigner(ctx, id)
+}
+
+func (uI *userInteractor) FindSigners(ctx context.Context, filter *models.UserFilter, pagination *models.Pagination) (_ *models.Pagination, _ []*models.User, err error) {
+	ctx, span := uI.start(ctx, "FindSigners")
+	defer func() { End(span, err) }()
+	return uI.next.FindSigners(ctx, filter, pagination)
+}
+
+func (uI *userInteractor) SearchSigners(ctx context.Context, query string, pagination *models.Pagination) (_ *models.Pagination, _ []*models.User, err error) {
+	ctx, span := uI.start(ctx, "SearchSigners")
+	defer func() { End(span, err) }()
+	return uI.next.SearchSigners(ctx, query, pagination)
+}
+
+func (uI *userInteractor) DeleteSignerByID(ctx context.Context, id int) (err error) {
+	ctx, span := uI.start(ctx, "DeleteSignerByID", userIDKey.Int(id))
+	defer func() { End(span, err) }()
+	return uI.next.DeleteSignerByID(ctx, id)
+}
+
+func (uI *userInteractor) DeleteOwnSignIn(ctx context.Context, id int) (err error) {
+	ctx, span := uI.start(ctx, "DeleteOwnSignIn", userIDKey.Int(id))
+	defer func() { End(span, err) }()
+	return uI.next.DeleteOwnSignIn(ctx, id)
+}
+
+func (uI *userInteractor) UpdateSignersByID(ctx context.Context, id int, user *models.User) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "UpdateSignersByID", userIDKey.Int(id))
+	defer func() { End(span, err) }()
+	return uI.next.UpdateSignersByID(ctx, id, user)
+}
+
+func (uI *userInteractor) UpdateOwnSignIn(ctx context.Context, id int, user *models.User) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "UpdateOwnSignIn", userIDKey.Int(id))
+	defer func() { End(span, err) }()
+	return uI.next.UpdateOwnSignIn(ctx, id, user)
+}
+
+func (uI *userInteractor) RateUser(ctx context.Context, myID uint, username, rate string) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "RateUser", userIDKey.Int64(int64(myID)), attribute.String("rate", rate))
+	defer func() { End(span, err) }()
+	return uI.next.RateUser(ctx, myID, username, rate)
+}
+
+func (uI *userInteractor) AssignRole(ctx context.Context, actorID, userID uint, role string) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "AssignRole", userIDKey.Int64(int64(userID)), attribute.String("role", role))
+	defer func() { End(span, err) }()
+	return uI.next.AssignRole(ctx, actorID, userID, role)
+}
+
+func (uI *userInteractor) BootstrapAdmin(ctx context.Context, username, password string) (err error) {
+	ctx, span := uI.start(ctx, "BootstrapAdmin")
+	defer func() { End(span, err) }()
+	return uI.next.BootstrapAdmin(ctx, username, password)
+}
+
+func (uI *userInteractor) UnlockUser(ctx context.Context, id uint) (err error) {
+	ctx, span := uI.start(ctx, "UnlockUser", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return uI.next.UnlockUser(ctx, id)
+}
+
+func (uI *userInteractor) FindDeletedSigners(ctx context.Context, pagination *models.Pagination) (_ *models.Pagination, _ []*models.User, err error) {
+	ctx, span := uI.start(ctx, "FindDeletedSigners", attribute.String("sort", pagination.Sort))
+	defer func() { End(span, err) }()
+	return uI.next.FindDeletedSigners(ctx, pagination)
+}
+
+func (uI *userInteractor) RestoreSigner(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "RestoreSigner", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return uI.next.RestoreSigner(ctx, id)
+}
+
+func (uI *userInteractor) PurgeSigner(ctx context.Context, id uint) (err error) {
+	ctx, span := uI.start(ctx, "PurgeSigner", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return uI.next.PurgeSigner(ctx, id)
+}
+
+func (uI *userInteractor) PurgeDeletedSigners(ctx context.Context, deletedBefore time.Time) (_ int, err error) {
+	ctx, span := uI.start(ctx, "PurgeDeletedSigners")
+	defer func() { End(span, err) }()
+	return uI.next.PurgeDeletedSigners(ctx, deletedBefore)
+}
+
+func (uI *userInteractor) CreateAdmin(ctx context.Context, user *models.User) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "CreateAdmin")
+	defer func() { End(span, err) }()
+	return uI.next.CreateAdmin(ctx, user)
+}
+
+func (uI *userInteractor) FindOneSignerByUserName(ctx context.Context, username string) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "FindOneSignerByUserName")
+	defer func() { End(span, err) }()
+	return uI.next.FindOneSignerByUserName(ctx, username)
+}
+
+func (uI *userInteractor) ResetPassword(ctx context.Context, username, password string) (_ *models.User, err error) {
+	ctx, span := uI.start(ctx, "ResetPassword")
+	defer func() { End(span, err) }()
+	return uI.next.ResetPassword(ctx, username, password)
+}
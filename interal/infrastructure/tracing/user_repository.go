@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// userRepository starts a "UserRepository.<method>" span around every call
+// of the wrapped repository, the GORM spans of the call are its children.
+type userRepository struct {
+	next   repository.UserRepository
+	tracer trace.Tracer
+}
+
+func TraceUserRepository(ur repository.UserRepository, tracer trace.Tracer) repository.UserRepository {
+	return &userRepository{ur, tracer}
+}
+
+func (ur *userRepository) start(ctx context.Context, method string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return ur.tracer.Start(ctx, "UserRepository."+method, trace.WithAttributes(attributes...))
+}
+
+func (ur *userRepository) CreateUser(ctx context.Context, user *models.User) (_ *models.User, err error) {
+	ctx, span := ur.start(ctx, "CreateUser")
+	defer func() { End(span, err) }()
+	return ur.next.CreateUser(ctx, user)
+}
+
+func (ur *userRepository) FindUsers(ctx context.Context, filter *models.UserFilter, pagination *models.Pagination) (_ *models.Pagination, _ []*models.User, err error) {
+	ctx, span := ur.start(ctx, "FindUsers", attribute.String("sort", pagination.Sort))
+	defer func() { End(span, err) }()
+	return ur.next.FindUsers(ctx, filter, pagination)
+}
+
+func (ur *userRepository) SearchUsers(ctx context.Context, query string, pagination *models.Pagination) (_ *models.Pagination, _ []*models.User, err error) {
+	ctx, span := ur.start(ctx, "SearchUsers")
+	defer func() { End(span, err) }()
+	return ur.next.SearchUsers(ctx, query, pagination)
+}
+
+func (ur *userRepository) FindOneUserByID(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, span := ur.start(ctx, "FindOneUserByID", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return ur.next.FindOneUserByID(ctx, id)
+}
+
+func (ur *userRepository) FindOneUserByUserName(ctx context.Context, username string) (_ *models.User, err error) {
+	ctx, span := ur.start(ctx, "FindOneUserByUserName")
+	defer func() { End(span, err) }()
+	return ur.next.FindOneUserByUserName(ctx, username)
+}
+
+func (ur *userRepository) DeleteUserByID(ctx context.Context, id int) (err error) {
+	ctx, span := ur.start(ctx, "DeleteUserByID", userIDKey.Int(id))
+	defer func() { End(span, err) }()
+	return ur.next.DeleteUserByID(ctx, id)
+}
+
+func (ur *userRepository) DeleteOwnUser(ctx context.Context, id int) (err error) {
+	ctx, span := ur.start(ctx, "DeleteOwnUser", userIDKey.Int(id))
+	defer func() { End(span, err) }()
+	return ur.next.DeleteOwnUser(ctx, id)
+}
+
+func (ur *userRepository) UpdateUserByID(ctx context.Context, id int, user *models.User) (_ *models.User, err error) {
+	ctx, span := ur.start(ctx, "UpdateUserByID", userIDKey.Int(id))
+	defer func() { End(span, err) }()
+	return ur.next.UpdateUserByID(ctx, id, user)
+}
+
+func (ur *userRepository) UpdateOwnUser(ctx context.Context, id int, user *models.User) (_ *models.User, err error) {
+	ctx, span := ur.start(ctx, "UpdateOwnUser", userIDKey.Int(id))
+	defer func() { End(span, err) }()
+	return ur.next.UpdateOwnUser(ctx, id, user)
+}
+
+func (ur *userRepository) UpdateUserPassword(ctx context.Context, id uint, passwordHash string) (err error) {
+	ctx, span := ur.start(ctx, "UpdateUserPassword", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return ur.next.UpdateUserPassword(ctx, id, passwordHash)
+}
+
+func (ur *userRepository) UpdateUserRole(ctx context.Context, id uint, role string) (err error) {
+	ctx, span := ur.start(ctx, "UpdateUserRole", userIDKey.Int64(int64(id)), attribute.String("role", role))
+	defer func() { End(span, err) }()
+	return ur.next.UpdateUserRole(ctx, id, role)
+}
+
+func (ur *userRepository) CountUsersByRole(ctx context.Context, role string) (_ int64, err error) {
+	ctx, span := ur.start(ctx, "CountUsersByRole", attribute.String("role", role))
+	defer func() { End(span, err) }()
+	return ur.next.CountUsersByRole(ctx, role)
+}
+
+func (ur *userRepository) RateUserByUsername(ctx context.Context, userWhoRateID uint, username, rate string) (_ *models.User, err error) {
+	ctx, span := ur.start(ctx, "RateUserByUsername", userIDKey.Int64(int64(userWhoRateID)), attribute.String("rate", rate))
+	defer func() { End(span, err) }()
+	return ur.next.RateUserByUsername(ctx, userWhoRateID, username, rate)
+}
+
+func (ur *userRepository) FindDeletedUsers(ctx context.Context, pagination *models.Pagination) (_ *models.Pagination, _ []*models.User, err error) {
+	ctx, span := ur.start(ctx, "FindDeletedUsers", attribute.String("sort", pagination.Sort))
+	defer func() { End(span, err) }()
+	return ur.next.FindDeletedUsers(ctx, pagination)
+}
+
+func (ur *userRepository) ReleaseUserName(ctx context.Context, id uint) (err error) {
+	ctx, span := ur.start(ctx, "ReleaseUserName", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return ur.next.ReleaseUserName(ctx, id)
+}
+
+func (ur *userRepository) RestoreUser(ctx context.Context, id uint) (_ *models.User, err error) {
+	ctx, span := ur.start(ctx, "RestoreUser", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return ur.next.RestoreUser(ctx, id)
+}
+
+func (ur *userRepository) PurgeUser(ctx context.Context, id uint) (err error) {
+	ctx, span := ur.start(ctx, "PurgeUser", userIDKey.Int64(int64(id)))
+	defer func() { End(span, err) }()
+	return ur.next.PurgeUser(ctx, id)
+}
+
+func (ur *userRepository) FindUserIDsDeletedBefore(ctx context.Context, before time.Time, limit int) (_ []uint, err error) {
+	ctx, span := ur.start(ctx, "FindUserIDsDeletedBefore")
+	defer func() { End(span, err) }()
+	return ur.next.FindUserIDsDeletedBefore(ctx, before, limit)
+}
@@ -1,5 +1,14 @@
 package controller
 
+import (
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
+)
+
 type AppController struct {
 	UserController
+	AuditController
+	HealthController
+	Roles           *permissions.Roles
+	SignInIPLimiter *ratelimit.Limiter
 }
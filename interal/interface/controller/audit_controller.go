@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"net/http"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/mappers"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"github.com/labstack/echo/v4"
+)
+
+type auditController struct {
+	auditInteractor interactor.AuditInteractor
+	pagination      mappers.PaginationPolicy
+}
+
+type AuditController interface {
+	GetAuditEventsHandler(c echo.Context) error
+}
+
+func NewAuditController(ai interactor.AuditInteractor, pagination mappers.PaginationPolicy) AuditController {
+	return &auditController{ai, pagination}
+}
+
+func (aC *auditController) GetAuditEventsHandler(c echo.Context) error {
+	filter, err := mappers.MapContextToAuditFilter(c)
+	if err != nil {
+		return err
+	}
+
+	pagination, err := mappers.MapContextToAuditPagination(c, aC.pagination)
+	if err != nil {
+		return err
+	}
+
+	pagination, events, err := aC.auditInteractor.FindAuditEvents(c.Request().Context(), filter, pagination)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mappers.MapAuditEventsToGetAuditEventsResponse(c, events, pagination))
+}
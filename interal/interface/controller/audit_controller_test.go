@@ -0,0 +1,161 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/gen/mocks"
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/mappers"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"github.com/golang/mock/gomock"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAuditEventsHandler(t *testing.T) {
+
+	events := []*models.AuditEvent{{ID: 1, ActorID: 1, TargetID: 124, Action: models.AuditActionUserDelete}}
+
+	testTable := []struct {
+		scenario         string
+		query            string
+		expectedFilter   *models.AuditFilter
+		repoError        error
+		httpCode         int
+		expectedFields   []string
+		expectedNextPage string
+	}{
+		{
+			"audit events are found",
+			"?actor_id=1&action=user.delete&limit=1",
+			&models.AuditFilter{ActorID: 1, Action: models.AuditActionUserDelete},
+			nil,
+			http.StatusOK,
+			nil,
+			"/audit?action=user.delete&actor_id=1&limit=1&page=2&sort=id+desc",
+		},
+		{
+			"wrong actor id",
+			"?actor_id=admin",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			[]string{"actor_id"},
+			"",
+		},
+		{
+			"wrong target id",
+			"?target_id=-1",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			[]string{"target_id"},
+			"",
+		},
+		{
+			"wrong start of the time range",
+			"?from=yesterday",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			[]string{"from"},
+			"",
+		},
+		{
+			"wrong end of the time range",
+			"?to=2026-10-17",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			[]string{"to"},
+			"",
+		},
+		{
+			"every wrong filter is reported",
+			"?actor_id=admin&target_id=x&from=yesterday&to=today",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			[]string{"actor_id", "target_id", "from", "to"},
+			"",
+		},
+		{
+			"unsupported sort",
+			"?sort=password",
+			nil,
+			nil,
+			http.StatusBadRequest,
+			[]string{"sort"},
+			"",
+		},
+		{
+			"audit events can't be read",
+			"?target_id=124",
+			&models.AuditFilter{TargetID: 124},
+			errors.New("db is down"),
+			http.StatusInternalServerError,
+			nil,
+			"",
+		},
+	}
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+			aController := NewAuditController(interactor.NewAuditInteractor(auditRepoMock), mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/audit"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if tc.expectedFilter != nil {
+				auditRepoMock.EXPECT().FindAuditEvents(ctx, tc.expectedFilter, gomock.Any()).
+					DoAndReturn(func(ctx context.Context, filter *models.AuditFilter, pagination *models.Pagination) (*models.Pagination, []*models.AuditEvent, error) {
+						if tc.repoError != nil {
+							return nil, nil, tc.repoError
+						}
+						pagination.TotalRows = 2
+						pagination.TotalPages = 2
+						return pagination, events, nil
+					})
+			}
+
+			err := aController.GetAuditEventsHandler(c)
+
+			if err != nil {
+				appErr := err.(*apperrors.AppError)
+				assert.Equal(t, tc.httpCode, appErr.HTTPCode)
+
+				var fields []string
+				for _, field := range appErr.Fields {
+					fields = append(fields, field.Field)
+				}
+				assert.Equal(t, tc.expectedFields, fields)
+				return
+			}
+			assert.Equal(t, tc.httpCode, rec.Code)
+
+			var response struct {
+				AuditEvents struct {
+					NextPage string               `json:"next_page"`
+					Rows     []*models.AuditEvent `json:"rows"`
+				} `json:"audit_events"`
+			}
+			if assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response)) {
+				assert.Equal(t, tc.expectedNextPage, response.AuditEvents.NextPage)
+				assert.Equal(t, events, response.AuditEvents.Rows)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"net/http"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"github.com/labstack/echo/v4"
+)
+
+type healthController struct {
+	healthInteractor interactor.HealthInteractor
+}
+
+type HealthController interface {
+	LivenessHandler(c echo.Context) error
+	ReadinessHandler(c echo.Context) error
+}
+
+func NewHealthController(hi interactor.HealthInteractor) HealthController {
+	return &healthController{hi}
+}
+
+// LivenessHandler answers as long as the process serves requests, it checks
+// no dependencies.
+func (hC *healthController) LivenessHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, requests.HealthResponse{Status: interactor.HealthStatusOK})
+}
+
+// ReadinessHandler answers 503 NOT_READY while any dependency is failing.
+func (hC *healthController) ReadinessHandler(c echo.Context) error {
+	checks, err := hC.healthInteractor.CheckReadiness(c.Request().Context())
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, requests.HealthResponse{Status: interactor.HealthStatusOK, Checks: checks})
+}
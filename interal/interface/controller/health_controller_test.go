@@ -0,0 +1,74 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLivenessHandler(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/healthz", nil), rec)
+
+	hController := NewHealthController(interactor.NewHealthInteractor(map[string]interactor.HealthCheck{
+		"database": func(ctx context.Context) error { return errors.New("connection refused") },
+	}))
+
+	if assert.NoError(t, hController.LivenessHandler(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, rec.Body.String())
+	}
+}
+
+func TestReadinessHandler(t *testing.T) {
+	testTable := []struct {
+		scenario       string
+		databaseErr    error
+		migrationsErr  error
+		httpCode       int
+		expectedChecks map[string]string
+	}{
+		{"ready", nil, nil, http.StatusOK, map[string]string{"database": "ok", "migrations": "ok"}},
+		{"database is down", errors.New("connection refused"), nil, http.StatusServiceUnavailable, map[string]string{"database": "failing", "migrations": "ok"}},
+		{"schema is behind", nil, &apperrors.SchemaOutdatedErr, http.StatusServiceUnavailable, map[string]string{"database": "ok", "migrations": "failing"}},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/readyz", nil), rec)
+
+			hController := NewHealthController(interactor.NewHealthInteractor(map[string]interactor.HealthCheck{
+				"database":   func(ctx context.Context) error { return testCase.databaseErr },
+				"migrations": func(ctx context.Context) error { return testCase.migrationsErr },
+			}))
+
+			err := hController.ReadinessHandler(c)
+			if testCase.httpCode != http.StatusOK {
+				appErr, ok := apperrors.As(err)
+				if assert.True(t, ok) {
+					assert.Equal(t, testCase.httpCode, appErr.HTTPCode)
+					assert.Equal(t, testCase.expectedChecks, appErr.Details["checks"])
+				}
+				return
+			}
+
+			if assert.NoError(t, err) {
+				response := requests.HealthResponse{}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+				assert.Equal(t, testCase.expectedChecks, response.Checks)
+			}
+		})
+	}
+}
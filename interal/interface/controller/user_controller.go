@@ -15,44 +15,52 @@ import (
 
 type userController struct {
 	userInteractor interactor.UserInteractor
+	pagination     mappers.PaginationPolicy
 }
 
 type UserController interface {
 	SignUpHandler(ctx echo.Context) error
 	GetOneUserHandler(ctx echo.Context) error
 	GetUsersHandler(ctx echo.Context) error
+	SearchUsersHandler(c echo.Context) error
 	SignInHandler(c echo.Context) error
+	RefreshTokenHandler(c echo.Context) error
+	SignOutHandler(c echo.Context) error
+	RevokeUserSessionsHandler(c echo.Context) error
+	ParseAuthToken(c echo.Context, auth string) (interface{}, error)
+	JWKSHandler(c echo.Context) error
 	DeleteUserHandler(c echo.Context) error
 	DeleteOwnerProfileHandler(c echo.Context) error
 	UpdateUserHandler(c echo.Context) error
 	UpdateOwnerProfileHandler(c echo.Context) error
 	RateUserHandler(c echo.Context) error
+	AssignRoleHandler(c echo.Context) error
+	UnlockUserHandler(c echo.Context) error
+	GetDeletedUsersHandler(c echo.Context) error
+	RestoreUserHandler(c echo.Context) error
+	PurgeUserHandler(c echo.Context) error
 }
 
-func NewUserController(us interactor.UserInteractor) UserController {
-	return &userController{us}
+func NewUserController(us interactor.UserInteractor, pagination mappers.PaginationPolicy) UserController {
+	return &userController{us, pagination}
 }
 
 func (uC *userController) SignUpHandler(c echo.Context) error {
 	var signUpRequest requests.SignUpRequest
 	if err := c.Bind(&signUpRequest); err != nil {
-		appErr := apperrors.CanNotBindErr.AppendMessage(err)
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(appErr)
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
 	if err := c.Validate(signUpRequest); err != nil {
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
-	duration, token, err := uC.userInteractor.SignUp(c.Request().Context(), mappers.MapSignUpRequestToUser(&signUpRequest))
+	tokens, err := uC.userInteractor.SignUp(c.Request().Context(), mappers.MapSignUpRequestToUser(&signUpRequest))
 	if err != nil {
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
-	saveAuthcookie(c, token, duration)
+	saveAuthcookies(c, tokens)
 
 	return c.JSON(http.StatusCreated, requests.SignUpInResponse{Message: "You are logged in!"})
 }
@@ -60,34 +68,111 @@ func (uC *userController) SignUpHandler(c echo.Context) error {
 func (uC *userController) SignInHandler(c echo.Context) error {
 	var signInRequest requests.SignInRequest
 	if err := c.Bind(&signInRequest); err != nil {
-		appErr := apperrors.CanNotBindErr.AppendMessage(err)
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(appErr)
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
-	duration, token, err := uC.userInteractor.SignIn(c.Request().Context(), signInRequest.UserName, signInRequest.Password)
+	tokens, err := uC.userInteractor.SignIn(c.Request().Context(), signInRequest.UserName, signInRequest.Password)
 	if err != nil {
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
-	saveAuthcookie(c, token, duration)
+	saveAuthcookies(c, tokens)
 
 	return c.JSON(http.StatusOK, requests.SignUpInResponse{Message: "You are logged in!"})
 }
 
+func (uC *userController) RefreshTokenHandler(c echo.Context) error {
+	var refreshRequest requests.RefreshRequest
+	if err := c.Bind(&refreshRequest); err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	if refreshRequest.RefreshToken == "" {
+		if cookie, err := c.Cookie(refreshTokenCookieName); err == nil {
+			refreshRequest.RefreshToken = cookie.Value
+		}
+	}
+
+	tokens, err := uC.userInteractor.RefreshTokens(c.Request().Context(), refreshRequest.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	saveAuthcookies(c, tokens)
+
+	return c.JSON(http.StatusOK, requests.SignUpInResponse{Message: "Your tokens are refreshed!"})
+}
+
+func (uC *userController) SignOutHandler(c echo.Context) error {
+	claims := FetchUserClaim(c)
+
+	var signOutRequest requests.RefreshRequest
+	if err := c.Bind(&signOutRequest); err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	if signOutRequest.RefreshToken == "" {
+		if cookie, err := c.Cookie(refreshTokenCookieName); err == nil {
+			signOutRequest.RefreshToken = cookie.Value
+		}
+	}
+
+	if err := uC.userInteractor.SignOut(c.Request().Context(), claims, signOutRequest.RefreshToken); err != nil {
+		return err
+	}
+
+	clearAuthcookies(c)
+
+	return c.JSON(http.StatusOK, requests.SignUpInResponse{Message: "You are logged out!"})
+}
+
+func (uC *userController) RevokeUserSessionsHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	if err := uC.userInteractor.RevokeUserSessions(c.Request().Context(), uint(id)); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, fmt.Sprintf("All sessions of the user with id:%d are revoked", id))
+}
+
+func (uC *userController) UnlockUserHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	if err := uC.userInteractor.UnlockUser(c.Request().Context(), uint(id)); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, fmt.Sprintf("The user with id:%d is unlocked", id))
+}
+
+// ParseAuthToken is used as the JWT middleware ParseTokenFunc, so revoked
+// tokens are rejected before any restricted handler runs.
+func (uC *userController) ParseAuthToken(c echo.Context, auth string) (interface{}, error) {
+	return uC.userInteractor.ParseToken(c.Request().Context(), auth)
+}
+
+// JWKSHandler publishes the public token verification keys, so other services
+// can verify access tokens without the signing secret.
+func (uC *userController) JWKSHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, uC.userInteractor.JWKS())
+}
+
 func (uC *userController) GetOneUserHandler(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		appErr := apperrors.CanNotBindErr.AppendMessage(err)
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(appErr)
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
 	user, err := uC.userInteractor.FindOneSigner(c.Request().Context(), uint(id))
 	if err != nil {
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
 	return c.JSON(http.StatusOK, mappers.MapUserToGetUserResponse(user))
@@ -98,52 +183,129 @@ func (uC *userController) GetUsersHandler(c echo.Context) error {
 
 	name := claims.User.UserName
 
-	pagination := mappers.MapContextToPagination(c)
-	pagination, users, err := uC.userInteractor.FindSigners(c.Request().Context(), pagination)
-	if err != nil {
-		c.Logger().Error(err)
-		return mappers.MapAppErrorToHTTPError(err)
+	var getUsersRequest requests.GetUsersRequest
+	if err := c.Bind(&getUsersRequest); err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
-	urlPath := c.Request().URL.Path
-	pagination.FirstPage = fmt.Sprintf("%s?limit=%d&page=%d&sort=%s", urlPath, pagination.Limit, 1, pagination.Sort)
-	pagination.LastPage = fmt.Sprintf("%s?limit=%d&page=%d&sort=%s", urlPath, pagination.Limit, pagination.TotalPages, pagination.Sort)
+	if err := c.Validate(getUsersRequest); err != nil {
+		return err
+	}
 
-	if pagination.Page > 1 {
-		pagination.PreviousPage = fmt.Sprintf("%s?limit=%d&page=%d&sort=%s", urlPath, pagination.Limit, pagination.Page-1, pagination.Sort)
+	pagination, err := mappers.MapContextToUserPagination(c, uC.pagination)
+	if err != nil {
+		return err
 	}
 
-	if pagination.Page < pagination.TotalPages {
-		pagination.NextPage = fmt.Sprintf("%s?limit=%d&page=%d&sort=%s", urlPath, pagination.Limit, pagination.Page+1, pagination.Sort)
+	pagination, users, err := uC.userInteractor.FindSigners(c.Request().Context(), mappers.MapGetUsersRequestToUserFilter(&getUsersRequest), pagination)
+	if err != nil {
+		return err
 	}
 
+	mappers.MapPageLinks(c, pagination)
+
 	return c.JSON(http.StatusOK, mappers.MapPaginationAndUsersToGetUsersResponse(users, pagination, name))
 }
 
+// SearchUsersHandler answers like GetUsersHandler, with the best matches
+// first. The sort is always "relevance".
+func (uC *userController) SearchUsersHandler(c echo.Context) error {
+	claims := FetchUserClaim(c)
+
+	var searchUsersRequest requests.SearchUsersRequest
+	if err := c.Bind(&searchUsersRequest); err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	if err := c.Validate(searchUsersRequest); err != nil {
+		return err
+	}
+
+	pagination, err := mappers.MapContextToPagination(c, uC.pagination)
+	if err != nil {
+		return err
+	}
+	pagination.Sort = mappers.RelevanceSort
+
+	pagination, users, err := uC.userInteractor.SearchSigners(c.Request().Context(), searchUsersRequest.Q, pagination)
+	if err != nil {
+		return err
+	}
+
+	mappers.MapPageLinks(c, pagination)
+
+	return c.JSON(http.StatusOK, mappers.MapPaginationAndUsersToGetUsersResponse(users, pagination, claims.User.UserName))
+}
+
 func (uC *userController) DeleteUserHandler(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		appErr := apperrors.CanNotBindErr.AppendMessage(err)
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(appErr)
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
 	if err := uC.userInteractor.DeleteSignerByID(c.Request().Context(), id); err != nil {
-		c.Logger().Warn(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
 	return c.JSON(http.StatusOK, fmt.Sprintf("User with id:%d is deleted", id))
 }
 
+// GetDeletedUsersHandler answers like GetUsersHandler with the deleted users,
+// the latest deleted first unless sort is given.
+func (uC *userController) GetDeletedUsersHandler(c echo.Context) error {
+	claims := FetchUserClaim(c)
+
+	pagination, err := mappers.MapContextToDeletedUserPagination(c, uC.pagination)
+	if err != nil {
+		return err
+	}
+
+	pagination, users, err := uC.userInteractor.FindDeletedSigners(c.Request().Context(), pagination)
+	if err != nil {
+		return err
+	}
+
+	mappers.MapPageLinks(c, pagination)
+
+	return c.JSON(http.StatusOK, mappers.MapPaginationAndUsersToGetUsersResponse(users, pagination, claims.User.UserName))
+}
+
+func (uC *userController) RestoreUserHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	user, err := uC.userInteractor.RestoreSigner(c.Request().Context(), uint(id))
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mappers.MapUserToGetUserResponse(user))
+}
+
+// PurgeUserHandler deletes a deleted user for good, with the ratings it gave
+// and got.
+func (uC *userController) PurgeUserHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	if err := uC.userInteractor.PurgeSigner(c.Request().Context(), uint(id)); err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, fmt.Sprintf("User with id:%d is purged", id))
+}
+
 func (uC *userController) DeleteOwnerProfileHandler(c echo.Context) error {
 	claims := FetchUserClaim(c)
 
 	id := int(claims.User.ID)
 
 	if err := uC.userInteractor.DeleteOwnSignIn(c.Request().Context(), id); err != nil {
-		c.Logger().Warn(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
 	return c.JSON(http.StatusOK, "Your profile is deleted")
@@ -152,22 +314,17 @@ func (uC *userController) DeleteOwnerProfileHandler(c echo.Context) error {
 func (uC *userController) UpdateUserHandler(c echo.Context) error {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		appErr := apperrors.CanNotBindErr.AppendMessage(err)
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(appErr)
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
 	var updateRequest requests.UpdateRequest
 	if err := c.Bind(&updateRequest); err != nil {
-		appErr := apperrors.CanNotBindErr.AppendMessage(err)
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(appErr)
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
 	user, err := uC.userInteractor.UpdateSignersByID(c.Request().Context(), id, mappers.MapUpdateRequestToUser(&updateRequest))
 	if err != nil {
-		c.Logger().Warn(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
 	return c.JSON(http.StatusOK, mappers.MapUserToUpdateResponse(user))
@@ -180,15 +337,12 @@ func (uC *userController) UpdateOwnerProfileHandler(c echo.Context) error {
 
 	var updateOwnRequest requests.UpdateOwnRequest
 	if err := c.Bind(&updateOwnRequest); err != nil {
-		appErr := apperrors.CanNotBindErr.AppendMessage(err)
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(appErr)
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
 	user, err := uC.userInteractor.UpdateOwnSignIn(c.Request().Context(), id, mappers.MapUpdateOwnRequestToUser(&updateOwnRequest))
 	if err != nil {
-		c.Logger().Warn(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
 	return c.JSON(http.StatusOK, mappers.MapUserToUpdateResponse(user))
@@ -196,43 +350,89 @@ func (uC *userController) UpdateOwnerProfileHandler(c echo.Context) error {
 
 func (uC *userController) RateUserHandler(c echo.Context) error {
 	username := c.Param("username")
-	user := FetchUserClaim(c).User
-	if username == user.UserName {
-		err := &apperrors.CanNotRateYorself
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
-	}
 
 	var rateRequest requests.RateRequest
 	if err := c.Bind(&rateRequest); err != nil {
-		appErr := apperrors.CanNotBindErr.AppendMessage(err)
-		c.Logger().Error(err.Error())
-		return mappers.MapAppErrorToHTTPError(appErr)
+		return apperrors.CanNotBindErr.AppendMessage(err)
 	}
 
 	if rateRequest.Rate != "up" && rateRequest.Rate != "down" && rateRequest.Rate != "rm" {
 		err := &apperrors.WrongTextInRateRequest
-		c.Logger().Warn(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 
-	user, err := uC.userInteractor.RateUser(c.Request().Context(), user.ID, username, rateRequest.Rate)
+	user, err := uC.userInteractor.RateUser(c.Request().Context(), FetchUserClaim(c).User.ID, username, rateRequest.Rate)
 	if err != nil {
-		c.Logger().Warn(err.Error())
-		return mappers.MapAppErrorToHTTPError(err)
+		return err
 	}
 	return c.JSON(http.StatusOK, mappers.MapUserToGetUserResponse(user))
 }
 
+func (uC *userController) AssignRoleHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	var assignRoleRequest requests.AssignRoleRequest
+	if err := c.Bind(&assignRoleRequest); err != nil {
+		return apperrors.CanNotBindErr.AppendMessage(err)
+	}
+
+	if err := c.Validate(assignRoleRequest); err != nil {
+		return err
+	}
+
+	user, err := uC.userInteractor.AssignRole(c.Request().Context(), FetchUserClaim(c).User.ID, uint(id), assignRoleRequest.Role)
+	if err != nil {
+		return err
+	}
+
+	return c.JSON(http.StatusOK, mappers.MapUserToUpdateResponse(user))
+}
+
 func FetchUserClaim(c echo.Context) *interactor.AuthClaims {
 	return c.Get("user").(*jwt.Token).Claims.(*interactor.AuthClaims)
 }
 
+// The access token cookie is sent to the whole API, whichever endpoint set
+// it. The refresh token cookie is sent only to /api/v1/auth/refresh and
+// /api/v1/auth/logout.
+const (
+	authCookieName         = "Authorization"
+	authCookiePath         = "/api/v1"
+	refreshTokenCookieName = "RefreshToken"
+	refreshTokenCookiePath = "/api/v1/auth"
+)
+
+func saveAuthcookies(c echo.Context, tokens *interactor.AuthTokens) {
+	saveAuthcookie(c, tokens.AccessToken, tokens.AccessTokenTTL)
+	saveRefreshTokenCookie(c, tokens.RefreshToken, tokens.RefreshTokenTTL, refreshTokenCookiePath)
+}
+
+// clearAuthcookies expires the cookies at the same paths they are set at,
+// otherwise the browser would keep them.
+func clearAuthcookies(c echo.Context) {
+	saveAuthcookie(c, "", -1)
+	saveRefreshTokenCookie(c, "", -1, refreshTokenCookiePath)
+}
+
+func saveRefreshTokenCookie(c echo.Context, token string, duration int, path string) {
+	cookie := new(http.Cookie)
+	cookie.Name = refreshTokenCookieName
+	cookie.Value = token
+	cookie.MaxAge = duration
+	cookie.Path = path
+	cookie.HttpOnly = true
+	c.SetCookie(cookie)
+}
+
 func saveAuthcookie(c echo.Context, token string, duration int) {
 	cookie := new(http.Cookie)
-	cookie.Name = "Authorization"
+	cookie.Name = authCookieName
 	cookie.Value = token
 	cookie.MaxAge = duration
+	cookie.Path = authCookiePath
 	cookie.HttpOnly = true
 	c.SetCookie(cookie)
 }
@@ -2,12 +2,13 @@ package controller
 
 import (
 	"context"
-	"crypto/sha1"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
@@ -17,10 +18,14 @@ import (
 	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/mappers"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/requests"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/hasher"
 	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/signer"
 	v "git.foxminded.com.ua/3_REST_API/interal/validator"
-	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/golang/mock/gomock"
 	"github.com/labstack/echo/v4"
@@ -32,7 +37,7 @@ func TestSignUpHandler(t *testing.T) {
 	inputUser := getTestUser()
 	inputUser.ID = 0
 	inputUser.Rating = 1
-	inputUser.Password = hashingUserFunc(inputUser.Password)
+	inputUser.Role = permissions.UserRole
 
 	testTable := []struct {
 		scenario         string
@@ -70,13 +75,31 @@ func TestSignUpHandler(t *testing.T) {
 			http.StatusBadRequest,
 			&apperrors.CanNotBindErr,
 		},
+		{
+			"a role in the request body is ignored",
+			inputUser,
+			getTestUser(),
+			`{"user_name": "JohnHall", "role": "superadmin", "first_name": "John", "last_name": "Hall", "password": "very12difficult()Password"}`,
+			requests.SignUpInResponse{Message: "You are logged in!"},
+			http.StatusCreated,
+			nil,
+		},
 		{
 			"tries to create a user with an existing username",
 			inputUser,
 			nil,
 			`{"user_name": "JohnHall", "first_name": "John", "last_name": "Hall", "password": "very12difficult()Password"}`,
 			requests.SignUpInResponse{Message: "You are logged in!"},
-			http.StatusBadRequest,
+			http.StatusConflict,
+			&apperrors.UsernameTakenErr,
+		},
+		{
+			"database fails to create the user",
+			inputUser,
+			nil,
+			`{"user_name": "JohnHall", "first_name": "John", "last_name": "Hall", "password": "very12difficult()Password"}`,
+			requests.SignUpInResponse{Message: "You are logged in!"},
+			http.StatusInternalServerError,
 			&apperrors.CanNotCreateUserErr,
 		},
 	}
@@ -88,23 +111,26 @@ func TestSignUpHandler(t *testing.T) {
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			userRepoMock := mocks.NewMockUserRepository(ctrl)
-			uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-			uController := NewUserController(uInteractor)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
 			e := echo.New()
-			e.Validator = &v.CustomValidator{Validator: validator.New()}
+			e.Validator = newTestValidator(t)
 			req := httptest.NewRequest(http.MethodPost, "/sing-up", strings.NewReader(tc.signUpRequest))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
 
-			userRepoMock.EXPECT().CreateUser(ctx, tc.inputuser).Return(tc.expectedUser, tc.expectedError).AnyTimes()
+			userRepoMock.EXPECT().CreateUser(ctx, hashedUser(tc.inputuser)).Return(tc.expectedUser, tc.expectedError).AnyTimes()
+			refreshTokenRepoMock.EXPECT().CreateRefreshToken(ctx, gomock.Any()).Return(nil).AnyTimes()
 
 			err := uController.SignUpHandler(c)
 
 			if err != nil {
 				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.expectedhttpCode, err.(*echo.HTTPError).Code)
+				assert.Equal(t, tc.expectedhttpCode, err.(*apperrors.AppError).HTTPCode)
 				return
 			}
 			assert.Equal(t, tc.expectedhttpCode, rec.Code)
@@ -118,6 +144,34 @@ func TestSignUpHandler(t *testing.T) {
 	}
 }
 
+func TestSignUpHandlerValidationFields(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	uInteractor := interactor.NewUserInteractor(mocks.NewMockUserRepository(ctrl), mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+	uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+	e := echo.New()
+	e.Validator = newTestValidator(t)
+	req := httptest.NewRequest(http.MethodPost, "/sing-up", strings.NewReader(`{"user_name": "JohnHall", "last_name": "Hall", "password": "1234"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	err := uController.SignUpHandler(c)
+
+	appErr, ok := err.(*apperrors.AppError)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, apperrors.ValidatorErr.Code, appErr.Code)
+	assert.Equal(t, []apperrors.FieldError{
+		{Field: "first_name", Rule: "required", Message: "the field is required"},
+		{Field: "password", Rule: "password", Message: "password must containe at least 7 letters,  1 number, 1 upper case, 1 special character"},
+	}, appErr.Fields)
+}
+
 func TestSignInHandler(t *testing.T) {
 
 	testTable := []struct {
@@ -137,7 +191,7 @@ func TestSignInHandler(t *testing.T) {
 				Role:      "admin",
 				FirstName: "John",
 				LastName:  "Hall",
-				Password:  hashingUserFunc("very12difficult()Password"),
+				Password:  "very12difficult()Password",
 				CreatedAt: nil,
 				UpdatedAt: nil,
 			},
@@ -154,7 +208,7 @@ func TestSignInHandler(t *testing.T) {
 				UserName:  "JohnHall",
 				FirstName: "John",
 				LastName:  "Hall",
-				Password:  hashingUserFunc("very12difficult()Password"),
+				Password:  "very12difficult()Password",
 				CreatedAt: nil,
 				UpdatedAt: nil,
 			},
@@ -171,264 +225,1162 @@ func TestSignInHandler(t *testing.T) {
 				UserName:  "JohnHall",
 				FirstName: "John",
 				LastName:  "Hall",
-				Password:  hashingUserFunc("very12difficult()Password"),
+				Password:  "very12difficult()Password",
 				CreatedAt: nil,
 				UpdatedAt: nil,
 			},
 			nil,
 			`{"user_name": "JohnHall", "password": "very12difficult()Password"}`,
 			requests.SignUpInResponse{Message: "You are logged in!"},
+			http.StatusUnauthorized,
+			&apperrors.UserNotFoundErr,
+		},
+		{
+			"database fails to find the user",
+			&models.User{
+				ID:       0,
+				UserName: "JohnHall",
+				Password: "very12difficult()Password",
+			},
+			nil,
+			`{"user_name": "JohnHall", "password": "very12difficult()Password"}`,
+			requests.SignUpInResponse{Message: "You are logged in!"},
+			http.StatusInternalServerError,
+			&apperrors.CanNotSignInErr,
+		},
+	}
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			e.Validator = newTestValidator(t)
+			req := httptest.NewRequest(http.MethodPost, "/sing-in", strings.NewReader(tc.signUpRequest))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			storedUser := tc.expectedUser
+			if storedUser != nil {
+				storedUser.Password = hashingUserFunc(t, tc.inputuser.Password)
+			}
+			userRepoMock.EXPECT().FindOneUserByUserName(ctx, tc.inputuser.UserName).
+				Return(storedUser, tc.expectedError).AnyTimes()
+			refreshTokenRepoMock.EXPECT().CreateRefreshToken(ctx, gomock.Any()).Return(nil).AnyTimes()
+
+			err := uController.SignInHandler(c)
+
+			if err != nil {
+				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
+				assert.Equal(t, tc.expectedhttpCode, err.(*apperrors.AppError).HTTPCode)
+				return
+			}
+			assert.Equal(t, tc.expectedhttpCode, rec.Code)
+			marshalledResponse, err := json.Marshal(tc.expectedResponse)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, string(marshalledResponse), strings.TrimSuffix(rec.Body.String(), "\n"))
+			}
+
+		})
+	}
+}
+
+func TestRefreshTokenHandler(t *testing.T) {
+
+	testTable := []struct {
+		scenario         string
+		refreshRequest   string
+		cookieToken      string
+		expectedToken    string
+		foundToken       *models.RefreshToken
+		findError        error
+		expectedResponse requests.SignUpInResponse
+		expectedhttpCode int
+	}{
+		{
+			"tokens refreshed with a cookie",
+			`{}`,
+			"refresh_token",
+			"refresh_token",
+			&models.RefreshToken{ID: 1, UserID: 124, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)},
+			nil,
+			requests.SignUpInResponse{Message: "Your tokens are refreshed!"},
+			http.StatusOK,
+		},
+		{
+			"tokens refreshed with a request body",
+			`{"refresh_token": "body_token"}`,
+			"refresh_token",
+			"body_token",
+			&models.RefreshToken{ID: 1, UserID: 124, FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour)},
+			nil,
+			requests.SignUpInResponse{Message: "Your tokens are refreshed!"},
+			http.StatusOK,
+		},
+		{
+			"unknown refresh token",
+			`{}`,
+			"refresh_token",
+			"refresh_token",
+			nil,
+			errors.New("record not found"),
+			requests.SignUpInResponse{},
+			http.StatusUnauthorized,
+		},
+	}
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/auth/refresh", strings.NewReader(tc.refreshRequest))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.AddCookie(&http.Cookie{Name: "RefreshToken", Value: tc.cookieToken})
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			refreshTokenRepoMock.EXPECT().FindRefreshTokenByHash(ctx, gomock.Any()).Return(tc.foundToken, tc.findError)
+			userRepoMock.EXPECT().FindOneUserByID(ctx, uint(124)).Return(getTestUser(), nil).AnyTimes()
+			refreshTokenRepoMock.EXPECT().RotateRefreshToken(ctx, uint(1), gomock.Any()).Return(nil).AnyTimes()
+
+			err := uController.RefreshTokenHandler(c)
+
+			if err != nil {
+				assert.Equal(t, tc.expectedhttpCode, err.(*apperrors.AppError).HTTPCode)
+				return
+			}
+			assert.Equal(t, tc.expectedhttpCode, rec.Code)
+			marshalledResponse, err := json.Marshal(tc.expectedResponse)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, string(marshalledResponse), strings.TrimSuffix(rec.Body.String(), "\n"))
+			}
+
+			var cookies []string
+			for _, cookie := range rec.Result().Cookies() {
+				cookies = append(cookies, cookie.Name+" "+cookie.Path)
+			}
+			assert.ElementsMatch(t, []string{"Authorization /api/v1", "RefreshToken /api/v1/auth"}, cookies)
+		})
+	}
+}
+
+func TestSignOutHandler(t *testing.T) {
+
+	testTable := []struct {
+		scenario         string
+		signOutRequest   string
+		expectedToken    string
+		foundToken       *models.RefreshToken
+		expectRevoke     bool
+		expectedResponse requests.SignUpInResponse
+		expectedhttpCode int
+	}{
+		{
+			"signed out with a refresh token",
+			`{"refresh_token": "refresh_token"}`,
+			"refresh_token",
+			&models.RefreshToken{ID: 1, UserID: 124, FamilyID: "family"},
+			true,
+			requests.SignUpInResponse{Message: "You are logged out!"},
+			http.StatusOK,
+		},
+		{
+			"refresh token of another user is ignored",
+			`{"refresh_token": "refresh_token"}`,
+			"refresh_token",
+			&models.RefreshToken{ID: 1, UserID: 125, FamilyID: "family"},
+			false,
+			requests.SignUpInResponse{Message: "You are logged out!"},
+			http.StatusOK,
+		},
+		{
+			"signed out without a refresh token",
+			`{}`,
+			"",
+			nil,
+			false,
+			requests.SignUpInResponse{Message: "You are logged out!"},
+			http.StatusOK,
+		},
+	}
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			revocationStore := repository.NewInMemoryRevocationStore()
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), revocationStore, newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader(tc.signOutRequest))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			token := tokenGenerator()
+			c.Set("user", token)
+
+			if tc.expectedToken != "" {
+				refreshTokenRepoMock.EXPECT().FindRefreshTokenByHash(ctx, gomock.Any()).Return(tc.foundToken, nil)
+			}
+			if tc.expectRevoke {
+				refreshTokenRepoMock.EXPECT().RevokeRefreshTokenFamily(ctx, tc.foundToken.FamilyID).Return(nil)
+			}
+
+			err := uController.SignOutHandler(c)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.expectedhttpCode, rec.Code)
+			marshalledResponse, err := json.Marshal(tc.expectedResponse)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, string(marshalledResponse), strings.TrimSuffix(rec.Body.String(), "\n"))
+			}
+
+			claims := token.Claims.(*interactor.AuthClaims)
+			revoked, err := revocationStore.IsRevoked(ctx, claims.ID, claims.User.ID, time.Now().Add(time.Hour))
+			if assert.NoError(t, err) {
+				assert.True(t, revoked)
+			}
+
+			// the cookies are expired at the paths they were set at
+			var expiredCookies []string
+			for _, cookie := range rec.Result().Cookies() {
+				assert.Equal(t, -1, cookie.MaxAge, cookie.Name)
+				expiredCookies = append(expiredCookies, cookie.Name+" "+cookie.Path)
+			}
+			assert.ElementsMatch(t, []string{"Authorization /api/v1", "RefreshToken /api/v1/auth"}, expiredCookies)
+		})
+	}
+}
+
+func TestRevokeUserSessionsHandler(t *testing.T) {
+
+	testTable := []struct {
+		scenario      string
+		inputUserID   string
+		httpCode      int
+		expectedError error
+	}{
+		{
+			"sessions are revoked",
+			"124",
+			http.StatusOK,
+			nil,
+		},
+		{
+			"wrong path params",
+			"userID",
+			http.StatusBadRequest,
+			&apperrors.CanNotBindErr,
+		},
+		{
+			"refresh tokens can't be revoked",
+			"124",
+			http.StatusInternalServerError,
+			&apperrors.CanNotRevokeTokenErr,
+		},
+	}
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/user/:id/revoke-sessions", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tc.inputUserID)
+
+			refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, uint(124)).Return(tc.expectedError).AnyTimes()
+
+			err := uController.RevokeUserSessionsHandler(c)
+
+			if err != nil {
+				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
+				return
+			}
+			assert.Equal(t, tc.httpCode, rec.Code)
+		})
+	}
+}
+
+func TestUnlockUserHandler(t *testing.T) {
+
+	testTable := []struct {
+		scenario      string
+		inputUserID   string
+		httpCode      int
+		expectedError error
+	}{
+		{
+			"user is unlocked",
+			"125",
+			http.StatusOK,
+			nil,
+		},
+		{
+			"wrong path params",
+			"userID",
+			http.StatusBadRequest,
+			&apperrors.CanNotBindErr,
+		},
+		{
+			"user doesn't exist",
+			"126",
 			http.StatusBadRequest,
 			&apperrors.UserNotFoundErr,
 		},
 	}
 
-	ctx := context.Background()
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), loginAttemptRepoMock, newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/user/:id/unlock", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tc.inputUserID)
+
+			userRepoMock.EXPECT().FindOneUserByID(ctx, uint(125)).Return(&models.User{ID: 125, UserName: "JaneHall"}, nil).AnyTimes()
+			userRepoMock.EXPECT().FindOneUserByID(ctx, uint(126)).Return(nil, apperrors.UserNotFoundErr.AppendMessage(errors.New("record not found"))).AnyTimes()
+			loginAttemptRepoMock.EXPECT().UnlockUser(ctx, uint(125)).Return(nil).AnyTimes()
+
+			err := uController.UnlockUserHandler(c)
+
+			if err != nil {
+				assert.True(t, apperrors.Is(err, tc.expectedError.(*apperrors.AppError)))
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
+				return
+			}
+			assert.Equal(t, tc.httpCode, rec.Code)
+		})
+	}
+}
+
+func TestSignInHandlerRetryAfter(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userLimiter := ratelimit.NewLimiter(repository.NewInMemoryRateLimitStore(), "user", ratelimit.Rule{Capacity: 1, RefillEvery: time.Minute})
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{UserLimiter: userLimiter}, interactor.DeletionOptions{})
+	uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+	userRepoMock.EXPECT().FindOneUserByUserName(gomock.Any(), "JohnHall").
+		Return(&models.User{ID: 121, UserName: "JohnHall", Password: hashingUserFunc(t, "1234")}, nil)
+
+	e := echo.New()
+	for _, httpCode := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/sing-in", strings.NewReader(`{"user_name":"JohnHall", "password":"4321"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		err := uController.SignInHandler(c)
+		assert.Equal(t, httpCode, err.(*apperrors.AppError).HTTPCode)
+
+		if httpCode == http.StatusTooManyRequests {
+			retryAfter := err.(*apperrors.AppError).RetryAfter
+			assert.True(t, retryAfter > 0 && retryAfter <= time.Minute, "retry after %s", retryAfter)
+		}
+	}
+}
+
+func TestAssignRoleHandler(t *testing.T) {
+
+	testTable := []struct {
+		scenario          string
+		inputUserID       string
+		assignRoleRequest string
+		httpCode          int
+		expectedError     error
+	}{
+		{
+			"role is assigned",
+			"125",
+			`{"role": "moderator"}`,
+			http.StatusOK,
+			nil,
+		},
+		{
+			"wrong path params",
+			"userID",
+			`{"role": "moderator"}`,
+			http.StatusBadRequest,
+			&apperrors.CanNotBindErr,
+		},
+		{
+			"unknown role",
+			"125",
+			`{"role": "superadmin"}`,
+			http.StatusBadRequest,
+			&apperrors.ValidatorErr,
+		},
+		{
+			"admin tries to change own role",
+			"124",
+			`{"role": "user"}`,
+			http.StatusForbidden,
+			&apperrors.CanNotChangeOwnRoleErr,
+		},
+	}
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			e.Validator = newTestValidator(t)
+			req := httptest.NewRequest(http.MethodPut, "/user/:id/role", strings.NewReader(tc.assignRoleRequest))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tc.inputUserID)
+			c.Set("user", tokenGenerator())
+
+			userRepoMock.EXPECT().FindOneUserByID(ctx, uint(125)).Return(&models.User{ID: 125, UserName: "JaneHall", Role: "user"}, nil).AnyTimes()
+			userRepoMock.EXPECT().UpdateUserRole(ctx, uint(125), "moderator").Return(nil).AnyTimes()
+
+			err := uController.AssignRoleHandler(c)
+
+			if err != nil {
+				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
+				return
+			}
+			assert.Equal(t, tc.httpCode, rec.Code)
+		})
+	}
+}
+
+func TestGetOneUserHandler(t *testing.T) {
+
+	user := getTestUser()
+	userId := strconv.Itoa(int(user.ID))
+
+	testTable := []struct {
+		scenario      string
+		expectedUser  *models.User
+		inputUserID   string
+		response      *requests.GetOneUserResponse
+		httpCode      int
+		expectedError error
+	}{
+		{
+			"get one user by id",
+			user,
+			userId,
+			mappers.MapUserToGetUserResponse(user),
+			http.StatusOK,
+			nil,
+		},
+		{
+			"wrong path params",
+			user,
+			"userID",
+			mappers.MapUserToGetUserResponse(user),
+			http.StatusBadRequest,
+			&apperrors.CanNotBindErr,
+		},
+		{
+			"user not found by id",
+			user,
+			userId,
+			mappers.MapUserToGetUserResponse(user),
+			http.StatusBadRequest,
+			&apperrors.UserNotFoundErr,
+		},
+	}
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/user/:id", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+
+			c.SetParamValues(tc.inputUserID)
+			userRepoMock.EXPECT().FindOneUserByID(ctx, tc.expectedUser.ID).Return(tc.expectedUser, tc.expectedError).AnyTimes()
+
+			err := uController.GetOneUserHandler(c)
+
+			if err != nil {
+				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
+				return
+			}
+			assert.Equal(t, tc.httpCode, rec.Code)
+			marshalledResponse, err := json.Marshal(tc.response)
+
+			if assert.NoError(t, err) {
+				assert.Equal(t, string(marshalledResponse), strings.TrimSuffix(rec.Body.String(), "\n"))
+			}
+
+		})
+	}
+}
+
+func TestGetUsersHandler(t *testing.T) {
+	now := time.Now()
+
+	expectedPagination := &models.Pagination{
+		Limit: 5,
+		Page:  1,
+		Sort:  "id desc",
+	}
+
+	paginationLimit := strconv.Itoa(int(expectedPagination.Limit))
+	paginationPage := strconv.Itoa(int(expectedPagination.Page))
+
+	usersGenerator := func(pagination *models.Pagination) []*models.User {
+		users := make([]*models.User, pagination.Limit)
+
+		for i := 0; i < len(users); i++ {
+			users[i] = &models.User{}
+			users[i].ID = uint(((pagination.Page - 1) * pagination.Limit) + i + 1)
+			users[i].UserName = fmt.Sprintf("UniqueUser%d", int(users[i].ID))
+			users[i].Role = "admin"
+			users[i].FirstName = fmt.Sprintf("FirstName%d", int(users[i].ID))
+			users[i].LastName = fmt.Sprintf("LastName%d", int(users[i].ID))
+			users[i].CreatedAt = &now
+			users[i].UpdatedAt = &now
+		}
+
+		return users
+	}
+
+	fineUsers := usersGenerator(expectedPagination)
+
+	tokenGenerator := func(user *models.User) *jwt.Token {
+		claims := &interactor.AuthClaims{
+			User: user,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * (time.Duration(1)))),
+			},
+		}
+
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	}
+
+	testTable := []struct {
+		scenario           string
+		expectedPagination *models.Pagination
+		expectedUsers      []*models.User
+		httpCode           int
+		expectedError      error
+	}{
+		{
+			"get users",
+			expectedPagination,
+			fineUsers,
+			http.StatusOK,
+			nil,
+		},
+		{
+			"wrong query param",
+			&models.Pagination{
+				Limit: 5,
+				Page:  1,
+				Sort:  "id desc",
+			},
+			fineUsers,
+			http.StatusOK,
+			nil,
+		},
+		{
+			"bad user role",
+			&models.Pagination{
+				Limit: 5,
+				Page:  1,
+				Sort:  "id desc",
+			},
+			fineUsers,
+			http.StatusForbidden,
+			&apperrors.WrongRoleErr,
+		},
+	}
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			e.Validator = newTestValidator(t)
+			q := make(url.Values)
+
+			if tc.scenario != "wrong query param" {
+				q.Set("limit", paginationLimit)
+				q.Set("page", paginationPage)
+				q.Set("sort", tc.expectedPagination.Sort)
+			}
+
+			userRepoMock.EXPECT().FindUsers(ctx, &models.UserFilter{}, tc.expectedPagination).Return(tc.expectedPagination, tc.expectedUsers, tc.expectedError).AnyTimes()
+
+			req := httptest.NewRequest(http.MethodGet, "/users?"+q.Encode(), nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			if tc.scenario == "bad user role" {
+				tc.expectedUsers[0].Role = "user"
+			}
+
+			c.Set("user", tokenGenerator(tc.expectedUsers[0]))
+
+			err := uController.GetUsersHandler(c)
+			if err != nil {
+				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
+				return
+			}
+			assert.Equal(t, tc.httpCode, rec.Code)
+
+			var pag requests.GetUsersResponse
+			json.NewDecoder(rec.Body).Decode(&pag)
+
+			if assert.NoError(t, err) {
+				for i, v := range pag.UsersResponse.Rows.([]interface{}) {
+					assert.Equal(t, v.(map[string]interface{})["user_name"].(string), tc.expectedUsers[i].UserName)
+					assert.Equal(t, v.(map[string]interface{})["first_name"].(string), tc.expectedUsers[i].FirstName)
+					assert.Equal(t, v.(map[string]interface{})["last_name"].(string), tc.expectedUsers[i].LastName)
+				}
+			}
+
+		})
+	}
+}
+
+func TestGetUsersHandlerQuery(t *testing.T) {
+	rating, createdAfter := -2, time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	testTable := []struct {
+		scenario           string
+		query              string
+		expectedFilter     *models.UserFilter
+		expectedPagination *models.Pagination
+		expectedError      *apperrors.AppError
+		expectedField      string
+	}{
+		{
+			"filters and sort keys",
+			"role=moderator&rating_gte=-2&created_after=2023-01-02T00:00:00Z&q=jo&sort=rating+DESC,user_name",
+			&models.UserFilter{Role: "moderator", RatingGTE: &rating, CreatedAfter: &createdAfter, Query: "jo"},
+			&models.Pagination{Limit: 5, Page: 1, Sort: "rating desc,user_name asc"},
+			nil,
+			"",
+		},
+		{
+			"sorting by password",
+			"sort=password",
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"sort",
+		},
+		{
+			"SQL in sort",
+			"sort=" + url.QueryEscape("id;DROP TABLE users"),
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"sort",
+		},
+		{
+			"unknown direction",
+			"sort=id+sideways",
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"sort",
+		},
+		{
+			"unknown role",
+			"role=superuser",
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"role",
+		},
+		{
+			"too long search",
+			"q=" + strings.Repeat("a", 65),
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"q",
+		},
+		{
+			"rating isn't a number",
+			"rating_gte=high",
+			nil,
+			nil,
+			&apperrors.CanNotBindErr,
+			"",
+		},
+		{
+			"time isn't RFC 3339",
+			"created_before=yesterday",
+			nil,
+			nil,
+			&apperrors.CanNotBindErr,
+			"",
+		},
+		{
+			"zero limit",
+			"limit=0",
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"limit",
+		},
+		{
+			"limit above the maximum",
+			"limit=1000000",
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"limit",
+		},
+		{
+			"limit isn't a number",
+			"limit=ten",
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"limit",
+		},
+		{
+			"negative page",
+			"page=-1",
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"page",
+		},
+		{
+			"with_total isn't a boolean",
+			"with_total=maybe",
+			nil,
+			nil,
+			&apperrors.ValidatorErr,
+			"with_total",
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+			e := echo.New()
+			e.Validator = newTestValidator(t)
+			req := httptest.NewRequest(http.MethodGet, "/users?"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set("user", jwt.NewWithClaims(jwt.SigningMethodHS256, &interactor.AuthClaims{User: getTestUser()}))
+
+			if tc.expectedError == nil {
+				userRepoMock.EXPECT().FindUsers(context.Background(), tc.expectedFilter, tc.expectedPagination).Return(tc.expectedPagination, []*models.User{}, nil)
+			}
+
+			err := uController.GetUsersHandler(c)
+			if tc.expectedError != nil {
+				appErr, ok := apperrors.As(err)
+				if assert.True(t, ok, err) {
+					assert.Equal(t, tc.expectedError.Code, appErr.Code)
+					if tc.expectedField != "" && assert.Len(t, appErr.Fields, 1) {
+						assert.Equal(t, tc.expectedField, appErr.Fields[0].Field)
+					}
+				}
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var response requests.GetUsersResponse
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response)) {
+				assert.Contains(t, response.UsersResponse.FirstPage, "role=moderator")
+				assert.Contains(t, response.UsersResponse.FirstPage, "sort=rating+desc%2Cuser_name+asc")
+			}
+		})
+	}
+}
+
+func TestSearchUsersHandler(t *testing.T) {
+	testTable := []struct {
+		scenario      string
+		query         string
+		expectedQuery string
+		expectedError *apperrors.AppError
+		expectedField string
+	}{
+		{"search", "q=jo+hall&limit=2", "jo hall", nil, ""},
+		{"no query", "limit=2", "", &apperrors.ValidatorErr, "q"},
+		{"too long query", "q=" + strings.Repeat("a", 65), "", &apperrors.ValidatorErr, "q"},
+		{"invalid limit", "q=jo&limit=0", "", &apperrors.ValidatorErr, "limit"},
+	}
+
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			userRepoMock := mocks.NewMockUserRepository(ctrl)
-			uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-			uController := NewUserController(uInteractor)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
 			e := echo.New()
-			e.Validator = &v.CustomValidator{Validator: validator.New()}
-			req := httptest.NewRequest(http.MethodPost, "/sing-in", strings.NewReader(tc.signUpRequest))
-			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			e.Validator = newTestValidator(t)
+			req := httptest.NewRequest(http.MethodGet, "/users/search?"+tc.query, nil)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
+			c.Set("user", jwt.NewWithClaims(jwt.SigningMethodHS256, &interactor.AuthClaims{User: getTestUser()}))
+
+			if tc.expectedError == nil {
+				userRepoMock.EXPECT().SearchUsers(context.Background(), tc.expectedQuery, &models.Pagination{Limit: 2, Page: 1, Sort: mappers.RelevanceSort}).
+					DoAndReturn(func(_ context.Context, _ string, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+						pagination.TotalRows, pagination.TotalPages = 3, 2
+						return pagination, []*models.User{getTestUser(), getTestUser()}, nil
+					})
+			}
 
-			userRepoMock.EXPECT().FindOneUserByUserNameAndPassword(ctx, tc.inputuser.UserName, tc.inputuser.Password).
-				Return(tc.expectedUser, tc.expectedError).AnyTimes()
-
-			err := uController.SignInHandler(c)
-
-			if err != nil {
-				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.expectedhttpCode, err.(*echo.HTTPError).Code)
+			err := uController.SearchUsersHandler(c)
+			if tc.expectedError != nil {
+				appErr, ok := apperrors.As(err)
+				if assert.True(t, ok, err) {
+					assert.Equal(t, tc.expectedError.Code, appErr.Code)
+					if assert.Len(t, appErr.Fields, 1) {
+						assert.Equal(t, tc.expectedField, appErr.Fields[0].Field)
+					}
+				}
 				return
 			}
-			assert.Equal(t, tc.expectedhttpCode, rec.Code)
-			marshalledResponse, err := json.Marshal(tc.expectedResponse)
-
-			if assert.NoError(t, err) {
-				assert.Equal(t, string(marshalledResponse), strings.TrimSuffix(rec.Body.String(), "\n"))
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
+
+			var response requests.GetUsersResponse
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response)) {
+				assert.Equal(t, int64(3), response.UsersResponse.TotalRows)
+				assert.Contains(t, response.UsersResponse.NextPage, "/users/search?")
+				assert.Contains(t, response.UsersResponse.NextPage, "q=jo+hall")
 			}
-
+			assert.Contains(t, rec.Header().Get("Link"), `rel="next"`)
 		})
 	}
 }
 
-func TestGetOneUserHandler(t *testing.T) {
-
-	user := getTestUser()
-	userId := strconv.Itoa(int(user.ID))
-
+func TestGetDeletedUsersHandler(t *testing.T) {
 	testTable := []struct {
 		scenario      string
-		expectedUser  *models.User
-		inputUserID   string
-		response      *requests.GetOneUserResponse
-		httpCode      int
-		expectedError error
+		query         string
+		expectedSort  string
+		expectedError *apperrors.AppError
 	}{
-		{
-			"get one user by id",
-			user,
-			userId,
-			mappers.MapUserToGetUserResponse(user),
-			http.StatusOK,
-			nil,
-		},
-		{
-			"wrong path params",
-			user,
-			"userID",
-			mappers.MapUserToGetUserResponse(user),
-			http.StatusBadRequest,
-			&apperrors.CanNotBindErr,
-		},
-		{
-			"user not found by id",
-			user,
-			userId,
-			mappers.MapUserToGetUserResponse(user),
-			http.StatusBadRequest,
-			&apperrors.UserNotFoundErr,
-		},
+		{"latest deleted first", "limit=2", "deleted_at desc", nil},
+		{"sorted by username", "limit=2&sort=user_name", "user_name asc", nil},
+		{"unknown sort", "sort=rating", "", &apperrors.ValidatorErr},
 	}
 
-	ctx := context.Background()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			userRepoMock := mocks.NewMockUserRepository(ctrl)
-			uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-			uController := NewUserController(uInteractor)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
 			e := echo.New()
-			req := httptest.NewRequest(http.MethodGet, "/user/:id", nil)
+			req := httptest.NewRequest(http.MethodGet, "/users/deleted?"+tc.query, nil)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
-			c.SetParamNames("id")
-
-			c.SetParamValues(tc.inputUserID)
-			userRepoMock.EXPECT().FindOneUserByID(ctx, tc.expectedUser.ID).Return(tc.expectedUser, tc.expectedError).AnyTimes()
-
-			err := uController.GetOneUserHandler(c)
+			c.Set("user", jwt.NewWithClaims(jwt.SigningMethodHS256, &interactor.AuthClaims{User: getTestUser()}))
+
+			freedName := "JohnHall"
+			if tc.expectedError == nil {
+				userRepoMock.EXPECT().FindDeletedUsers(context.Background(), &models.Pagination{Limit: 2, Page: 1, Sort: tc.expectedSort}).
+					DoAndReturn(func(_ context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+						pagination.TotalRows, pagination.TotalPages = 1, 1
+						return pagination, []*models.User{{ID: 3, DeletedUserName: &freedName}}, nil
+					})
+			}
 
-			if err != nil {
-				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.httpCode, err.(*echo.HTTPError).Code)
+			err := uController.GetDeletedUsersHandler(c)
+			if tc.expectedError != nil {
+				appErr, ok := apperrors.As(err)
+				if assert.True(t, ok, err) {
+					assert.Equal(t, tc.expectedError.Code, appErr.Code)
+				}
 				return
 			}
-			assert.Equal(t, tc.httpCode, rec.Code)
-			marshalledResponse, err := json.Marshal(tc.response)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, rec.Code)
 
-			if assert.NoError(t, err) {
-				assert.Equal(t, string(marshalledResponse), strings.TrimSuffix(rec.Body.String(), "\n"))
+			var response requests.GetUsersResponse
+			if assert.NoError(t, json.NewDecoder(rec.Body).Decode(&response)) {
+				rows, _ := json.Marshal(response.UsersResponse.Rows)
+				assert.Contains(t, string(rows), `"user_name":"JohnHall"`)
 			}
-
 		})
 	}
 }
 
-func TestGetUsersHandler(t *testing.T) {
-	now := time.Now()
-
-	expectedPagination := &models.Pagination{
-		Limit: 5,
-		Page:  1,
-		Sort:  "id desc",
+func TestRestoreUserHandler(t *testing.T) {
+	testTable := []struct {
+		scenario     string
+		inputUserID  string
+		restoreError error
+		httpCode     int
+	}{
+		{"user is restored", "125", nil, http.StatusOK},
+		{"wrong path params", "userID", nil, http.StatusBadRequest},
+		{"user isn't deleted", "126", apperrors.UserNotFoundErr.AppendMessage(errors.New("record not found")), http.StatusBadRequest},
+		{"username is taken", "127", apperrors.UsernameTakenErr.AppendMessage(errors.New("duplicate")), apperrors.UsernameTakenErr.HTTPCode},
 	}
 
-	paginationLimit := strconv.Itoa(int(expectedPagination.Limit))
-	paginationPage := strconv.Itoa(int(expectedPagination.Page))
-
-	usersGenerator := func(pagination *models.Pagination) []*models.User {
-		users := make([]*models.User, pagination.Limit)
-
-		for i := 0; i < len(users); i++ {
-			users[i] = &models.User{}
-			users[i].ID = uint(((pagination.Page - 1) * pagination.Limit) + i + 1)
-			users[i].UserName = fmt.Sprintf("UniqueUser%d", int(users[i].ID))
-			users[i].Role = "admin"
-			users[i].FirstName = fmt.Sprintf("FirstName%d", int(users[i].ID))
-			users[i].LastName = fmt.Sprintf("LastName%d", int(users[i].ID))
-			users[i].CreatedAt = &now
-			users[i].UpdatedAt = &now
-		}
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-		return users
-	}
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
-	fineUsers := usersGenerator(expectedPagination)
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/user/:id/restore", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues(tc.inputUserID)
 
-	tokenGenerator := func(user *models.User) *jwt.Token {
-		claims := &interactor.AuthClaims{
-			User: user,
-			RegisteredClaims: jwt.RegisteredClaims{
-				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * (time.Duration(1)))),
-			},
-		}
+			if id, err := strconv.Atoi(tc.inputUserID); err == nil {
+				if tc.restoreError != nil {
+					userRepoMock.EXPECT().RestoreUser(ctx, uint(id)).Return(nil, tc.restoreError)
+				} else {
+					userRepoMock.EXPECT().RestoreUser(ctx, uint(id)).Return(&models.User{ID: uint(id), UserName: "JaneHall"}, nil)
+				}
+			}
 
-		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+			err := uController.RestoreUserHandler(c)
+			if err != nil {
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
+				return
+			}
+			assert.Equal(t, tc.httpCode, rec.Code)
+		})
 	}
+}
 
+func TestGetUsersHandlerLinkHeader(t *testing.T) {
 	testTable := []struct {
-		scenario           string
-		expectedPagination *models.Pagination
-		expectedUsers      []*models.User
-		httpCode           int
-		expectedError      error
+		scenario      string
+		page          int
+		totalPages    int
+		expectedLinks [][2]string
 	}{
 		{
-			"get users",
-			expectedPagination,
-			fineUsers,
-			http.StatusOK,
-			nil,
+			"middle page",
+			2,
+			3,
+			[][2]string{{"first", "1"}, {"prev", "1"}, {"next", "3"}, {"last", "3"}},
 		},
 		{
-			"wrong query param",
-			&models.Pagination{
-				Limit: 5,
-				Page:  1,
-				Sort:  "id desc",
-			},
-			fineUsers,
-			http.StatusOK,
-			nil,
+			"page past the end",
+			9,
+			2,
+			[][2]string{{"first", "1"}, {"prev", "2"}, {"last", "2"}},
 		},
 		{
-			"bad user role",
-			&models.Pagination{
-				Limit: 5,
-				Page:  1,
-				Sort:  "id desc",
-			},
-			fineUsers,
-			http.StatusForbidden,
-			&apperrors.WrongRoleErr,
+			"no users",
+			1,
+			0,
+			[][2]string{{"first", "1"}, {"last", "1"}},
 		},
 	}
 
-	ctx := context.Background()
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			userRepoMock := mocks.NewMockUserRepository(ctrl)
-			uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-			uController := NewUserController(uInteractor)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.PaginationPolicy{DefaultLimit: 2, MaxLimit: 10})
 
 			e := echo.New()
-			q := make(url.Values)
-
-			if tc.scenario != "wrong query param" {
-				q.Set("limit", paginationLimit)
-				q.Set("page", paginationPage)
-				q.Set("sort", tc.expectedPagination.Sort)
-			}
-
-			userRepoMock.EXPECT().FindUsers(ctx, tc.expectedPagination).Return(tc.expectedPagination, tc.expectedUsers, tc.expectedError).AnyTimes()
-
-			req := httptest.NewRequest(http.MethodGet, "/users?"+q.Encode(), nil)
+			e.Validator = newTestValidator(t)
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/users?role=user&page=%d", tc.page), nil)
 			rec := httptest.NewRecorder()
 			c := e.NewContext(req, rec)
+			c.Set("user", jwt.NewWithClaims(jwt.SigningMethodHS256, &interactor.AuthClaims{User: getTestUser()}))
 
-			if tc.scenario == "bad user role" {
-				tc.expectedUsers[0].Role = "user"
-			}
-
-			c.Set("user", tokenGenerator(tc.expectedUsers[0]))
+			userRepoMock.EXPECT().FindUsers(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ *models.UserFilter, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+					assert.Equal(t, 2, pagination.Limit)
+					pagination.TotalPages = tc.totalPages
+					return pagination, []*models.User{}, nil
+				})
 
-			err := uController.GetUsersHandler(c)
-			if err != nil {
-				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.httpCode, err.(*echo.HTTPError).Code)
+			if !assert.NoError(t, uController.GetUsersHandler(c)) {
 				return
 			}
-			assert.Equal(t, tc.httpCode, rec.Code)
-
-			var pag requests.GetUsersResponse
-			json.NewDecoder(rec.Body).Decode(&pag)
-
-			if assert.NoError(t, err) {
-				for i, v := range pag.UsersResponse.Rows.([]interface{}) {
-					assert.Equal(t, v.(map[string]interface{})["user_name"].(string), tc.expectedUsers[i].UserName)
-					assert.Equal(t, v.(map[string]interface{})["first_name"].(string), tc.expectedUsers[i].FirstName)
-					assert.Equal(t, v.(map[string]interface{})["last_name"].(string), tc.expectedUsers[i].LastName)
+			links := strings.Split(rec.Header().Get("Link"), ", ")
+			if assert.Len(t, links, len(tc.expectedLinks)) {
+				for i, expected := range tc.expectedLinks {
+					assert.True(t, strings.HasPrefix(links[i], "</users?"), links[i])
+					assert.Contains(t, links[i], "role=user")
+					assert.Contains(t, links[i], "page="+expected[1]+"&")
+					assert.True(t, strings.HasSuffix(links[i], `>; rel="`+expected[0]+`"`), links[i])
 				}
 			}
+		})
+	}
+}
+
+func TestGetUsersHandlerCursorLinks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	uInteractor := interactor.NewUserInteractor(userRepoMock, mocks.NewMockRefreshTokenRepository(ctrl), newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1, CursorKey: []byte("cursor key")}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+	uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
+
+	e := echo.New()
+	e.Validator = newTestValidator(t)
+	getUsers := func(target string) *requests.GetUsersResponse {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set("user", jwt.NewWithClaims(jwt.SigningMethodHS256, &interactor.AuthClaims{User: getTestUser()}))
+
+		if err := uController.GetUsersHandler(c); err != nil {
+			t.Fatal(err)
+		}
+		var response requests.GetUsersResponse
+		if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+			t.Fatal(err)
+		}
+		return &response
+	}
 
+	next := &models.Cursor{Sort: "rating desc", Values: []string{"3"}, ID: 7}
+	userRepoMock.EXPECT().FindUsers(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ *models.UserFilter, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+			pagination.TotalRows, pagination.TotalPages = 4, 2
+			pagination.NextKeyset = next
+			return pagination, []*models.User{}, nil
 		})
+	response := getUsers("/users?role=user&sort=rating+desc&limit=2")
+	assert.NotEmpty(t, response.UsersResponse.NextCursor)
+	assert.Contains(t, response.UsersResponse.NextPage, "page=2")
+	link, err := url.Parse(response.UsersResponse.NextCursorPage)
+	if !assert.NoError(t, err) {
+		return
 	}
+	assert.Equal(t, response.UsersResponse.NextCursor, link.Query().Get("cursor"))
+	assert.Equal(t, "user", link.Query().Get("role"))
+	assert.Empty(t, link.Query().Get("page"))
+
+	userRepoMock.EXPECT().FindUsers(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, _ *models.UserFilter, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+			assert.Equal(t, next, pagination.Keyset)
+			assert.False(t, pagination.WithTotal)
+			pagination.PrevKeyset = &models.Cursor{Sort: "rating desc", Values: []string{"1"}, ID: 9, Before: true}
+			return pagination, []*models.User{}, nil
+		})
+	response = getUsers("/users?role=user&limit=2&cursor=" + url.QueryEscape(response.UsersResponse.NextCursor))
+	assert.Equal(t, "rating desc", response.UsersResponse.Sort)
+	assert.Empty(t, response.UsersResponse.NextPage)
+	assert.Empty(t, response.UsersResponse.LastPage)
+	assert.Empty(t, response.UsersResponse.NextCursorPage)
+	assert.Contains(t, response.UsersResponse.PreviousCursorPage, "cursor="+url.QueryEscape(response.UsersResponse.PrevCursor))
 }
 
 func TestDeleteUserHandler(t *testing.T) {
@@ -446,7 +1398,7 @@ func TestDeleteUserHandler(t *testing.T) {
 		{
 			"successfully deleted user",
 			"1234",
-			getTestUser(),
+			expectedUserWithRoleUser,
 			http.StatusOK,
 			nil,
 		},
@@ -460,14 +1412,14 @@ func TestDeleteUserHandler(t *testing.T) {
 		{
 			"id is exsist",
 			"12598",
-			getTestUser(),
+			expectedUserWithRoleUser,
 			http.StatusInternalServerError,
 			&apperrors.CanNotDeleteUserErr,
 		},
 		{
-			"role have lack of rights",
+			"admin can not be deleted",
 			"1234",
-			expectedUserWithRoleUser,
+			getTestUser(),
 			http.StatusForbidden,
 			&apperrors.WrongRoleErr,
 		},
@@ -480,8 +1432,10 @@ func TestDeleteUserHandler(t *testing.T) {
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			userRepoMock := mocks.NewMockUserRepository(ctrl)
-			uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-			uController := NewUserController(uInteractor)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
 			e := echo.New()
 
@@ -494,13 +1448,15 @@ func TestDeleteUserHandler(t *testing.T) {
 			c.Set("user", tokenGenerator())
 
 			id, _ := strconv.Atoi(tc.expectedID)
+			userRepoMock.EXPECT().FindOneUserByID(ctx, uint(id)).Return(tc.expectedUser, nil).AnyTimes()
 			userRepoMock.EXPECT().DeleteUserByID(ctx, id).Return(tc.expectedError).AnyTimes()
+			refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, uint(id)).Return(nil).AnyTimes()
 
 			err := uController.DeleteUserHandler(c)
 
 			if err != nil {
-				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.httpCode, err.(*echo.HTTPError).Code)
+				assert.True(t, apperrors.Is(err, tc.expectedError.(*apperrors.AppError)))
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
 				return
 			}
 			assert.Equal(t, tc.httpCode, rec.Code)
@@ -530,8 +1486,10 @@ func TestDeleteOwnerProfileHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
-	uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-	uController := NewUserController(uInteractor)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+	uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
@@ -542,12 +1500,13 @@ func TestDeleteOwnerProfileHandler(t *testing.T) {
 			c := e.NewContext(req, rec)
 
 			userRepoMock.EXPECT().DeleteOwnUser(ctx, tc.expectedID).Return(tc.expectedError)
+			refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, uint(tc.expectedID)).Return(nil).AnyTimes()
 			c.Set("user", tokenGenerator())
 			err := uController.DeleteOwnerProfileHandler(c)
 
 			if err != nil {
 				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.httpCode, err.(*echo.HTTPError).Code)
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
 				return
 			}
 			assert.Equal(t, tc.httpCode, rec.Code)
@@ -574,7 +1533,6 @@ func TestUpdateUserHandler(t *testing.T) {
 				ID:        0,
 				UserName:  "JohnHall",
 				FirstName: "John",
-				Role:      "user",
 				LastName:  "Hall",
 			},
 			http.StatusOK,
@@ -599,12 +1557,11 @@ func TestUpdateUserHandler(t *testing.T) {
 		{
 			"user has admin status",
 			"1234",
-			`{"user_name": "JohnHall", "role": "admin", "first_name": "John", "last_name": "Hall"}`,
+			`{"user_name": "AdminJohn", "role": "admin", "first_name": "John", "last_name": "Hall"}`,
 			&models.User{
 				ID:        0,
-				UserName:  "JohnHall",
+				UserName:  "AdminJohn",
 				FirstName: "John",
-				Role:      "admin",
 				LastName:  "Hall",
 			},
 			http.StatusInternalServerError,
@@ -617,8 +1574,10 @@ func TestUpdateUserHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
-	uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-	uController := NewUserController(uInteractor)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+	uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
@@ -634,13 +1593,14 @@ func TestUpdateUserHandler(t *testing.T) {
 
 			id, _ := strconv.Atoi(tc.expectedID)
 
+			userRepoMock.EXPECT().FindOneUserByID(ctx, uint(id)).Return(&models.User{ID: uint(id), UserName: "JohnHall"}, nil).AnyTimes()
 			userRepoMock.EXPECT().UpdateUserByID(ctx, id, tc.expectedUser).Return(tc.expectedUser, tc.expectedError).AnyTimes()
 
 			err := uController.UpdateUserHandler(c)
 
 			if err != nil {
 				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.httpCode, err.(*echo.HTTPError).Code)
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
 				return
 			}
 			assert.Equal(t, tc.httpCode, rec.Code)
@@ -654,6 +1614,7 @@ func TestUpdateOwnerProfileHandler(t *testing.T) {
 	inputUser := getTestUser()
 	inputUser.ID = 0
 	inputUser.Rating = 0
+	inputUser.Role = ""
 	testTable := []struct {
 		scenario string
 
@@ -687,8 +1648,10 @@ func TestUpdateOwnerProfileHandler(t *testing.T) {
 	defer ctrl.Finish()
 
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
-	uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-	uController := NewUserController(uInteractor)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+		interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+	uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
@@ -700,13 +1663,14 @@ func TestUpdateOwnerProfileHandler(t *testing.T) {
 			c := e.NewContext(req, rec)
 			c.Set("user", tokenGenerator())
 
-			userRepoMock.EXPECT().UpdateOwnUser(ctx, int(tc.expectedUser.ID), tc.inputUser).Return(tc.expectedUser, tc.expectedError).AnyTimes()
+			userRepoMock.EXPECT().FindOneUserByID(ctx, tc.expectedUser.ID).Return(getTestUser(), nil).AnyTimes()
+			userRepoMock.EXPECT().UpdateOwnUser(ctx, int(tc.expectedUser.ID), hashedUser(tc.inputUser)).Return(tc.expectedUser, tc.expectedError).AnyTimes()
 
 			err := uController.UpdateOwnerProfileHandler(c)
 
 			if err != nil {
 				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.httpCode, err.(*echo.HTTPError).Code)
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
 				return
 			}
 			assert.Equal(t, tc.httpCode, rec.Code)
@@ -750,7 +1714,7 @@ func TestRateHandler(t *testing.T) {
 		},
 		{
 			"rate himself",
-			`{"rate": true}`,
+			`{"rate": "up"}`,
 			getTestUser(),
 			args{
 				context.Background(),
@@ -761,6 +1725,19 @@ func TestRateHandler(t *testing.T) {
 			http.StatusForbidden,
 			&apperrors.CanNotRateYorself,
 		},
+		{
+			"rate himself with another letter case",
+			`{"rate": "up"}`,
+			getTestUser(),
+			args{
+				context.Background(),
+				124,
+				"johnHALL",
+				"up",
+			},
+			http.StatusForbidden,
+			&apperrors.CanNotRateYorself,
+		},
 		{
 			"wrong bind params",
 			`{"rate": ", "last_name": "Hall", "passwordy12difficult()Password"}`,
@@ -792,8 +1769,10 @@ func TestRateHandler(t *testing.T) {
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			userRepoMock := mocks.NewMockUserRepository(ctrl)
-			uInteractor := interactor.NewUserInteractor(userRepoMock, "hash_salt", []byte("signing_key"), 1)
-			uController := NewUserController(uInteractor)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := interactor.NewUserInteractor(userRepoMock, refreshTokenRepoMock, newTestAuditRepository(ctrl), newTestLoginAttemptRepository(ctrl), newTestTransactor(ctrl), repository.NewInMemoryRevocationStore(), newTestPasswordHasher(t), permissions.DefaultRoles(),
+				interactor.TokenOptions{KeySet: newTestKeySet(t), TokenTTL: 1, RefreshTokenTTL: 1}, interactor.LockoutOptions{}, interactor.DeletionOptions{})
+			uController := NewUserController(uInteractor, mappers.DefaultPaginationPolicy)
 
 			e := echo.New()
 
@@ -806,14 +1785,19 @@ func TestRateHandler(t *testing.T) {
 			c.SetParamValues(tc.args.username)
 			c.Set("user", tokenGenerator())
 
+			targetID := uint(125)
+			if strings.EqualFold(tc.args.username, getTestUser().UserName) {
+				targetID = getTestUser().ID
+			}
+			userRepoMock.EXPECT().FindOneUserByUserName(ctx, tc.args.username).Return(&models.User{ID: targetID, UserName: tc.args.username}, nil).AnyTimes()
 			userRepoMock.EXPECT().RateUserByUsername(ctx, tc.args.myID, tc.args.username, tc.args.expectedRatedUpDown).Return(tc.expectedUser, tc.expectedError).
 				AnyTimes()
 
 			err := uController.RateUserHandler(c)
 
 			if err != nil {
-				apperrors.Is(err, tc.expectedError.(*apperrors.AppError))
-				assert.Equal(t, tc.httpCode, err.(*echo.HTTPError).Code)
+				assert.True(t, apperrors.Is(err, tc.expectedError.(*apperrors.AppError)))
+				assert.Equal(t, tc.httpCode, err.(*apperrors.AppError).HTTPCode)
 				return
 			}
 			assert.Equal(t, tc.httpCode, rec.Code)
@@ -822,11 +1806,92 @@ func TestRateHandler(t *testing.T) {
 	}
 }
 
-func hashingUserFunc(password string) string {
-	pwd := sha1.New()
-	pwd.Write([]byte(password))
-	pwd.Write([]byte("hash_salt"))
-	return fmt.Sprintf("%x", pwd.Sum(nil))
+// newTestTransactor runs the transaction function right away.
+func newTestTransactor(ctrl *gomock.Controller) repository.Transactor {
+	transactorMock := mocks.NewMockTransactor(ctrl)
+	transactorMock.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		}).AnyTimes()
+	return transactorMock
+}
+
+func newTestAuditRepository(ctrl *gomock.Controller) repository.AuditRepository {
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	auditRepoMock.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return auditRepoMock
+}
+
+func newTestLoginAttemptRepository(ctrl *gomock.Controller) repository.LoginAttemptRepository {
+	loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
+	loginAttemptRepoMock.EXPECT().RecordSuccessfulLogin(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	loginAttemptRepoMock.EXPECT().RecordFailedLogin(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, userID uint) (*models.LoginAttempts, error) {
+			return &models.LoginAttempts{UserID: userID, FailedAttempts: 1, ConsecutiveFailures: 1}, nil
+		}).AnyTimes()
+	return loginAttemptRepoMock
+}
+
+func newTestPasswordHasher(t *testing.T) hasher.PasswordHasher {
+	passwordHasher, err := hasher.NewPasswordHasher(hasher.BcryptAlgorithm, "hash_salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return passwordHasher
+}
+
+func newTestValidator(t *testing.T) *v.CustomValidator {
+	validator, err := v.NewCustomValidator(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return validator
+}
+
+func newTestKeySet(t *testing.T) *signer.KeySet {
+	keySet, err := signer.NewKeySet(signer.NewHMACKey("test", []byte("signing_key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keySet
+}
+
+func hashingUserFunc(t *testing.T, password string) string {
+	hash, err := newTestPasswordHasher(t).Hash(password)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// hashedUserMatcher matches a user whose password was replaced by any valid
+// hash of the expected user's plain password.
+type hashedUserMatcher struct {
+	user *models.User
+}
+
+func hashedUser(user *models.User) gomock.Matcher {
+	return hashedUserMatcher{user}
+}
+
+func (m hashedUserMatcher) Matches(x interface{}) bool {
+	user, ok := x.(*models.User)
+	if !ok {
+		return false
+	}
+
+	passwordHasher, _ := hasher.NewPasswordHasher(hasher.BcryptAlgorithm, "hash_salt")
+	if ok, err := passwordHasher.Verify(m.user.Password, user.Password); err != nil || !ok {
+		return false
+	}
+
+	expected := *m.user
+	expected.Password = user.Password
+	return reflect.DeepEqual(&expected, user)
+}
+
+func (m hashedUserMatcher) String() string {
+	return fmt.Sprintf("is %v with a hashed password", m.user)
 }
 
 func getTestUser() *models.User {
@@ -846,6 +1911,8 @@ func tokenGenerator() *jwt.Token {
 	claims := &interactor.AuthClaims{
 		User: getTestUser(),
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * (time.Duration(1)))),
 		},
 	}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"math"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -destination=../../../gen/mocks/mock_audit_repository.go -package=mocks . AuditRepository
+
+type AuditRepository interface {
+	CreateAuditEvent(ctx context.Context, event *models.AuditEvent) error
+	FindAuditEvents(ctx context.Context, filter *models.AuditFilter, pagination *models.Pagination) (*models.Pagination, []*models.AuditEvent, error)
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db}
+}
+
+func (ar *auditRepository) CreateAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	if err := conn(ctx, ar.db).Create(event).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ar *auditRepository) FindAuditEvents(ctx context.Context, filter *models.AuditFilter, pagination *models.Pagination) (*models.Pagination, []*models.AuditEvent, error) {
+	if err := pagination.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := ar.filtered(ctx, filter).Count(&pagination.TotalRows).Error; err != nil {
+		return nil, nil, err
+	}
+	pagination.TotalPages = int(math.Ceil(float64(pagination.TotalRows) / float64(pagination.Limit)))
+
+	events := []*models.AuditEvent{}
+	if pagination.Page > pagination.TotalPages {
+		return pagination, events, nil
+	}
+	if err := ar.filtered(ctx, filter).Limit(pagination.Limit).Offset(pagination.Offset()).Order(pagination.Sort).Find(&events).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(events) > 0 {
+		pagination.FromRow = pagination.Offset() + 1
+		pagination.ToRow = pagination.Offset() + len(events)
+	}
+	return pagination, events, nil
+}
+
+func (ar *auditRepository) filtered(ctx context.Context, filter *models.AuditFilter) *gorm.DB {
+	query := conn(ctx, ar.db).Model(&models.AuditEvent{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	return query
+}
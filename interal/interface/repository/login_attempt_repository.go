@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+//go:generate mockgen -destination=../../../gen/mocks/mock_login_attempt_repository.go -package=mocks . LoginAttemptRepository
+
+type LoginAttemptRepository interface {
+	// FindLoginAttempts returns zero attempts for a user who never signed in.
+	FindLoginAttempts(ctx context.Context, userID uint) (*models.LoginAttempts, error)
+	// RecordFailedLogin counts the failure and returns the updated attempts.
+	RecordFailedLogin(ctx context.Context, userID uint) (*models.LoginAttempts, error)
+	RecordSuccessfulLogin(ctx context.Context, userID uint) error
+	LockUser(ctx context.Context, userID uint, until time.Time) error
+	UnlockUser(ctx context.Context, userID uint) error
+}
+
+type loginAttemptRepository struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptRepository(db *gorm.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db}
+}
+
+func (lr *loginAttemptRepository) FindLoginAttempts(ctx context.Context, userID uint) (*models.LoginAttempts, error) {
+	attempts := models.LoginAttempts{}
+	err := conn(ctx, lr.db).Where("user_id = ?", userID).First(&attempts).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.LoginAttempts{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &attempts, nil
+}
+
+// RecordFailedLogin increments the counters in the database, so concurrent
+// failures are all counted.
+func (lr *loginAttemptRepository) RecordFailedLogin(ctx context.Context, userID uint) (*models.LoginAttempts, error) {
+	now := time.Now()
+	err := conn(ctx, lr.db).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failed_attempts":      gorm.Expr("failed_attempts + 1"),
+			"consecutive_failures": gorm.Expr("consecutive_failures + 1"),
+			"last_failed_at":       now,
+			"updated_at":           now,
+		}),
+	}).Create(&models.LoginAttempts{UserID: userID, FailedAttempts: 1, ConsecutiveFailures: 1, LastFailedAt: &now}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return lr.FindLoginAttempts(ctx, userID)
+}
+
+func (lr *loginAttemptRepository) RecordSuccessfulLogin(ctx context.Context, userID uint) error {
+	now := time.Now()
+	return conn(ctx, lr.db).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"successful_attempts":  gorm.Expr("successful_attempts + 1"),
+			"consecutive_failures": 0,
+			"locked_until":         nil,
+			"last_succeeded_at":    now,
+			"updated_at":           now,
+		}),
+	}).Create(&models.LoginAttempts{UserID: userID, SuccessfulAttempts: 1, LastSucceededAt: &now}).Error
+}
+
+func (lr *loginAttemptRepository) LockUser(ctx context.Context, userID uint, until time.Time) error {
+	if err := conn(ctx, lr.db).Model(&models.LoginAttempts{}).Where("user_id = ?", userID).
+		Update("locked_until", until).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (lr *loginAttemptRepository) UnlockUser(ctx context.Context, userID uint) error {
+	if err := conn(ctx, lr.db).Model(&models.LoginAttempts{}).Where("user_id = ?", userID).
+		Updates(map[string]interface{}{"consecutive_failures": 0, "locked_until": nil}).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	InMemoryRateLimitStore = "memory"
+)
+
+//go:generate mockgen -destination=../../../gen/mocks/mock_rate_limit_store.go -package=mocks . RateLimitStore
+
+// RateLimitStore keeps token buckets. The whole take-a-token step is one
+// store call, so a shared store (e.g. Redis with a script) can do it atomically.
+type RateLimitStore interface {
+	// TakeToken takes a token from the bucket of key which holds up to
+	// capacity tokens and gets a new one every refillEvery. When the bucket is
+	// empty it returns how long to wait for the next token.
+	TakeToken(ctx context.Context, key string, capacity int, refillEvery time.Duration) (time.Duration, error)
+	ResetBucket(ctx context.Context, key string) error
+}
+
+type bucket struct {
+	tokens      float64
+	capacity    int
+	refillEvery time.Duration
+	refilledAt  time.Time
+}
+
+type inMemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	sweptAt   time.Time
+	sweepTime time.Duration
+}
+
+// NewInMemoryRateLimitStore is suitable for a single instance only.
+func NewInMemoryRateLimitStore() RateLimitStore {
+	return &inMemoryRateLimitStore{
+		buckets:   map[string]*bucket{},
+		sweptAt:   time.Now(),
+		sweepTime: time.Minute,
+	}
+}
+
+func (rs *inMemoryRateLimitStore) TakeToken(ctx context.Context, key string, capacity int, refillEvery time.Duration) (time.Duration, error) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	rs.sweep(now)
+
+	b, ok := rs.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(capacity), capacity: capacity, refillEvery: refillEvery, refilledAt: now}
+		rs.buckets[key] = b
+	}
+
+	b.tokens += float64(now.Sub(b.refilledAt)) / float64(refillEvery)
+	if b.tokens > float64(capacity) {
+		b.tokens = float64(capacity)
+	}
+	b.refilledAt = now
+
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) * float64(refillEvery)), nil
+	}
+	b.tokens--
+	return 0, nil
+}
+
+func (rs *inMemoryRateLimitStore) ResetBucket(ctx context.Context, key string) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	delete(rs.buckets, key)
+	return nil
+}
+
+// sweep drops buckets which are full again, they are the same as absent ones.
+func (rs *inMemoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(rs.sweptAt) < rs.sweepTime {
+		return
+	}
+	rs.sweptAt = now
+
+	for key, b := range rs.buckets {
+		if b.tokens+float64(now.Sub(b.refilledAt))/float64(b.refillEvery) >= float64(b.capacity) {
+			delete(rs.buckets, key)
+		}
+	}
+}
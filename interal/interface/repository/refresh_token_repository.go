@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"gorm.io/gorm"
+)
+
+//go:generate mockgen -destination=../../../gen/mocks/mock_refresh_token_repository.go -package=mocks . RefreshTokenRepository
+
+type RefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, oldTokenID uint, newToken *models.RefreshToken) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID uint) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db}
+}
+
+func (rr *refreshTokenRepository) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if err := conn(ctx, rr.db).Create(token).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *refreshTokenRepository) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	token := models.RefreshToken{}
+	if err := conn(ctx, rr.db).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// RotateRefreshToken revokes the old token and stores the new one in a single
+// transaction. The old token is revoked only if it is still active, so two
+// concurrent refreshes with the same token can't both succeed.
+func (rr *refreshTokenRepository) RotateRefreshToken(ctx context.Context, oldTokenID uint, newToken *models.RefreshToken) error {
+	return conn(ctx, rr.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", oldTokenID).
+			UpdateColumn("revoked_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return &apperrors.RefreshTokenReuseErr
+		}
+
+		return tx.Create(newToken).Error
+	})
+}
+
+func (rr *refreshTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	if err := conn(ctx, rr.db).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		UpdateColumn("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (rr *refreshTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uint) error {
+	if err := conn(ctx, rr.db).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		UpdateColumn("revoked_at", time.Now()).Error; err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	InMemoryRevocationStore = "memory"
+	GormRevocationStore     = "database"
+)
+
+//go:generate mockgen -destination=../../../gen/mocks/mock_revocation_store.go -package=mocks . RevocationStore
+
+// RevocationStore keeps access tokens which must be rejected before their
+// expiration: single tokens by their jti and all tokens of a user issued
+// up to some moment. The issued at claim has whole seconds, so the moment is
+// truncated to seconds and the tokens issued in the second of the revocation
+// are rejected too.
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, jti string, userID uint, expiresAt time.Time) error
+	RevokeUserTokens(ctx context.Context, userID uint, issuedBefore time.Time) error
+	IsRevoked(ctx context.Context, jti string, userID uint, issuedAt time.Time) (bool, error)
+}
+
+type gormRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewGormRevocationStore(db *gorm.DB) RevocationStore {
+	return &gormRevocationStore{db}
+}
+
+func (rs *gormRevocationStore) RevokeToken(ctx context.Context, jti string, userID uint, expiresAt time.Time) error {
+	if err := conn(ctx, rs.db).Where("expires_at < ?", time.Now()).Delete(&models.RevokedToken{}).Error; err != nil {
+		return err
+	}
+
+	return conn(ctx, rs.db).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}).Error
+}
+
+func (rs *gormRevocationStore) RevokeUserTokens(ctx context.Context, userID uint, issuedBefore time.Time) error {
+	return conn(ctx, rs.db).Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&models.UserSessionRevocation{UserID: userID, RevokedBefore: issuedBefore.Truncate(time.Second)}).Error
+}
+
+func (rs *gormRevocationStore) IsRevoked(ctx context.Context, jti string, userID uint, issuedAt time.Time) (bool, error) {
+	var count int64
+	if err := conn(ctx, rs.db).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	revocation := models.UserSessionRevocation{}
+	err := conn(ctx, rs.db).Where("user_id = ?", userID).First(&revocation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !issuedAt.After(revocation.RevokedBefore), nil
+}
+
+type inMemoryRevocationStore struct {
+	mu            sync.RWMutex
+	tokens        map[string]time.Time
+	revokedBefore map[uint]time.Time
+}
+
+// NewInMemoryRevocationStore is suitable for a single instance only, its
+// revocations are lost on restart.
+func NewInMemoryRevocationStore() RevocationStore {
+	return &inMemoryRevocationStore{
+		tokens:        map[string]time.Time{},
+		revokedBefore: map[uint]time.Time{},
+	}
+}
+
+func (rs *inMemoryRevocationStore) RevokeToken(ctx context.Context, jti string, userID uint, expiresAt time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	now := time.Now()
+	for revokedJTI, revokedExpiresAt := range rs.tokens {
+		if revokedExpiresAt.Before(now) {
+			delete(rs.tokens, revokedJTI)
+		}
+	}
+
+	rs.tokens[jti] = expiresAt
+	return nil
+}
+
+func (rs *inMemoryRevocationStore) RevokeUserTokens(ctx context.Context, userID uint, issuedBefore time.Time) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.revokedBefore[userID] = issuedBefore.Truncate(time.Second)
+	return nil
+}
+
+func (rs *inMemoryRevocationStore) IsRevoked(ctx context.Context, jti string, userID uint, issuedAt time.Time) (bool, error) {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	if _, ok := rs.tokens[jti]; ok {
+		return true, nil
+	}
+
+	revokedBefore, ok := rs.revokedBefore[userID]
+	return ok && !issuedAt.After(revokedBefore), nil
+}
@@ -0,0 +1,45 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokeUserTokens(t *testing.T) {
+	stores := map[string]repository.RevocationStore{
+		"memory":   repository.NewInMemoryRevocationStore(),
+		"database": repository.NewGormRevocationStore(newTestDB(t)),
+	}
+
+	for name, store := range stores {
+		store := store
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			revokedAt := time.Date(2026, 10, 17, 12, 0, 0, 700_000_000, time.UTC)
+			if err := store.RevokeUserTokens(ctx, 121, revokedAt); err != nil {
+				t.Fatal(err)
+			}
+
+			// the issued at claim has whole seconds, the second of the revocation is revoked too
+			for issuedAt, expected := range map[time.Time]bool{
+				revokedAt.Truncate(time.Second).Add(-time.Second): true,
+				revokedAt.Truncate(time.Second):                   true,
+				revokedAt.Truncate(time.Second).Add(time.Second):  false,
+			} {
+				revoked, err := store.IsRevoked(ctx, "jti", 121, issuedAt)
+				if assert.NoError(t, err) {
+					assert.Equal(t, expected, revoked, "issued at %s", issuedAt)
+				}
+			}
+
+			revoked, err := store.IsRevoked(ctx, "jti", 122, revokedAt.Add(-time.Hour))
+			if assert.NoError(t, err) {
+				assert.False(t, revoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	mysqlDuplicateEntry          = 1062
+	postgresUniqueViolation      = "23505"
+	sqliteUniqueViolationMessage = "UNIQUE constraint failed"
+)
+
+// IsUniqueViolation reports whether err is a unique constraint violation of
+// MySQL, PostgreSQL or SQLite. PostgreSQL and SQLite errors are matched by
+// their SQLSTATE method and message, so their drivers don't have to be
+// linked in.
+func IsUniqueViolation(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDuplicateEntry
+	}
+
+	// pgconn.PgError and pq.Error
+	var postgresErr interface{ SQLState() string }
+	if errors.As(err, &postgresErr) {
+		return postgresErr.SQLState() == postgresUniqueViolation
+	}
+
+	// SQLite drivers share only the message
+	return err != nil && strings.Contains(err.Error(), sqliteUniqueViolationMessage)
+}
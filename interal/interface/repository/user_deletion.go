@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"gorm.io/gorm"
+)
+
+// deleted reads the soft-deleted users only.
+func (ur *userRepository) deleted(ctx context.Context) *gorm.DB {
+	return conn(ctx, ur.db).Unscoped().Model(&models.User{}).Where("deleted_at IS NOT NULL")
+}
+
+// FindDeletedUsers sorts by the DeletedUserSortFields of pagination.Sort
+// only.
+func (ur *userRepository) FindDeletedUsers(ctx context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+	if err := pagination.Validate(); err != nil {
+		return nil, nil, err
+	}
+	keys, err := models.ParseSort(pagination.Sort, models.DeletedUserSortFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := ur.deleted(ctx).Count(&pagination.TotalRows).Error; err != nil {
+		return nil, nil, err
+	}
+	pagination.TotalPages = int(math.Ceil(float64(pagination.TotalRows) / float64(pagination.Limit)))
+	users := []*models.User{}
+	if pagination.Page > pagination.TotalPages {
+		return pagination, users, nil
+	}
+
+	query := ur.deleted(ctx).Limit(pagination.Limit).Offset(pagination.Offset())
+	for _, column := range userOrder(keys) {
+		query = query.Order(column)
+	}
+	if err := query.Find(&users).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(users) > 0 {
+		pagination.FromRow = pagination.Offset() + 1
+		pagination.ToRow = pagination.Offset() + len(users)
+	}
+	return pagination, users, nil
+}
+
+// ReleaseUserName frees the username of a deleted user for new users. It is
+// kept in deleted_user_name, user_name becomes NULL which the unique indexes
+// allow any number of times.
+func (ur *userRepository) ReleaseUserName(ctx context.Context, id uint) error {
+	return ur.deleted(ctx).Where("id = ? AND user_name IS NOT NULL", id).
+		UpdateColumns(map[string]interface{}{"deleted_user_name": gorm.Expr("user_name"), "user_name": nil}).Error
+}
+
+// RestoreUser undeletes a user. A freed username is taken back, it is
+// UsernameTakenErr when somebody has taken it meanwhile. It returns
+// UserNotFoundErr when there is no such deleted user.
+func (ur *userRepository) RestoreUser(ctx context.Context, id uint) (*models.User, error) {
+	user, err := ur.findDeletedUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := map[string]interface{}{"deleted_at": nil}
+	if user.DeletedUserName != nil {
+		columns["user_name"] = *user.DeletedUserName
+		columns["deleted_user_name"] = nil
+	}
+	if err := ur.deleted(ctx).Where("id = ?", id).UpdateColumns(columns).Error; err != nil {
+		if IsUniqueViolation(err) {
+			return nil, apperrors.UsernameTakenErr.WithDetail("field", "user_name").AppendMessage(err)
+		}
+		return nil, err
+	}
+	return ur.FindOneUserByID(ctx, id)
+}
+
+// PurgeUser deletes a deleted user for good, with the ratings given to and by
+// the user. The ratings the user gave are taken back from the rated users.
+// It returns UserNotFoundErr when there is no such deleted user.
+func (ur *userRepository) PurgeUser(ctx context.Context, id uint) error {
+	if _, err := ur.findDeletedUser(ctx, id); err != nil {
+		return err
+	}
+
+	var given []models.RatedByUser
+	if err := conn(ctx, ur.db).Where("rated_by_user_id = ?", id).Find(&given).Error; err != nil {
+		return err
+	}
+	for _, rating := range given {
+		var change int
+		switch rating.Rate {
+		case "up":
+			change = 1
+		case "down":
+			change = -1
+		default:
+			continue
+		}
+		if err := conn(ctx, ur.db).Unscoped().Model(&models.User{}).Where("id = ?", rating.UserID).
+			UpdateColumn("rating", gorm.Expr("rating - ?", change)).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := conn(ctx, ur.db).Unscoped().Where("user_id = ? OR rated_by_user_id = ?", id, id).Delete(&models.RatedByUser{}).Error; err != nil {
+		return err
+	}
+	return conn(ctx, ur.db).Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(&models.User{}).Error
+}
+
+// FindUserIDsDeletedBefore returns up to limit users deleted before the
+// time, the earliest first.
+func (ur *userRepository) FindUserIDsDeletedBefore(ctx context.Context, before time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := ur.deleted(ctx).Where("deleted_at < ?", before).Order("deleted_at").Order("id").Limit(limit).Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (ur *userRepository) findDeletedUser(ctx context.Context, id uint) (*models.User, error) {
+	user := &models.User{}
+	if err := ur.deleted(ctx).Where("id = ?", id).First(user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.UserNotFoundErr.AppendMessage(err)
+		}
+		return nil, err
+	}
+	return user, nil
+}
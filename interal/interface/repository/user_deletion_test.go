@@ -0,0 +1,136 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDeletedUsers(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+
+	ids := map[string]uint{}
+	for _, name := range []string{"alice", "bobby", "carol", "david"} {
+		user, err := userRepo.CreateUser(ctx, &models.User{UserName: name, FirstName: name, LastName: name, Role: "user"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[name] = user.ID
+	}
+	// bobby rated alice up and carol down, alice rated bobby up
+	for _, rating := range []models.RatedByUser{
+		{UserID: ids["alice"], RatedByUserID: ids["bobby"], Rate: "up"},
+		{UserID: ids["carol"], RatedByUserID: ids["bobby"], Rate: "down"},
+		{UserID: ids["bobby"], RatedByUserID: ids["alice"], Rate: "up"},
+	} {
+		rating := rating
+		if err := db.Create(&rating).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Model(&models.User{}).Where("id IN ?", []uint{ids["alice"], ids["bobby"]}).Update("rating", 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", ids["carol"]).Update("rating", -1).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"bobby", "carol"} {
+		if err := userRepo.DeleteUserByID(ctx, int(ids[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Unscoped().Model(&models.User{}).Where("id = ?", ids["bobby"]).Update("deleted_at", time.Now().AddDate(0, 0, -40)).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("lists the deleted users only", func(t *testing.T) {
+		pagination, users, err := userRepo.FindDeletedUsers(ctx, &models.Pagination{Limit: 10, Page: 1, Sort: "deleted_at desc"})
+		if assert.NoError(t, err) && assert.Len(t, users, 2) {
+			assert.Equal(t, "carol", users[0].UserName)
+			assert.Equal(t, "bobby", users[1].UserName)
+			assert.Equal(t, int64(2), pagination.TotalRows)
+		}
+	})
+
+	t.Run("rejects an unknown sort", func(t *testing.T) {
+		_, _, err := userRepo.FindDeletedUsers(ctx, &models.Pagination{Limit: 10, Page: 1, Sort: "rating"})
+		assert.Error(t, err)
+	})
+
+	t.Run("finds the users deleted before a time", func(t *testing.T) {
+		deletedIDs, err := userRepo.FindUserIDsDeletedBefore(ctx, time.Now().AddDate(0, 0, -30), 10)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []uint{ids["bobby"]}, deletedIDs)
+		}
+	})
+
+	t.Run("a released username can be taken and restored only when free", func(t *testing.T) {
+		if err := userRepo.ReleaseUserName(ctx, ids["carol"]); !assert.NoError(t, err) {
+			return
+		}
+		_, users, err := userRepo.FindDeletedUsers(ctx, &models.Pagination{Limit: 10, Page: 1, Sort: "id"})
+		if assert.NoError(t, err) && assert.Len(t, users, 2) {
+			assert.Equal(t, "", users[1].UserName)
+			assert.Equal(t, "carol", users[1].DisplayName())
+		}
+
+		newCarol, err := userRepo.CreateUser(ctx, &models.User{UserName: "carol", FirstName: "carol", LastName: "carol", Role: "user"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = userRepo.RestoreUser(ctx, ids["carol"])
+		assert.True(t, errors.Is(err, &apperrors.UsernameTakenErr), err)
+
+		if err := db.Unscoped().Delete(newCarol).Error; !assert.NoError(t, err) {
+			return
+		}
+		restored, err := userRepo.RestoreUser(ctx, ids["carol"])
+		if assert.NoError(t, err) {
+			assert.Equal(t, "carol", restored.UserName)
+			assert.Nil(t, restored.DeletedUserName)
+			assert.False(t, restored.DeletedAt.Valid)
+		}
+	})
+
+	t.Run("restores only deleted users", func(t *testing.T) {
+		_, err := userRepo.RestoreUser(ctx, ids["alice"])
+		assert.True(t, errors.Is(err, &apperrors.UserNotFoundErr), err)
+	})
+
+	t.Run("purges a user with the ratings", func(t *testing.T) {
+		if err := userRepo.PurgeUser(ctx, ids["bobby"]); !assert.NoError(t, err) {
+			return
+		}
+
+		err := db.Unscoped().First(&models.User{}, ids["bobby"]).Error
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound), err)
+		var ratings int64
+		if assert.NoError(t, db.Unscoped().Model(&models.RatedByUser{}).Count(&ratings).Error) {
+			assert.Equal(t, int64(0), ratings)
+		}
+
+		alice, err := userRepo.FindOneUserByID(ctx, ids["alice"])
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, alice.Rating)
+		}
+		carol, err := userRepo.FindOneUserByID(ctx, ids["carol"])
+		if assert.NoError(t, err) {
+			assert.Equal(t, 0, carol.Rating)
+		}
+	})
+
+	t.Run("purges only deleted users", func(t *testing.T) {
+		err := userRepo.PurgeUser(ctx, ids["alice"])
+		assert.True(t, errors.Is(err, &apperrors.UserNotFoundErr), err)
+	})
+}
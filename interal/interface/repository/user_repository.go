@@ -3,26 +3,49 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 //go:generate mockgen -destination=../../../gen/mocks/mock_user_repository.go -package=mocks . UserRepository
 
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *models.User) (*models.User, error)
-	FindUsers(ctx context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error)
+	FindUsers(ctx context.Context, filter *models.UserFilter, pagination *models.Pagination) (*models.Pagination, []*models.User, error)
+	SearchUsers(ctx context.Context, query string, pagination *models.Pagination) (*models.Pagination, []*models.User, error)
 	FindOneUserByID(ctx context.Context, id uint) (*models.User, error)
-	FindOneUserByUserNameAndPassword(ctx context.Context, username, password string) (*models.User, error)
+	FindOneUserByUserName(ctx context.Context, username string) (*models.User, error)
 	DeleteUserByID(ctx context.Context, id int) error
 	DeleteOwnUser(ctx context.Context, id int) error
 	UpdateUserByID(ctx context.Context, id int, user *models.User) (*models.User, error)
 	UpdateOwnUser(ctx context.Context, id int, user *models.User) (*models.User, error)
+	UpdateUserPassword(ctx context.Context, id uint, passwordHash string) error
+	UpdateUserRole(ctx context.Context, id uint, role string) error
+	CountUsersByRole(ctx context.Context, role string) (int64, error)
 	RateUserByUsername(ctx context.Context, userWhoRateID uint, username, rate string) (*models.User, error)
+	FindDeletedUsers(ctx context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error)
+	ReleaseUserName(ctx context.Context, id uint) error
+	RestoreUser(ctx context.Context, id uint) (*models.User, error)
+	PurgeUser(ctx context.Context, id uint) error
+	FindUserIDsDeletedBefore(ctx context.Context, before time.Time, limit int) ([]uint, error)
+}
+
+// userNameIs matches usernames ignoring case on every database. MySQL does it
+// by its collation alone, and LOWER there would keep the unique index of
+// user_name from being used.
+func userNameIs(dialect string) string {
+	if dialect == "mysql" {
+		return "user_name = ?"
+	}
+	return "LOWER(user_name) = LOWER(?)"
 }
 
 type userRepository struct {
@@ -33,56 +56,276 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db}
 }
 
+// CreateUser returns UsernameTakenErr when the username already exists.
 func (ur *userRepository) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
-	if err := ur.db.WithContext(ctx).Create(&user).Error; err != nil {
+	if err := conn(ctx, ur.db).Create(&user).Error; err != nil {
+		if IsUniqueViolation(err) {
+			return nil, apperrors.UsernameTakenErr.WithDetail("field", "user_name").AppendMessage(err)
+		}
 		return nil, err
 	}
 	return user, nil
 }
 
-func (ur *userRepository) FindUsers(ctx context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+// FindUsers sorts by the UserSortFields of pagination.Sort only, the
+// columns are quoted rather than passed to the database as they are. A page
+// past the end is empty and isn't read. With a pagination.Keyset it reads the
+// page after (or before) that position, and counts the users only when
+// pagination.WithTotal is set.
+func (ur *userRepository) FindUsers(ctx context.Context, filter *models.UserFilter, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+	if err := pagination.Validate(); err != nil {
+		return nil, nil, err
+	}
+	keys, err := models.ParseSort(pagination.Sort, models.UserSortFields)
+	if err != nil {
+		return nil, nil, err
+	}
+	if pagination.Keyset != nil {
+		return ur.findUsersByKeyset(ctx, filter, pagination, keys)
+	}
 
-	offset := (pagination.Page - 1) * pagination.Limit
+	if err := ur.count(ctx, filter, pagination); err != nil {
+		return nil, nil, err
+	}
 	users := []*models.User{}
-	if err := ur.db.WithContext(ctx).Limit(pagination.Limit).Offset(offset).Order(pagination.Sort).Find(&users).Error; err != nil {
+	if pagination.Page > pagination.TotalPages {
+		return pagination, users, nil
+	}
+
+	query := ur.filtered(ctx, filter).Limit(pagination.Limit).Offset(pagination.Offset())
+	for _, column := range userOrder(keys) {
+		query = query.Order(column)
+	}
+	if err := query.Find(&users).Error; err != nil {
 		return nil, nil, err
 	}
 
-	if err := ur.db.Model(&models.User{}).Count(&pagination.TotalRows).Error; err != nil {
+	if len(users) > 0 {
+		pagination.FromRow = pagination.Offset() + 1
+		pagination.ToRow = pagination.Offset() + len(users)
+		if pagination.Page > 1 {
+			pagination.PrevKeyset = userCursor(keys, users[0], true)
+		}
+		if pagination.Page < pagination.TotalPages {
+			pagination.NextKeyset = userCursor(keys, users[len(users)-1], false)
+		}
+	}
+	return pagination, users, nil
+}
+
+// findUsersByKeyset reads one user more than the limit to know whether
+// there is a page further in the direction of the cursor. The page before
+// a cursor is read in the reversed order and turned back.
+func (ur *userRepository) findUsersByKeyset(ctx context.Context, filter *models.UserFilter, pagination *models.Pagination, keys []models.SortKey) (*models.Pagination, []*models.User, error) {
+	cursor := pagination.Keyset
+	after, args, err := keysetCondition(keys, cursor)
+	if err != nil {
+		return nil, nil, apperrors.InvalidCursorErr.AppendMessage(err)
+	}
+
+	query := ur.filtered(ctx, filter).Where(after, args...).Limit(pagination.Limit + 1)
+	for _, column := range userOrder(keys) {
+		column.Desc = column.Desc != cursor.Before
+		query = query.Order(column)
+	}
+	users := []*models.User{}
+	if err := query.Find(&users).Error; err != nil {
 		return nil, nil, err
 	}
 
-	pagination.TotalPages = int(math.Ceil(float64(pagination.TotalRows) / float64(pagination.Limit)))
+	further := len(users) > pagination.Limit
+	if further {
+		users = users[:pagination.Limit]
+	}
+	if cursor.Before {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+
+	if len(users) > 0 {
+		if further || !cursor.Before {
+			pagination.PrevKeyset = userCursor(keys, users[0], true)
+		}
+		if further || cursor.Before {
+			pagination.NextKeyset = userCursor(keys, users[len(users)-1], false)
+		}
+	}
+
+	if pagination.WithTotal {
+		if err := ur.count(ctx, filter, pagination); err != nil {
+			return nil, nil, err
+		}
+	}
 	return pagination, users, nil
 }
 
+func (ur *userRepository) count(ctx context.Context, filter *models.UserFilter, pagination *models.Pagination) error {
+	if err := ur.filtered(ctx, filter).Count(&pagination.TotalRows).Error; err != nil {
+		return err
+	}
+	pagination.TotalPages = int(math.Ceil(float64(pagination.TotalRows) / float64(pagination.Limit)))
+	return nil
+}
+
+// filtered matches the names by a LIKE prefix with "!" as the escape
+// character, which needs no escaping in the SQL of any supported database.
+func (ur *userRepository) filtered(ctx context.Context, filter *models.UserFilter) *gorm.DB {
+	query := conn(ctx, ur.db).Model(&models.User{})
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+	if filter.RatingGTE != nil {
+		query = query.Where("rating >= ?", *filter.RatingGTE)
+	}
+	if filter.RatingLTE != nil {
+		query = query.Where("rating <= ?", *filter.RatingLTE)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at > ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.Query != "" {
+		prefix := likeEscaper.Replace(strings.ToLower(filter.Query)) + "%"
+		query = query.Where("(LOWER(user_name) LIKE ? ESCAPE '!' OR LOWER(first_name) LIKE ? ESCAPE '!' OR LOWER(last_name) LIKE ? ESCAPE '!')", prefix, prefix, prefix)
+	}
+	return query
+}
+
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// userOrder ends with the ID, so users with equal keys keep their order
+// between pages.
+func userOrder(keys []models.SortKey) []clause.OrderByColumn {
+	columns := make([]clause.OrderByColumn, 0, len(keys)+1)
+	sortedByID := false
+	for _, key := range keys {
+		columns = append(columns, clause.OrderByColumn{Column: clause.Column{Name: key.Field}, Desc: key.Desc})
+		sortedByID = sortedByID || key.Field == "id"
+	}
+	if !sortedByID {
+		columns = append(columns, clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+	}
+	return columns
+}
+
+// keysetCondition matches the rows which come after the cursor in the order
+// of userOrder, or before it when the cursor is Before:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ..., with < for descending keys.
+func keysetCondition(keys []models.SortKey, cursor *models.Cursor) (string, []interface{}, error) {
+	if len(cursor.Values) != len(keys) {
+		return "", nil, errors.New("the cursor doesn't match the sort")
+	}
+
+	type bound struct {
+		column clause.Column
+		desc   bool
+		value  interface{}
+	}
+	bounds := make([]bound, 0, len(keys)+1)
+	sortedByID := false
+	for i, key := range keys {
+		value, err := parseUserSortValue(key.Field, cursor.Values[i])
+		if err != nil {
+			return "", nil, err
+		}
+		bounds = append(bounds, bound{clause.Column{Name: key.Field}, key.Desc, value})
+		sortedByID = sortedByID || key.Field == "id"
+	}
+	if !sortedByID {
+		bounds = append(bounds, bound{clause.Column{Name: "id"}, false, cursor.ID})
+	}
+
+	var alternatives []string
+	var args []interface{}
+	for i, last := range bounds {
+		conditions := make([]string, 0, i+1)
+		for _, equal := range bounds[:i] {
+			conditions = append(conditions, "? = ?")
+			args = append(args, equal.column, equal.value)
+		}
+		operator := ">"
+		if last.desc != cursor.Before {
+			operator = "<"
+		}
+		conditions = append(conditions, "? "+operator+" ?")
+		args = append(args, last.column, last.value)
+		alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// userCursor is the position of user in the order of keys.
+func userCursor(keys []models.SortKey, user *models.User, before bool) *models.Cursor {
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, userSortValue(user, key.Field))
+	}
+	return &models.Cursor{Sort: models.FormatSort(keys), Values: values, ID: user.ID, Before: before}
+}
+
+func userSortValue(user *models.User, field string) string {
+	switch field {
+	case "id":
+		return strconv.FormatUint(uint64(user.ID), 10)
+	case "user_name":
+		return user.UserName
+	case "rating":
+		return strconv.Itoa(user.Rating)
+	case "created_at":
+		if user.CreatedAt == nil {
+			return ""
+		}
+		return user.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return ""
+}
+
+func parseUserSortValue(field, value string) (interface{}, error) {
+	switch field {
+	case "id":
+		return strconv.ParseUint(value, 10, 0)
+	case "user_name":
+		return value, nil
+	case "rating":
+		return strconv.Atoi(value)
+	case "created_at":
+		return time.Parse(time.RFC3339Nano, value)
+	}
+	return nil, fmt.Errorf("can't sort by %q", field)
+}
+
+// FindOneUserByID returns UserNotFoundErr when there is no such user, any
+// other error is a failure of the database.
 func (ur *userRepository) FindOneUserByID(ctx context.Context, id uint) (*models.User, error) {
 	user := models.User{}
-	if err := ur.db.WithContext(ctx).Where("id = ?", id).First(&user).Error; err != nil {
+	if err := conn(ctx, ur.db).Where("id = ?", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.UserNotFoundErr.AppendMessage(err)
+		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (ur *userRepository) FindOneUserByUserNameAndPassword(ctx context.Context, username, password string) (*models.User, error) {
+// FindOneUserByUserName ignores the case of username. It returns
+// UserNotFoundErr when there is no such user, any other error is a failure
+// of the database.
+func (ur *userRepository) FindOneUserByUserName(ctx context.Context, username string) (*models.User, error) {
 	user := models.User{}
-	if err := ur.db.WithContext(ctx).Where("user_name = ?", username).Where("password = ?", password).First(&user).Error; err != nil {
+	if err := conn(ctx, ur.db).Where(userNameIs(ur.db.Dialector.Name()), username).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.UserNotFoundErr.AppendMessage(err)
+		}
 		return nil, err
 	}
 	return &user, nil
 }
 
 func (ur *userRepository) DeleteUserByID(ctx context.Context, id int) error {
-	user := &models.User{}
-	tx := ur.db.WithContext(ctx).Where("id = ?", id).First(&user)
-	if tx.Error != nil {
-		return tx.Error
-	}
-	if user.Role == "admin" {
-		return errors.New("admin user not allowed to delete")
-	}
-
-	if err := ur.db.WithContext(ctx).Delete(&models.User{}, id).Error; err != nil {
+	if err := conn(ctx, ur.db).Delete(&models.User{}, id).Error; err != nil {
 		return err
 	}
 	return nil
@@ -90,14 +333,14 @@ func (ur *userRepository) DeleteUserByID(ctx context.Context, id int) error {
 
 func (ur *userRepository) DeleteOwnUser(ctx context.Context, id int) error {
 
-	if err := ur.db.WithContext(ctx).Delete(&models.User{}, id).Error; err != nil {
+	if err := conn(ctx, ur.db).Delete(&models.User{}, id).Error; err != nil {
 		return err
 	}
 	return nil
 }
 
 func (ur *userRepository) UpdateUserByID(ctx context.Context, id int, user *models.User) (*models.User, error) {
-	if err := ur.db.WithContext(ctx).Where("id = ?", id).Updates(&user).First(&user).Error; err != nil {
+	if err := conn(ctx, ur.db).Where("id = ?", id).Updates(&user).First(&user).Error; err != nil {
 		return nil, err
 	}
 	return user, nil
@@ -105,7 +348,7 @@ func (ur *userRepository) UpdateUserByID(ctx context.Context, id int, user *mode
 
 func (ur *userRepository) UpdateOwnUser(ctx context.Context, id int, user *models.User) (*models.User, error) {
 
-	tx := ur.db.WithContext(ctx).Where("id = ?", id).Updates(&user).First(&user)
+	tx := conn(ctx, ur.db).Where("id = ?", id).Updates(&user).First(&user)
 	if tx.Error != nil {
 		return nil, tx.Error
 	}
@@ -113,10 +356,35 @@ func (ur *userRepository) UpdateOwnUser(ctx context.Context, id int, user *model
 	return user, nil
 }
 
+func (ur *userRepository) UpdateUserPassword(ctx context.Context, id uint, passwordHash string) error {
+	if err := conn(ctx, ur.db).Model(&models.User{}).Where("id = ?", id).UpdateColumn("password", passwordHash).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ur *userRepository) UpdateUserRole(ctx context.Context, id uint, role string) error {
+	if err := conn(ctx, ur.db).Model(&models.User{}).Where("id = ?", id).UpdateColumn("role", role).Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (ur *userRepository) CountUsersByRole(ctx context.Context, role string) (int64, error) {
+	var count int64
+	if err := conn(ctx, ur.db).Model(&models.User{}).Where("role = ?", role).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (ur *userRepository) RateUserByUsername(ctx context.Context, rateUserID uint, username, rate string) (*models.User, error) {
 	user := &models.User{}
-	if err := ur.db.WithContext(ctx).Where("user_name = ?", username).Preload("RatedByUsers").First(&user).Error; err != nil {
-		return nil, apperrors.UserNotFoundErr.AppendMessage(err)
+	if err := conn(ctx, ur.db).Where(userNameIs(ur.db.Dialector.Name()), username).Preload("RatedByUsers").First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.UserNotFoundErr.AppendMessage(err)
+		}
+		return nil, err
 	}
 
 	existingRateUser, rating, err := determineUserRate(user.Rating, user.RatedByUsers, rateUserID, rate)
@@ -127,16 +395,23 @@ func (ur *userRepository) RateUserByUsername(ctx context.Context, rateUserID uin
 
 	if existingRateUser != nil {
 		existingRateUser.Rate = rate
-		if err := ur.db.WithContext(ctx).Where("id = ?", existingRateUser.ID).Updates(&existingRateUser).Error; err != nil {
+		if err := conn(ctx, ur.db).Where("id = ?", existingRateUser.ID).Updates(&existingRateUser).Error; err != nil {
 			return nil, apperrors.CanNotUpdateErr.AppendMessage(err)
 		}
 	} else {
-		if err := ur.db.WithContext(ctx).Model(&user).Association("RatedByUsers").Append(&models.RatedByUser{RatedByUserID: rateUserID, Rate: rate}); err != nil {
+		// a plain insert, the upsert of Association().Append would hide on
+		// MySQL that a concurrent request rated the user first
+		ratedByUser := models.RatedByUser{UserID: user.ID, RatedByUserID: rateUserID, Rate: rate}
+		if err := conn(ctx, ur.db).Create(&ratedByUser).Error; err != nil {
+			if IsUniqueViolation(err) {
+				return nil, apperrors.ProblemWithGivingRating.AppendMessage(err)
+			}
 			return nil, apperrors.CanNotCreateTableErr.AppendMessage(err)
 		}
+		user.RatedByUsers = append(user.RatedByUsers, ratedByUser)
 	}
 
-	if err := ur.db.WithContext(ctx).Where("user_name = ?", username).UpdateColumns(&models.User{Rating: user.Rating}).Error; err != nil {
+	if err := conn(ctx, ur.db).Where("id = ?", user.ID).UpdateColumns(&models.User{Rating: user.Rating}).Error; err != nil {
 		return nil, apperrors.CanNotUpdateErr.AppendMessage(err)
 	}
 
@@ -0,0 +1,272 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/datastore"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := datastore.NewDB(&config.Config{DBDriver: datastore.DriverSQLite, DBName: ":memory:"}, logger.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	migrator, err := datastore.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestUserRepositoryOnSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+
+	created, err := userRepo.CreateUser(ctx, &models.User{UserName: "Alice", Role: "user"})
+	assert.NoError(t, err)
+
+	t.Run("username lookup ignores case", func(t *testing.T) {
+		user, err := userRepo.FindOneUserByUserName(ctx, "aLiCe")
+		assert.NoError(t, err)
+		assert.Equal(t, created.ID, user.ID)
+	})
+
+	t.Run("unknown username", func(t *testing.T) {
+		_, err := userRepo.FindOneUserByUserName(ctx, "bob")
+		assert.True(t, errors.Is(err, &apperrors.UserNotFoundErr))
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	})
+
+	t.Run("unknown id", func(t *testing.T) {
+		_, err := userRepo.FindOneUserByID(ctx, created.ID+100)
+		assert.True(t, errors.Is(err, &apperrors.UserNotFoundErr))
+		assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	})
+
+	t.Run("username taken in another case", func(t *testing.T) {
+		_, err := userRepo.CreateUser(ctx, &models.User{UserName: "ALICE", Role: "user"})
+		assert.True(t, errors.Is(err, &apperrors.UsernameTakenErr))
+	})
+
+	t.Run("rate user by username in another case", func(t *testing.T) {
+		rater, err := userRepo.CreateUser(ctx, &models.User{UserName: "bob", Role: "user"})
+		assert.NoError(t, err)
+
+		user, err := userRepo.RateUserByUsername(ctx, rater.ID, "ALICE", "up")
+		assert.NoError(t, err)
+		assert.Equal(t, 1, user.Rating)
+	})
+
+	t.Run("one rating per rater", func(t *testing.T) {
+		rater, err := userRepo.FindOneUserByUserName(ctx, "bob")
+		assert.NoError(t, err)
+
+		err = db.Create(&models.RatedByUser{UserID: created.ID, RatedByUserID: rater.ID, Rate: "down"}).Error
+		assert.True(t, repository.IsUniqueViolation(err))
+	})
+}
+
+func TestFindUsersOnSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+
+	day := func(d int) *time.Time {
+		t := time.Date(2023, 1, d, 0, 0, 0, 0, time.UTC)
+		return &t
+	}
+	for _, user := range []*models.User{
+		{UserName: "john_hall", FirstName: "John", LastName: "Hall", Role: "user", Rating: 3, CreatedAt: day(1)},
+		{UserName: "jo%nes", FirstName: "Mary", LastName: "Jones", Role: "moderator", Rating: -1, CreatedAt: day(2)},
+		{UserName: "alice", FirstName: "Alice", LastName: "Johnson", Role: "admin", Rating: 3, CreatedAt: day(3)},
+		{UserName: "bob", FirstName: "Bob", LastName: "Brown", Role: "user", Rating: 0, CreatedAt: day(4)},
+	} {
+		if _, err := userRepo.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rating := 0
+	testTable := []struct {
+		scenario      string
+		filter        *models.UserFilter
+		sort          string
+		expectedNames []string
+	}{
+		{"no filter", &models.UserFilter{}, "", []string{"john_hall", "jo%nes", "alice", "bob"}},
+		{"role", &models.UserFilter{Role: "user"}, "", []string{"john_hall", "bob"}},
+		{"rating at least", &models.UserFilter{RatingGTE: &rating}, "", []string{"john_hall", "alice", "bob"}},
+		{"rating at most", &models.UserFilter{RatingLTE: &rating}, "", []string{"jo%nes", "bob"}},
+		{"created in a range", &models.UserFilter{CreatedAfter: day(1), CreatedBefore: day(4)}, "", []string{"jo%nes", "alice"}},
+		{"name prefix of any name ignoring case", &models.UserFilter{Query: "JO"}, "", []string{"john_hall", "jo%nes", "alice"}},
+		{"wildcards are literal", &models.UserFilter{Query: "jo%"}, "", []string{"jo%nes"}},
+		{"underscore is literal", &models.UserFilter{Query: "john_"}, "", []string{"john_hall"}},
+		{"several keys with the ID last", &models.UserFilter{}, "rating desc,created_at desc", []string{"alice", "john_hall", "bob", "jo%nes"}},
+		{"equal keys are sorted by ID", &models.UserFilter{}, "rating desc", []string{"john_hall", "alice", "bob", "jo%nes"}},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			pagination, users, err := userRepo.FindUsers(ctx, tc.filter, &models.Pagination{Limit: 10, Page: 1, Sort: tc.sort})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			names := make([]string, 0, len(users))
+			for _, user := range users {
+				names = append(names, user.UserName)
+			}
+			assert.Equal(t, tc.expectedNames, names)
+			assert.Equal(t, int64(len(tc.expectedNames)), pagination.TotalRows)
+		})
+	}
+
+	for _, i := range []int{0, len(testTable) - 2, len(testTable) - 1} {
+		tc := testTable[i]
+		t.Run("keyset pages: "+tc.scenario, func(t *testing.T) {
+			names := func(users []*models.User) []string {
+				names := make([]string, 0, len(users))
+				for _, user := range users {
+					names = append(names, user.UserName)
+				}
+				return names
+			}
+
+			pagination, users, err := userRepo.FindUsers(ctx, tc.filter, &models.Pagination{Limit: 3, Page: 1, Sort: tc.sort})
+			if !assert.NoError(t, err) || !assert.NotNil(t, pagination.NextKeyset) {
+				return
+			}
+			assert.Nil(t, pagination.PrevKeyset)
+			walked := names(users)
+
+			pagination, users, err = userRepo.FindUsers(ctx, tc.filter, &models.Pagination{Limit: 3, Sort: tc.sort, Keyset: pagination.NextKeyset})
+			if !assert.NoError(t, err) {
+				return
+			}
+			walked = append(walked, names(users)...)
+			assert.Equal(t, tc.expectedNames, walked)
+			assert.Nil(t, pagination.NextKeyset)
+			assert.Zero(t, pagination.TotalRows, "counted without WithTotal")
+
+			pagination, users, err = userRepo.FindUsers(ctx, tc.filter, &models.Pagination{Limit: 3, Sort: tc.sort, Keyset: pagination.PrevKeyset, WithTotal: true})
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tc.expectedNames[:3], names(users))
+			assert.Nil(t, pagination.PrevKeyset)
+			assert.NotNil(t, pagination.NextKeyset)
+			assert.Equal(t, int64(4), pagination.TotalRows)
+		})
+	}
+
+	t.Run("keyset pages don't shift when users are added", func(t *testing.T) {
+		pagination, _, err := userRepo.FindUsers(ctx, &models.UserFilter{}, &models.Pagination{Limit: 2, Page: 1, Sort: "id asc"})
+		if !assert.NoError(t, err) {
+			return
+		}
+		if _, err := userRepo.CreateUser(ctx, &models.User{UserName: "zed", Role: "user", CreatedAt: day(5)}); err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { db.Unscoped().Where("user_name = ?", "zed").Delete(&models.User{}) })
+
+		_, users, err := userRepo.FindUsers(ctx, &models.UserFilter{}, &models.Pagination{Limit: 2, Sort: "id asc", Keyset: pagination.NextKeyset})
+		if assert.NoError(t, err) && assert.Len(t, users, 2) {
+			assert.Equal(t, "alice", users[0].UserName)
+			assert.Equal(t, "bob", users[1].UserName)
+		}
+	})
+
+	t.Run("cursor of another sort", func(t *testing.T) {
+		_, _, err := userRepo.FindUsers(ctx, &models.UserFilter{}, &models.Pagination{Limit: 2, Sort: "rating desc,created_at desc", Keyset: &models.Cursor{Values: []string{"3"}, ID: 1}})
+		assert.True(t, errors.Is(err, &apperrors.InvalidCursorErr))
+	})
+
+	t.Run("page past the end", func(t *testing.T) {
+		pagination, users, err := userRepo.FindUsers(ctx, &models.UserFilter{}, &models.Pagination{Limit: 3, Page: 3})
+		if assert.NoError(t, err) {
+			assert.Empty(t, users)
+			assert.Equal(t, int64(4), pagination.TotalRows)
+			assert.Equal(t, 2, pagination.TotalPages)
+		}
+	})
+
+	t.Run("rows of the page", func(t *testing.T) {
+		pagination, _, err := userRepo.FindUsers(ctx, &models.UserFilter{}, &models.Pagination{Limit: 3, Page: 2})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 4, pagination.FromRow)
+			assert.Equal(t, 4, pagination.ToRow)
+		}
+	})
+
+	t.Run("zero limit and negative page", func(t *testing.T) {
+		_, _, err := userRepo.FindUsers(ctx, &models.UserFilter{}, &models.Pagination{Limit: 0, Page: 1})
+		assert.Error(t, err)
+		_, _, err = userRepo.FindUsers(ctx, &models.UserFilter{}, &models.Pagination{Limit: 3, Page: -1})
+		assert.Error(t, err)
+	})
+
+	t.Run("fields which aren't whitelisted", func(t *testing.T) {
+		_, _, err := userRepo.FindUsers(ctx, &models.UserFilter{}, &models.Pagination{Limit: 10, Page: 1, Sort: "password"})
+		assert.Error(t, err)
+	})
+}
+
+// TestFindOneUserByUserNameQuery checks that MySQL compares usernames as they
+// are, so its unique index of user_name is used.
+func TestFindOneUserByUserNameQuery(t *testing.T) {
+	testTable := []struct {
+		scenario    string
+		dialector   gorm.Dialector
+		expectedSQL string
+	}{
+		{
+			"PostgreSQL lowers both sides",
+			postgres.New(postgres.Config{DSN: "host=localhost"}),
+			"WHERE LOWER(user_name) = LOWER($1)",
+		},
+		{
+			"MySQL relies on the collation",
+			mysql.New(mysql.Config{DSN: "user@/db", SkipInitializeWithVersion: true}),
+			"WHERE user_name = ?",
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			db, err := gorm.Open(tc.dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var statements []*gorm.Statement
+			err = db.Callback().Query().After("gorm:query").Register("test:statements", func(db *gorm.DB) {
+				statements = append(statements, db.Statement)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a dry run finds nothing
+			_, _ = repository.NewUserRepository(db).FindOneUserByUserName(context.Background(), "JohnHall")
+			if assert.NotEmpty(t, statements) {
+				assert.Contains(t, statements[0].SQL.String(), tc.expectedSQL)
+				assert.Equal(t, []interface{}{"JohnHall"}, statements[0].Vars)
+			}
+		})
+	}
+}
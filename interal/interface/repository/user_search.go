@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"gorm.io/gorm/clause"
+)
+
+// maxSearchTerms bounds the words of a search, the rest are ignored.
+const maxSearchTerms = 8
+
+// postgresSearchDocument is the expression of idx_users_search, PostgreSQL
+// uses the index only for this very expression.
+const postgresSearchDocument = "to_tsvector('simple', coalesce(user_name, '') || ' ' || coalesce(first_name, '') || ' ' || coalesce(last_name, ''))"
+
+// mysqlBooleanOperators have a meaning in MATCH ... AGAINST in boolean mode.
+const mysqlBooleanOperators = `+-<>()~*"@`
+
+// SearchUsers finds the users whose username, first or last name match
+// every word of query, the best matches first. The words are prefixes of the
+// words of the names on PostgreSQL and MySQL, which search their full-text
+// indexes, and parts of the names on any other database, which uses LIKE.
+// pagination.Sort is ignored.
+func (ur *userRepository) SearchUsers(ctx context.Context, query string, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+	if err := pagination.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	users := []*models.User{}
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return pagination, users, nil
+	}
+	match, rank := userSearch(ur.db.Dialector.Name(), terms)
+
+	if err := conn(ctx, ur.db).Model(&models.User{}).Where(match).Count(&pagination.TotalRows).Error; err != nil {
+		return nil, nil, err
+	}
+	pagination.TotalPages = int(math.Ceil(float64(pagination.TotalRows) / float64(pagination.Limit)))
+	if pagination.Page > pagination.TotalPages {
+		return pagination, users, nil
+	}
+
+	// Order doesn't take an expression with values, so the rank and the ID are
+	// one ORDER BY expression
+	order := clause.Expr{SQL: rank.SQL + ", ?", Vars: append(append([]interface{}{}, rank.Vars...), clause.Column{Name: "id"}), WithoutParentheses: true}
+	err := conn(ctx, ur.db).Model(&models.User{}).Where(match).Clauses(clause.OrderBy{Expression: order}).
+		Limit(pagination.Limit).Offset(pagination.Offset()).Find(&users).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(users) > 0 {
+		pagination.FromRow = pagination.Offset() + 1
+		pagination.ToRow = pagination.Offset() + len(users)
+	}
+	return pagination, users, nil
+}
+
+func searchTerms(query string) []string {
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// userSearch returns the condition of the users which match terms, and the
+// order of relevance.
+func userSearch(dialect string, terms []string) (match, rank clause.Expr) {
+	switch dialect {
+	case "postgres":
+		// every term is a quoted lexeme, so the operators of tsquery in it
+		// are plain characters
+		lexemes := make([]string, 0, len(terms))
+		for _, term := range terms {
+			term = strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(term)
+			lexemes = append(lexemes, "'"+term+"':*")
+		}
+		tsquery := strings.Join(lexemes, " & ")
+		return clause.Expr{SQL: postgresSearchDocument + " @@ to_tsquery('simple', ?)", Vars: []interface{}{tsquery}},
+			clause.Expr{SQL: "ts_rank(" + postgresSearchDocument + ", to_tsquery('simple', ?)) DESC", Vars: []interface{}{tsquery}, WithoutParentheses: true}
+	case "mysql":
+		words := strings.Fields(strings.Map(func(r rune) rune {
+			if strings.ContainsRune(mysqlBooleanOperators, r) {
+				return ' '
+			}
+			return r
+		}, strings.Join(terms, " ")))
+		if len(words) == 0 {
+			break
+		}
+		for i, word := range words {
+			words[i] = "+" + word + "*"
+		}
+		against := strings.Join(words, " ")
+		matchSQL := "MATCH (user_name, first_name, last_name) AGAINST (? IN BOOLEAN MODE)"
+		return clause.Expr{SQL: matchSQL, Vars: []interface{}{against}},
+			clause.Expr{SQL: matchSQL + " DESC", Vars: []interface{}{against}, WithoutParentheses: true}
+	}
+	return likeUserSearch(terms)
+}
+
+// likeUserSearch matches the terms anywhere in the names. An exact username
+// comes first, then the usernames and the names which start with the first
+// term, then the rest.
+func likeUserSearch(terms []string) (match, rank clause.Expr) {
+	conditions := make([]string, 0, len(terms))
+	for _, term := range terms {
+		part := "%" + likeEscaper.Replace(term) + "%"
+		conditions = append(conditions, "(LOWER(user_name) LIKE ? ESCAPE '!' OR LOWER(first_name) LIKE ? ESCAPE '!' OR LOWER(last_name) LIKE ? ESCAPE '!')")
+		match.Vars = append(match.Vars, part, part, part)
+	}
+	match.SQL = strings.Join(conditions, " AND ")
+
+	prefix := likeEscaper.Replace(terms[0]) + "%"
+	rank.SQL = "CASE WHEN LOWER(user_name) = ? THEN 0 WHEN LOWER(user_name) LIKE ? ESCAPE '!' THEN 1 " +
+		"WHEN LOWER(first_name) LIKE ? ESCAPE '!' OR LOWER(last_name) LIKE ? ESCAPE '!' THEN 2 ELSE 3 END"
+	rank.Vars = []interface{}{strings.Join(terms, " "), prefix, prefix, prefix}
+	rank.WithoutParentheses = true
+	return match, rank
+}
@@ -0,0 +1,126 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestSearchUsersOnSQLite(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	userRepo := repository.NewUserRepository(db)
+
+	for _, user := range []*models.User{
+		{UserName: "mjohnson", FirstName: "Mary", LastName: "Smith-Johnson", Role: "user"},
+		{UserName: "john", FirstName: "John", LastName: "Hall", Role: "user"},
+		{UserName: "alice", FirstName: "Alice", LastName: "Johnes", Role: "user"},
+		{UserName: "johnny_b", FirstName: "Bob", LastName: "Brown", Role: "user"},
+		{UserName: "100%_sure", FirstName: "Sam", LastName: "Sure", Role: "user"},
+		{UserName: "deleted_john", FirstName: "Dan", LastName: "Doe", Role: "user"},
+	} {
+		if _, err := userRepo.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deleted, err := userRepo.FindOneUserByUserName(ctx, "deleted_john")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := userRepo.DeleteUserByID(ctx, int(deleted.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	testTable := []struct {
+		scenario      string
+		query         string
+		expectedNames []string
+	}{
+		{"exact username, username prefixes, name prefixes, parts", "JOHN", []string{"john", "johnny_b", "alice", "mjohnson"}},
+		{"every word matches", "john hall", []string{"john"}},
+		{"percent is literal", "0%", []string{"100%_sure"}},
+		{"underscore is literal", "y_b", []string{"johnny_b"}},
+		{"the escape character is literal", "!", nil},
+		{"no words", "  ", nil},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			pagination, users, err := userRepo.SearchUsers(ctx, tc.query, &models.Pagination{Limit: 10, Page: 1})
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			var names []string
+			for _, user := range users {
+				names = append(names, user.UserName)
+			}
+			assert.Equal(t, tc.expectedNames, names)
+			assert.Equal(t, int64(len(tc.expectedNames)), pagination.TotalRows)
+		})
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		pagination, users, err := userRepo.SearchUsers(ctx, "john", &models.Pagination{Limit: 3, Page: 2})
+		if assert.NoError(t, err) && assert.Len(t, users, 1) {
+			assert.Equal(t, "mjohnson", users[0].UserName)
+			assert.Equal(t, 2, pagination.TotalPages)
+		}
+	})
+}
+
+// TestSearchUsersQuery checks the full-text queries without a database: the
+// statements are only built.
+func TestSearchUsersQuery(t *testing.T) {
+	testTable := []struct {
+		scenario     string
+		dialector    gorm.Dialector
+		query        string
+		expectedSQL  string
+		expectedVars []interface{}
+	}{
+		{
+			"PostgreSQL quotes every word",
+			postgres.New(postgres.Config{DSN: "host=localhost"}),
+			`O'Neil a\b:*|!`,
+			"@@ to_tsquery('simple', $1)",
+			[]interface{}{`'o''neil':* & 'a\\b:*|!':*`},
+		},
+		{
+			"MySQL drops the boolean operators",
+			mysql.New(mysql.Config{DSN: "user@/db", SkipInitializeWithVersion: true}),
+			`+jo -"hall" (x)~ *`,
+			"MATCH (user_name, first_name, last_name) AGAINST (? IN BOOLEAN MODE)",
+			[]interface{}{"+jo* +hall* +x*"},
+		},
+	}
+
+	for _, tc := range testTable {
+		t.Run(tc.scenario, func(t *testing.T) {
+			db, err := gorm.Open(tc.dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true, Logger: logger.Discard})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var statements []*gorm.Statement
+			err = db.Callback().Query().After("gorm:query").Register("test:statements", func(db *gorm.DB) {
+				statements = append(statements, db.Statement)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = repository.NewUserRepository(db).SearchUsers(context.Background(), tc.query, &models.Pagination{Limit: 10, Page: 1})
+			if assert.NoError(t, err) && assert.NotEmpty(t, statements) {
+				assert.Contains(t, statements[0].SQL.String(), tc.expectedSQL)
+				assert.Equal(t, tc.expectedVars, statements[0].Vars)
+			}
+		})
+	}
+}
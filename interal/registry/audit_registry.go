@@ -0,0 +1,11 @@
+package registry
+
+import (
+	"git.foxminded.com.ua/3_REST_API/interal/interface/controller"
+	ir "git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+)
+
+func (r *registry) NewAuditController() controller.AuditController {
+	return controller.NewAuditController(interactor.NewAuditInteractor(ir.NewAuditRepository(r.db)), r.paginationPolicy())
+}
@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"context"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/datastore"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/controller"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+)
+
+// readinessCheckTimeout keeps a hanging database from hanging the probe.
+const readinessCheckTimeout = 2 * time.Second
+
+func (r *registry) NewHealthController() controller.HealthController {
+	return controller.NewHealthController(interactor.NewHealthInteractor(map[string]interactor.HealthCheck{
+		"database":   r.pingDB,
+		"migrations": r.checkMigrations,
+	}))
+}
+
+func (r *registry) pingDB(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (r *registry) checkMigrations(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	migrator, err := datastore.NewMigrator(r.db)
+	if err != nil {
+		return err
+	}
+	return migrator.CheckUpToDate(ctx)
+}
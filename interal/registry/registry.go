@@ -1,26 +1,137 @@
 package registry
 
 import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"git.foxminded.com.ua/3_REST_API/interal/config"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/mappers"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/metrics"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/tracing"
 	"git.foxminded.com.ua/3_REST_API/interal/interface/controller"
+	ir "git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/hasher"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/signer"
 	"gorm.io/gorm"
 )
 
 type registry struct {
-	db     *gorm.DB
-	config *config.Config
+	db              *gorm.DB
+	config          *config.Config
+	passwordHasher  hasher.PasswordHasher
+	revocationStore ir.RevocationStore
+	keySet          *signer.KeySet
+	roles           *permissions.Roles
+	rateLimitStore  ir.RateLimitStore
+	metrics         *metrics.Metrics
+	tracing         *tracing.Tracing
+	cursorKey       []byte
+	deletion        interactor.DeletionOptions
 }
 
 type Registry interface {
 	NewAppController() *controller.AppController
+	NewUserInteractor() interactor.UserInteractor
+	Metrics() *metrics.Metrics
+	Tracing() *tracing.Tracing
+	BootstrapAdmin(ctx context.Context) error
+}
+
+func NewRegistry(db *gorm.DB, config *config.Config) (Registry, error) {
+	passwordHasher, err := hasher.NewPasswordHasher(config.PasswordHasher, config.HashSalt)
+	if err != nil {
+		return nil, apperrors.HasherInitializeErr.AppendMessage(err)
+	}
+
+	var revocationStore ir.RevocationStore
+	switch config.RevocationStore {
+	case ir.InMemoryRevocationStore:
+		revocationStore = ir.NewInMemoryRevocationStore()
+	case ir.GormRevocationStore, "":
+		revocationStore = ir.NewGormRevocationStore(db)
+	default:
+		return nil, apperrors.RevocationStoreInitializeErr.AppendMessage(fmt.Errorf("unknown revocation store %q", config.RevocationStore))
+	}
+
+	keySet, err := signer.LoadKeySet(config.SigningMethod, config.KeyID, config.SigningKey, config.PrivateKeyFile, config.VerifyKeys)
+	if err != nil {
+		return nil, apperrors.KeySetInitializeErr.AppendMessage(err)
+	}
+
+	// Without CURSOR_SIGNING_KEY the cursors are valid only until a restart
+	// and only on this instance.
+	cursorKey := []byte(config.CursorSigningKey)
+	if len(cursorKey) == 0 {
+		cursorKey = make([]byte, 32)
+		if _, err := rand.Read(cursorKey); err != nil {
+			return nil, apperrors.KeySetInitializeErr.AppendMessage(err)
+		}
+	}
+
+	var deletion interactor.DeletionOptions
+	switch config.DeletedUserNames {
+	case interactor.FreeDeletedUserNames:
+		deletion.ReleaseUserNames = true
+	case interactor.ReserveDeletedUserNames, "":
+	default:
+		return nil, apperrors.DeletionOptionsInitializeErr.AppendMessage(fmt.Errorf("unknown deleted usernames policy %q", config.DeletedUserNames))
+	}
+	if config.DeletedUserDays < 0 {
+		return nil, apperrors.DeletionOptionsInitializeErr.AppendMessage(fmt.Errorf("negative retention of deleted users %d", config.DeletedUserDays))
+	}
+
+	roles, err := permissions.NewRoles(config.RolePermissions)
+	if err != nil {
+		return nil, apperrors.RolesInitializeErr.AppendMessage(err)
+	}
+
+	var rateLimitStore ir.RateLimitStore
+	switch config.RateLimitStore {
+	case ir.InMemoryRateLimitStore, "":
+		rateLimitStore = ir.NewInMemoryRateLimitStore()
+	default:
+		return nil, apperrors.RateLimitStoreInitializeErr.AppendMessage(fmt.Errorf("unknown rate limit store %q", config.RateLimitStore))
+	}
+
+	m := metrics.New()
+	if err := db.Use(metrics.GormPlugin{Metrics: m, DBName: config.DBName}); err != nil {
+		return nil, apperrors.MetricsInitializeErr.AppendMessage(err)
+	}
+
+	t, err := tracing.New(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Use(tracing.GormPlugin{Tracer: t.Tracer()}); err != nil {
+		return nil, apperrors.TracingInitializeErr.AppendMessage(err)
+	}
+
+	return &registry{db, config, passwordHasher, revocationStore, keySet, roles, rateLimitStore, m, t, cursorKey, deletion}, nil
+}
+
+func (r *registry) paginationPolicy() mappers.PaginationPolicy {
+	return mappers.PaginationPolicy{DefaultLimit: r.config.PageDefaultLimit, MaxLimit: r.config.PageMaxLimit}
+}
+
+func (r *registry) Metrics() *metrics.Metrics {
+	return r.metrics
 }
 
-func NewRegistry(db *gorm.DB, config *config.Config) Registry {
-	return &registry{db, config}
+func (r *registry) Tracing() *tracing.Tracing {
+	return r.tracing
 }
 
 func (r *registry) NewAppController() *controller.AppController {
 	return &controller.AppController{
-		UserController: r.NewUserController(),
+		UserController:   r.NewUserController(),
+		AuditController:  r.NewAuditController(),
+		HealthController: r.NewHealthController(),
+		Roles:            r.roles,
+		SignInIPLimiter:  ratelimit.NewLimiter(r.rateLimitStore, "ip", ratelimit.PerMinute(r.config.SignInIPLimit)),
 	}
 }
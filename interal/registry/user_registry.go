@@ -1,12 +1,49 @@
 package registry
 
 import (
+	"context"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/metrics"
+	"git.foxminded.com.ua/3_REST_API/interal/infrastructure/tracing"
 	"git.foxminded.com.ua/3_REST_API/interal/interface/controller"
 	ir "git.foxminded.com.ua/3_REST_API/interal/interface/repository"
 	"git.foxminded.com.ua/3_REST_API/interal/usecase/interactor"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
 )
 
 func (r *registry) NewUserController() controller.UserController {
-	return controller.NewUserController(
-		interactor.NewUserInteractor(ir.NewUserRepository(r.db), r.config.HashSalt, []byte(r.config.SigningKey), r.config.TokenTtl))
+	return controller.NewUserController(metrics.InstrumentUserInteractor(r.NewUserInteractor(), r.metrics), r.paginationPolicy())
+}
+
+func (r *registry) NewUserInteractor() interactor.UserInteractor {
+	tracer := r.tracing.Tracer()
+	return tracing.TraceUserInteractor(interactor.NewUserInteractor(tracing.TraceUserRepository(ir.NewUserRepository(r.db), tracer), ir.NewRefreshTokenRepository(r.db), ir.NewAuditRepository(r.db),
+		ir.NewLoginAttemptRepository(r.db), ir.NewTransactor(r.db),
+		r.revocationStore, r.passwordHasher, r.roles,
+		interactor.TokenOptions{
+			KeySet:          r.keySet,
+			Issuer:          r.config.TokenIssuer,
+			Audience:        r.config.TokenAudience,
+			TokenTTL:        r.config.TokenTtl,
+			RefreshTokenTTL: r.config.RefreshTokenTtl,
+			UserCacheTTL:    r.config.UserCacheTtl,
+			CursorKey:       r.cursorKey,
+		},
+		interactor.LockoutOptions{
+			UserLimiter: ratelimit.NewLimiter(r.rateLimitStore, "user", ratelimit.PerMinute(r.config.SignInUserLimit)),
+			Threshold:   r.config.LockoutThreshold,
+			Duration:    time.Second * time.Duration(r.config.LockoutDuration),
+			MaxDuration: time.Second * time.Duration(r.config.LockoutMaxDuration),
+		},
+		r.deletion), tracer)
+}
+
+// BootstrapAdmin creates the first admin from ADMIN_USERNAME/ADMIN_PASSWORD
+// when they are set and there is no admin yet.
+func (r *registry) BootstrapAdmin(ctx context.Context) error {
+	if r.config.AdminUserName == "" {
+		return nil
+	}
+	return r.NewUserInteractor().BootstrapAdmin(ctx, r.config.AdminUserName, r.config.AdminPassword)
 }
@@ -0,0 +1,60 @@
+package hasher
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// legacySHA1Hasher understands hashes made before the PasswordHasher was
+// introduced: hex(sha1(password + HASH_SALT)) with one global salt. It is
+// kept only to verify such hashes, they are always reported for a rehash.
+type legacySHA1Hasher struct {
+	salt string
+}
+
+func NewLegacySHA1Hasher(salt string) *legacySHA1Hasher {
+	return &legacySHA1Hasher{salt: salt}
+}
+
+func (lh *legacySHA1Hasher) Hash(password string) (string, error) {
+	if password == "" {
+		return "", errEmptyPassword
+	}
+	if lh.salt == "" {
+		return "", errors.New("empty hashSalt field")
+	}
+
+	pwd := sha1.New()
+	if _, err := pwd.Write([]byte(password)); err != nil {
+		return "", err
+	}
+	if _, err := pwd.Write([]byte(lh.salt)); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", pwd.Sum(nil)), nil
+}
+
+func (lh *legacySHA1Hasher) Verify(password, encodedHash string) (bool, error) {
+	hash, err := lh.Hash(password)
+	if err != nil {
+		return false, err
+	}
+
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(encodedHash)) == 1, nil
+}
+
+func (lh *legacySHA1Hasher) NeedsRehash(encodedHash string) bool {
+	return true
+}
+
+func (lh *legacySHA1Hasher) Identifies(encodedHash string) bool {
+	if len(encodedHash) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(encodedHash)
+	return err == nil
+}
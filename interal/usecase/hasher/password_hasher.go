@@ -0,0 +1,93 @@
+package hasher
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	BcryptAlgorithm   = "bcrypt"
+	Argon2idAlgorithm = "argon2id"
+)
+
+var errEmptyPassword = errors.New("empty pasword field")
+
+// PasswordHasher hashes passwords into self-describing encoded strings
+// (algorithm, parameters and per-user salt are all stored in the hash),
+// so that any stored hash can be verified and upgraded later.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encodedHash string) (bool, error)
+	NeedsRehash(encodedHash string) bool
+	// VerifyDummy takes as long as Verify of a real hash, it is used when
+	// there is no user so the response time doesn't reveal that.
+	VerifyDummy(password string)
+}
+
+// algorithm hashes with one algorithm and is able to recognize its own hashes.
+type algorithm interface {
+	Hash(password string) (string, error)
+	Verify(password, encodedHash string) (bool, error)
+	NeedsRehash(encodedHash string) bool
+	Identifies(encodedHash string) bool
+}
+
+type passwordHasher struct {
+	primary    algorithm
+	algorithms []algorithm
+	dummyHash  string
+}
+
+// NewPasswordHasher returns a hasher which creates new hashes with the given
+// algorithm and still verifies hashes made by any other known algorithm,
+// including the legacy salted SHA-1 ones. Hashes not made by the primary
+// algorithm with its current parameters are reported by NeedsRehash.
+func NewPasswordHasher(algorithmName, legacySalt string) (PasswordHasher, error) {
+	bcryptHasher := NewBcryptHasher(DefaultBcryptCost)
+	argon2idHasher := NewArgon2idHasher(DefaultArgon2idParams)
+
+	var primary algorithm
+	switch algorithmName {
+	case BcryptAlgorithm, "":
+		primary = bcryptHasher
+	case Argon2idAlgorithm:
+		primary = argon2idHasher
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q", algorithmName)
+	}
+
+	dummyHash, err := primary.Hash("dummy password")
+	if err != nil {
+		return nil, err
+	}
+
+	return &passwordHasher{
+		primary:    primary,
+		algorithms: []algorithm{bcryptHasher, argon2idHasher, NewLegacySHA1Hasher(legacySalt)},
+		dummyHash:  dummyHash,
+	}, nil
+}
+
+func (ph *passwordHasher) Hash(password string) (string, error) {
+	return ph.primary.Hash(password)
+}
+
+func (ph *passwordHasher) Verify(password, encodedHash string) (bool, error) {
+	for _, a := range ph.algorithms {
+		if a.Identifies(encodedHash) {
+			return a.Verify(password, encodedHash)
+		}
+	}
+	return false, errors.New("unknown password hash format")
+}
+
+func (ph *passwordHasher) VerifyDummy(password string) {
+	_, _ = ph.primary.Verify(password, ph.dummyHash)
+}
+
+func (ph *passwordHasher) NeedsRehash(encodedHash string) bool {
+	if !ph.primary.Identifies(encodedHash) {
+		return true
+	}
+	return ph.primary.NeedsRehash(encodedHash)
+}
@@ -0,0 +1,132 @@
+package hasher
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHasher(t *testing.T) {
+	testTable := []struct {
+		scenario  string
+		algorithm string
+	}{
+		{"bcrypt hasher", BcryptAlgorithm},
+		{"argon2id hasher", Argon2idAlgorithm},
+		{"default hasher", ""},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			passwordHasher, err := NewPasswordHasher(testCase.algorithm, "hash_salt")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hash, err := passwordHasher.Hash("very12difficult()Password")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			otherHash, err := passwordHasher.Hash("very12difficult()Password")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, hash == otherHash, false, "every hash must have its own salt")
+
+			ok, err := passwordHasher.Verify("very12difficult()Password", hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, ok, true)
+
+			ok, err = passwordHasher.Verify("wrongPassword", hash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, ok, false)
+
+			assert.Equal(t, passwordHasher.NeedsRehash(hash), false)
+		})
+	}
+}
+
+func TestNewPasswordHasherUnknownAlgorithm(t *testing.T) {
+	_, err := NewPasswordHasher("md5", "hash_salt")
+	assert.Equal(t, err != nil, true)
+}
+
+func TestPasswordHasherNeedsRehash(t *testing.T) {
+	passwordHasher, err := NewPasswordHasher(Argon2idAlgorithm, "hash_salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bcryptHash, err := NewBcryptHasher(bcrypt.MinCost).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	weakArgon2idHash, err := NewArgon2idHasher(Argon2idParams{Memory: 1024, Iterations: 1, Threads: 1, SaltLength: 16, KeyLength: 32}).Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testTable := []struct {
+		scenario     string
+		encodedHash  string
+		needsRehash  bool
+		verifiedWith string
+	}{
+		{"legacy sha1 hash", testingHashingFunc("password", "hash_salt"), true, "password"},
+		{"hash of another algorithm", bcryptHash, true, "password"},
+		{"hash with outdated parameters", weakArgon2idHash, true, "password"},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ok, err := passwordHasher.Verify(testCase.verifiedWith, testCase.encodedHash)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, ok, true)
+			assert.Equal(t, passwordHasher.NeedsRehash(testCase.encodedHash), testCase.needsRehash)
+		})
+	}
+}
+
+func TestLegacySHA1Hasher(t *testing.T) {
+	testTable := []struct {
+		scenario      string
+		salt          string
+		inputPassword string
+		expectedOk    bool
+		expectedError error
+	}{
+		{"password matches", "hash_salt", "password", true, nil},
+		{"password doesn't match", "hash_salt", "password1", false, nil},
+		{"empty password field", "hash_salt", "", false, errEmptyPassword},
+		{"empty hash salt field", "", "password", false, fmt.Errorf("empty hashSalt field")},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ok, err := NewLegacySHA1Hasher(testCase.salt).Verify(testCase.inputPassword, testingHashingFunc("password", "hash_salt"))
+			if err != nil {
+				assert.Equal(t, err, testCase.expectedError)
+				return
+			}
+			assert.Equal(t, ok, testCase.expectedOk)
+		})
+	}
+}
+
+func testingHashingFunc(password, salt string) string {
+	pwd := sha1.New()
+	pwd.Write([]byte(password))
+	pwd.Write([]byte(salt))
+	return fmt.Sprintf("%x", pwd.Sum(nil))
+}
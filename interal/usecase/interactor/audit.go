@@ -0,0 +1,68 @@
+package interactor
+
+import (
+	"context"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+)
+
+// AuditActor describes who makes a request, it is recorded in audit events.
+type AuditActor struct {
+	UserID    uint
+	ClientIP  string
+	RequestID string
+}
+
+type auditActorKey struct{}
+
+// WithAuditActor attaches the actor to the context of a mutating call.
+func WithAuditActor(ctx context.Context, actor AuditActor) context.Context {
+	return context.WithValue(ctx, auditActorKey{}, actor)
+}
+
+func auditActorFromContext(ctx context.Context) AuditActor {
+	actor, _ := ctx.Value(auditActorKey{}).(AuditActor)
+	return actor
+}
+
+// audit writes the event with the actor of ctx. Called inside
+// WithinTransaction it shares the transaction with the change, so a failed
+// audit write rolls the change back.
+func (uI *userInteractor) audit(ctx context.Context, action string, targetID uint, changes models.AuditChanges) error {
+	actor := auditActorFromContext(ctx)
+
+	if err := uI.auditRepo.CreateAuditEvent(ctx, &models.AuditEvent{
+		ActorID:   actor.UserID,
+		TargetID:  targetID,
+		Action:    action,
+		Changes:   changes,
+		ClientIP:  actor.ClientIP,
+		RequestID: actor.RequestID,
+	}); err != nil {
+		return apperrors.CanNotWriteAuditErr.AppendMessage(err)
+	}
+	return nil
+}
+
+// userChanges returns the fields which differ between before and after.
+// Password values are never recorded, only the fact of the change.
+func userChanges(before, after *models.User) models.AuditChanges {
+	changes := models.AuditChanges{}
+	addChange := func(field string, from, to interface{}) {
+		if from != to {
+			changes[field] = models.AuditChange{From: from, To: to}
+		}
+	}
+
+	addChange("user_name", before.UserName, after.UserName)
+	addChange("role", before.Role, after.Role)
+	addChange("rating", before.Rating, after.Rating)
+	addChange("first_name", before.FirstName, after.FirstName)
+	addChange("last_name", before.LastName, after.LastName)
+	if before.Password != after.Password {
+		changes["password"] = models.AuditChange{From: "***", To: "***"}
+	}
+
+	return changes
+}
@@ -0,0 +1,29 @@
+package interactor
+
+import (
+	"context"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+)
+
+type AuditInteractor interface {
+	FindAuditEvents(ctx context.Context, filter *models.AuditFilter, pagination *models.Pagination) (*models.Pagination, []*models.AuditEvent, error)
+}
+
+type auditInteractor struct {
+	auditRepo repository.AuditRepository
+}
+
+func NewAuditInteractor(auditRepo repository.AuditRepository) *auditInteractor {
+	return &auditInteractor{auditRepo}
+}
+
+func (aI *auditInteractor) FindAuditEvents(ctx context.Context, filter *models.AuditFilter, pagination *models.Pagination) (*models.Pagination, []*models.AuditEvent, error) {
+	pagination, events, err := aI.auditRepo.FindAuditEvents(ctx, filter, pagination)
+	if err != nil {
+		return nil, nil, apperrors.CanNotReadAuditErr.AppendMessage(err)
+	}
+	return pagination, events, nil
+}
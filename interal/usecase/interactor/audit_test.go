@@ -0,0 +1,129 @@
+package interactor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/gen/mocks"
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"github.com/golang/mock/gomock"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestUserChanges(t *testing.T) {
+	before := &models.User{ID: 121, UserName: "JohnHall", Role: "user", Rating: 3, FirstName: "John", LastName: "Hall", Password: "old_hash"}
+
+	testTable := []struct {
+		scenario        string
+		after           *models.User
+		expectedChanges models.AuditChanges
+	}{
+		{
+			"nothing is changed",
+			&models.User{ID: 121, UserName: "JohnHall", Role: "user", Rating: 3, FirstName: "John", LastName: "Hall", Password: "old_hash"},
+			models.AuditChanges{},
+		},
+		{
+			"names are changed",
+			&models.User{ID: 121, UserName: "JohnHall", Role: "user", Rating: 3, FirstName: "Jack", LastName: "Hill", Password: "old_hash"},
+			models.AuditChanges{
+				"first_name": {From: "John", To: "Jack"},
+				"last_name":  {From: "Hall", To: "Hill"},
+			},
+		},
+		{
+			"password values are hidden",
+			&models.User{ID: 121, UserName: "JohnHall", Role: "user", Rating: 3, FirstName: "John", LastName: "Hall", Password: "new_hash"},
+			models.AuditChanges{"password": {From: "***", To: "***"}},
+		},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			assert.Equal(t, userChanges(before, testCase.after), testCase.expectedChanges)
+		})
+	}
+}
+
+func TestUpdateIsAuditedWithActor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo:   userRepoMock,
+		auditRepo:  auditRepoMock,
+		transactor: newTestTransactor(ctrl),
+	}
+
+	ctx := WithAuditActor(context.Background(), AuditActor{UserID: 1, ClientIP: "10.0.0.1", RequestID: "request-id"})
+	before := &models.User{ID: 121, UserName: "JohnHall", Role: "user", FirstName: "John"}
+	after := &models.User{ID: 121, UserName: "JohnHall", Role: "user", FirstName: "Jack"}
+
+	userRepoMock.EXPECT().FindOneUserByID(ctx, uint(121)).Return(before, nil)
+	userRepoMock.EXPECT().UpdateUserByID(ctx, 121, gomock.Any()).Return(after, nil)
+	auditRepoMock.EXPECT().CreateAuditEvent(ctx, &models.AuditEvent{
+		ActorID:   1,
+		TargetID:  121,
+		Action:    models.AuditActionUserUpdate,
+		Changes:   models.AuditChanges{"first_name": {From: "John", To: "Jack"}},
+		ClientIP:  "10.0.0.1",
+		RequestID: "request-id",
+	}).Return(nil)
+
+	user, err := uInteractor.UpdateSignersByID(ctx, 121, &models.User{FirstName: "Jack"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, user, after)
+}
+
+func TestFailedAuditFailsTheChange(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo:   userRepoMock,
+		auditRepo:  auditRepoMock,
+		transactor: newTestTransactor(ctrl),
+		roles:      permissions.DefaultRoles(),
+	}
+
+	ctx := context.Background()
+	userRepoMock.EXPECT().FindOneUserByID(ctx, uint(121)).Return(&models.User{ID: 121, Role: permissions.UserRole}, nil)
+	userRepoMock.EXPECT().DeleteUserByID(ctx, 121).Return(nil)
+	auditRepoMock.EXPECT().CreateAuditEvent(ctx, gomock.Any()).Return(errors.New("audit_events table is missing"))
+
+	err := uInteractor.DeleteSignerByID(ctx, 121)
+	assert.Equal(t, apperrors.Is(err, &apperrors.CanNotWriteAuditErr), true)
+}
+
+func TestFindAuditEvents(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	aInteractor := NewAuditInteractor(auditRepoMock)
+
+	ctx := context.Background()
+	filter := &models.AuditFilter{ActorID: 1}
+	pagination := &models.Pagination{Limit: 5, Page: 1, Sort: "id desc"}
+	events := []*models.AuditEvent{{ID: 1, ActorID: 1, TargetID: 121, Action: models.AuditActionUserDelete}}
+
+	auditRepoMock.EXPECT().FindAuditEvents(ctx, filter, pagination).Return(pagination, events, nil)
+	_, foundEvents, err := aInteractor.FindAuditEvents(ctx, filter, pagination)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, foundEvents, events)
+
+	auditRepoMock.EXPECT().FindAuditEvents(ctx, filter, pagination).Return(nil, nil, errors.New("db is down"))
+	_, _, err = aInteractor.FindAuditEvents(ctx, filter, pagination)
+	assert.Equal(t, apperrors.Is(err, &apperrors.CanNotReadAuditErr), true)
+}
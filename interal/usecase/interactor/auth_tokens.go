@@ -0,0 +1,248 @@
+package interactor
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/signer"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// AuthTokens is a pair of a short-lived access token (JWT) and a long-lived
+// opaque refresh token, together with their lifetimes in seconds.
+type AuthTokens struct {
+	AccessToken     string
+	AccessTokenTTL  int
+	RefreshToken    string
+	RefreshTokenTTL int
+}
+
+// RefreshTokens rotates the given refresh token: it is revoked and a new
+// access/refresh pair of the same family is issued. When an already rotated
+// token is presented again, it is considered stolen and the whole family is
+// revoked, so neither the thief nor the owner can keep using it.
+func (uI *userInteractor) RefreshTokens(ctx context.Context, refreshToken string) (*AuthTokens, error) {
+	if refreshToken == "" {
+		return nil, apperrors.InvalidRefreshTokenErr.AppendMessage(errors.New("empty refresh token"))
+	}
+
+	oldToken, err := uI.refreshTokenRepo.FindRefreshTokenByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, apperrors.InvalidRefreshTokenErr.AppendMessage(err)
+	}
+
+	if oldToken.RevokedAt != nil {
+		return nil, uI.revokeRefreshTokenFamily(ctx, oldToken.FamilyID)
+	}
+
+	if time.Now().After(oldToken.ExpiresAt) {
+		return nil, apperrors.InvalidRefreshTokenErr.AppendMessage(errors.New("refresh token is expired"))
+	}
+
+	user, err := uI.userRepo.FindOneUserByID(ctx, oldToken.UserID)
+	if err != nil {
+		return nil, apperrors.InvalidRefreshTokenErr.AppendMessage(err)
+	}
+
+	rawToken, newToken, err := uI.newRefreshToken(user.ID, oldToken.FamilyID)
+	if err != nil {
+		return nil, apperrors.CanNotCreateTokenErr.AppendMessage(err)
+	}
+
+	if err := uI.refreshTokenRepo.RotateRefreshToken(ctx, oldToken.ID, newToken); err != nil {
+		if errors.Is(err, &apperrors.RefreshTokenReuseErr) {
+			return nil, uI.revokeRefreshTokenFamily(ctx, oldToken.FamilyID)
+		}
+		return nil, apperrors.CanNotCreateTokenErr.AppendMessage(err)
+	}
+
+	accessToken, err := uI.makeSignedToken(user)
+	if err != nil {
+		return nil, apperrors.CanNotCreateTokenErr.AppendMessage(err)
+	}
+
+	return &AuthTokens{
+		AccessToken:     accessToken,
+		AccessTokenTTL:  uI.expireDuration,
+		RefreshToken:    rawToken,
+		RefreshTokenTTL: uI.refreshExpireDuration,
+	}, nil
+}
+
+// ParseToken verifies the access token and rejects it when it was revoked by
+// a logout or by revoking all sessions of its user. The token's user is
+// loaded from the repository (or the short-lived user cache) into
+// AuthClaims.User, so a deleted user is rejected and the role is up to date.
+// A token which doesn't verify is InvalidTokenErr, only a failure of the
+// revocation store is a server error.
+func (uI *userInteractor) ParseToken(ctx context.Context, tokenString string) (*jwt.Token, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &AuthClaims{}, uI.keySet.Keyfunc)
+	if err != nil {
+		return nil, apperrors.InvalidTokenErr.AppendMessage(err)
+	}
+
+	claims := token.Claims.(*AuthClaims)
+	if uI.issuer != "" && !claims.VerifyIssuer(uI.issuer, true) {
+		return nil, apperrors.InvalidTokenErr.AppendMessage(fmt.Errorf("unexpected jwt issuer=%v", claims.Issuer))
+	}
+	if uI.audience != "" && !claims.VerifyAudience(uI.audience, true) {
+		return nil, apperrors.InvalidTokenErr.AppendMessage(fmt.Errorf("unexpected jwt audience=%v", claims.Audience))
+	}
+	if claims.IssuedAt == nil {
+		return nil, apperrors.InvalidTokenErr.AppendMessage(errors.New("token has no issued at claim"))
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 0)
+	if err != nil {
+		return nil, apperrors.InvalidTokenErr.AppendMessage(err)
+	}
+
+	revoked, err := uI.revocationStore.IsRevoked(ctx, claims.ID, uint(userID), claims.IssuedAt.Time)
+	if err != nil {
+		return nil, apperrors.CanNotParseTokenErr.AppendMessage(err)
+	}
+	if revoked {
+		return nil, &apperrors.TokenRevokedErr
+	}
+
+	user, err := uI.findAuthUser(ctx, uint(userID))
+	if err != nil {
+		return nil, apperrors.InvalidTokenErr.AppendMessage(err)
+	}
+	claims.User = user
+	claims.Role = user.Role
+
+	return token, nil
+}
+
+// SignOut revokes the access token and, when it is known, the refresh token
+// family of this sign in.
+func (uI *userInteractor) SignOut(ctx context.Context, claims *AuthClaims, refreshToken string) error {
+	if err := uI.revocationStore.RevokeToken(ctx, claims.ID, claims.User.ID, claims.ExpiresAt.Time); err != nil {
+		return apperrors.CanNotRevokeTokenErr.AppendMessage(err)
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	token, err := uI.refreshTokenRepo.FindRefreshTokenByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil || token.UserID != claims.User.ID {
+		return nil
+	}
+
+	if err := uI.refreshTokenRepo.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return apperrors.CanNotRevokeTokenErr.AppendMessage(err)
+	}
+	return nil
+}
+
+// RevokeUserSessions rejects every access token issued to the user so far and
+// revokes all of the user's refresh tokens.
+func (uI *userInteractor) RevokeUserSessions(ctx context.Context, userID uint) error {
+	uI.userCache.invalidate(userID)
+
+	if err := uI.revocationStore.RevokeUserTokens(ctx, userID, time.Now()); err != nil {
+		return apperrors.CanNotRevokeTokenErr.AppendMessage(err)
+	}
+
+	if err := uI.refreshTokenRepo.RevokeUserRefreshTokens(ctx, userID); err != nil {
+		return apperrors.CanNotRevokeTokenErr.AppendMessage(err)
+	}
+	return nil
+}
+
+// JWKS returns the public keys which verify access tokens, so other services
+// can check them without the signing secret.
+func (uI *userInteractor) JWKS() signer.JWKS {
+	return uI.keySet.JWKS()
+}
+
+// issueTokens starts a new refresh token family, it is used on sign in/up.
+func (uI *userInteractor) issueTokens(ctx context.Context, user *models.User) (*AuthTokens, error) {
+	accessToken, err := uI.makeSignedToken(user)
+	if err != nil {
+		return nil, apperrors.CanNotCreateTokenErr.AppendMessage(err)
+	}
+
+	familyID, err := randomString(16)
+	if err != nil {
+		return nil, apperrors.CanNotCreateTokenErr.AppendMessage(err)
+	}
+
+	rawToken, refreshToken, err := uI.newRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, apperrors.CanNotCreateTokenErr.AppendMessage(err)
+	}
+
+	if err := uI.refreshTokenRepo.CreateRefreshToken(ctx, refreshToken); err != nil {
+		return nil, apperrors.CanNotCreateTokenErr.AppendMessage(err)
+	}
+
+	return &AuthTokens{
+		AccessToken:     accessToken,
+		AccessTokenTTL:  uI.expireDuration,
+		RefreshToken:    rawToken,
+		RefreshTokenTTL: uI.refreshExpireDuration,
+	}, nil
+}
+
+func (uI *userInteractor) findAuthUser(ctx context.Context, userID uint) (*models.User, error) {
+	if user, ok := uI.userCache.get(userID); ok {
+		return user, nil
+	}
+
+	generation := uI.userCache.generation(userID)
+	user, err := uI.userRepo.FindOneUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	uI.userCache.set(user, generation)
+	return user, nil
+}
+
+func (uI *userInteractor) newRefreshToken(userID uint, familyID string) (string, *models.RefreshToken, error) {
+	rawToken, err := randomString(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return rawToken, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashRefreshToken(rawToken),
+		ExpiresAt: time.Now().Add(time.Second * time.Duration(uI.refreshExpireDuration)),
+	}, nil
+}
+
+func (uI *userInteractor) revokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	if err := uI.refreshTokenRepo.RevokeRefreshTokenFamily(ctx, familyID); err != nil {
+		return apperrors.RefreshTokenReuseErr.AppendMessage(err)
+	}
+	return &apperrors.RefreshTokenReuseErr
+}
+
+// hashRefreshToken is a plain SHA-256: refresh tokens are long random strings,
+// so unlike passwords they don't need a slow salted hash.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,437 @@
+package interactor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/gen/mocks"
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/golang/mock/gomock"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestRefreshTokens(t *testing.T) {
+	now := time.Now()
+	user := &models.User{ID: 121, UserName: "JohnHall", Role: "user"}
+
+	activeToken := func() *models.RefreshToken {
+		return &models.RefreshToken{
+			ID:        7,
+			UserID:    user.ID,
+			FamilyID:  "family",
+			TokenHash: hashRefreshToken("refresh_token"),
+			ExpiresAt: now.Add(time.Hour),
+		}
+	}
+
+	revokedToken := activeToken()
+	revokedToken.RevokedAt = &now
+
+	expiredToken := activeToken()
+	expiredToken.ExpiresAt = now.Add(-time.Hour)
+
+	testTable := []struct {
+		scenario      string
+		inputToken    string
+		foundToken    *models.RefreshToken
+		findError     error
+		rotateError   error
+		expectRotate  bool
+		expectRevoke  bool
+		expectedError error
+	}{
+		{
+			"refresh token is rotated",
+			"refresh_token",
+			activeToken(),
+			nil,
+			nil,
+			true,
+			false,
+			nil,
+		},
+		{
+			"already rotated token revokes the family",
+			"refresh_token",
+			revokedToken,
+			nil,
+			nil,
+			false,
+			true,
+			&apperrors.RefreshTokenReuseErr,
+		},
+		{
+			"token rotated concurrently revokes the family",
+			"refresh_token",
+			activeToken(),
+			nil,
+			&apperrors.RefreshTokenReuseErr,
+			true,
+			true,
+			&apperrors.RefreshTokenReuseErr,
+		},
+		{
+			"expired token",
+			"refresh_token",
+			expiredToken,
+			nil,
+			nil,
+			false,
+			false,
+			&apperrors.InvalidRefreshTokenErr,
+		},
+		{
+			"unknown token",
+			"unknown_token",
+			nil,
+			errors.New("record not found"),
+			nil,
+			false,
+			false,
+			&apperrors.InvalidRefreshTokenErr,
+		},
+		{
+			"empty token",
+			"",
+			nil,
+			nil,
+			nil,
+			false,
+			false,
+			&apperrors.InvalidRefreshTokenErr,
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:              userRepoMock,
+				refreshTokenRepo:      refreshTokenRepoMock,
+				keySet:                newTestKeySet(t, "signing_key"),
+				expireDuration:        1,
+				refreshExpireDuration: 2,
+			}
+
+			if testCase.inputToken != "" {
+				refreshTokenRepoMock.EXPECT().FindRefreshTokenByHash(ctx, hashRefreshToken(testCase.inputToken)).
+					Return(testCase.foundToken, testCase.findError)
+			}
+
+			var rotatedToken *models.RefreshToken
+			if testCase.expectRotate {
+				userRepoMock.EXPECT().FindOneUserByID(ctx, user.ID).Return(user, nil)
+				refreshTokenRepoMock.EXPECT().RotateRefreshToken(ctx, testCase.foundToken.ID, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uint, newToken *models.RefreshToken) error {
+						rotatedToken = newToken
+						return testCase.rotateError
+					})
+			}
+
+			if testCase.expectRevoke {
+				refreshTokenRepoMock.EXPECT().RevokeRefreshTokenFamily(ctx, testCase.foundToken.FamilyID).Return(nil)
+			}
+
+			tokens, err := uInteractor.RefreshTokens(ctx, testCase.inputToken)
+			if err != nil {
+
+				if testCase.expectedError != nil && apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)) {
+					return
+				}
+
+				t.Fatal(err)
+			}
+
+			assert.Equal(t, rotatedToken.FamilyID, testCase.foundToken.FamilyID)
+			assert.Equal(t, rotatedToken.UserID, user.ID)
+			assert.Equal(t, rotatedToken.TokenHash, hashRefreshToken(tokens.RefreshToken))
+			assert.Equal(t, tokens.RefreshTokenTTL, 2)
+			assert.Equal(t, tokens.AccessTokenTTL, 1)
+		})
+	}
+}
+
+func TestParseToken(t *testing.T) {
+	user := &models.User{ID: 121, UserName: "JohnHall", Role: "admin"}
+	demotedUser := &models.User{ID: 121, UserName: "JohnHall", Role: "user"}
+
+	testTable := []struct {
+		scenario      string
+		signingKey    string
+		issuer        string
+		revoked       bool
+		storedUser    *models.User
+		findError     error
+		expectedRole  string
+		expectedError error
+	}{
+		{
+			"valid token",
+			"signing_key",
+			"issuer",
+			false,
+			user,
+			nil,
+			"admin",
+			nil,
+		},
+		{
+			"role is re-read from the repository",
+			"signing_key",
+			"issuer",
+			false,
+			demotedUser,
+			nil,
+			"user",
+			nil,
+		},
+		{
+			"user was deleted",
+			"signing_key",
+			"issuer",
+			false,
+			nil,
+			errors.New("record not found"),
+			"",
+			&apperrors.InvalidTokenErr,
+		},
+		{
+			"revoked token",
+			"signing_key",
+			"issuer",
+			true,
+			user,
+			nil,
+			"",
+			&apperrors.TokenRevokedErr,
+		},
+		{
+			"token signed with another key",
+			"another_key",
+			"issuer",
+			false,
+			user,
+			nil,
+			"",
+			&apperrors.InvalidTokenErr,
+		},
+		{
+			"token of another issuer",
+			"signing_key",
+			"another_issuer",
+			false,
+			user,
+			nil,
+			"",
+			&apperrors.InvalidTokenErr,
+		},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			revocationStoreMock := mocks.NewMockRevocationStore(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:        userRepoMock,
+				revocationStore: revocationStoreMock,
+				keySet:          newTestKeySet(t, "signing_key"),
+				issuer:          "issuer",
+				audience:        "audience",
+				expireDuration:  10,
+			}
+
+			tokenString, err := (&userInteractor{
+				keySet:         newTestKeySet(t, testCase.signingKey),
+				issuer:         testCase.issuer,
+				audience:       "audience",
+				expireDuration: 10,
+			}).makeSignedToken(user)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			revocationStoreMock.EXPECT().IsRevoked(ctx, gomock.Any(), user.ID, gomock.Any()).Return(testCase.revoked, nil).AnyTimes()
+			userRepoMock.EXPECT().FindOneUserByID(ctx, user.ID).Return(testCase.storedUser, testCase.findError).AnyTimes()
+
+			token, err := uInteractor.ParseToken(ctx, tokenString)
+			if err != nil {
+
+				if testCase.expectedError != nil && apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)) {
+					return
+				}
+
+				t.Fatal(err)
+			}
+
+			claims := token.Claims.(*AuthClaims)
+			assert.Equal(t, claims.User.ID, user.ID)
+			assert.Equal(t, claims.User.Role, testCase.expectedRole)
+			assert.Equal(t, claims.Role, testCase.expectedRole)
+		})
+	}
+}
+
+func TestParseTokenUsesUserCache(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	user := &models.User{ID: 121, UserName: "JohnHall", Role: "admin"}
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	revocationStoreMock := mocks.NewMockRevocationStore(ctrl)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo:         userRepoMock,
+		refreshTokenRepo: refreshTokenRepoMock,
+		revocationStore:  revocationStoreMock,
+		keySet:           newTestKeySet(t, "signing_key"),
+		expireDuration:   10,
+		userCache:        newUserCache(time.Minute),
+	}
+
+	tokenString, err := uInteractor.makeSignedToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	revocationStoreMock.EXPECT().IsRevoked(ctx, gomock.Any(), user.ID, gomock.Any()).Return(false, nil).AnyTimes()
+	userRepoMock.EXPECT().FindOneUserByID(ctx, user.ID).Return(user, nil).Times(2)
+
+	for i := 0; i < 3; i++ {
+		if _, err := uInteractor.ParseToken(ctx, tokenString); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	revocationStoreMock.EXPECT().RevokeUserTokens(ctx, user.ID, gomock.Any()).Return(nil)
+	refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, user.ID).Return(nil)
+	if err := uInteractor.RevokeUserSessions(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := uInteractor.ParseToken(ctx, tokenString); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUserCacheDropsReadsRacingWithAnUpdate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	stored := models.User{ID: 121, UserName: "JohnHall", Role: permissions.AdminRole}
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo:   userRepoMock,
+		auditRepo:  newTestAuditRepository(ctrl),
+		transactor: newTestTransactor(ctrl),
+		roles:      permissions.DefaultRoles(),
+		userCache:  newUserCache(time.Minute),
+	}
+
+	racing := true
+	userRepoMock.EXPECT().FindOneUserByID(ctx, stored.ID).
+		DoAndReturn(func(ctx context.Context, id uint) (*models.User, error) {
+			user := stored
+			if racing {
+				// the demotion commits after the authenticated request has
+				// read the row, before it caches the row
+				racing = false
+				if _, err := uInteractor.AssignRole(ctx, 1, id, permissions.UserRole); err != nil {
+					t.Fatal(err)
+				}
+			}
+			return &user, nil
+		}).Times(3)
+	userRepoMock.EXPECT().UpdateUserRole(ctx, stored.ID, permissions.UserRole).
+		DoAndReturn(func(_ context.Context, _ uint, role string) error {
+			stored.Role = role
+			return nil
+		})
+
+	user, err := uInteractor.findAuthUser(ctx, stored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, user.Role, permissions.AdminRole)
+
+	_, cached := uInteractor.userCache.get(stored.ID)
+	assert.Equal(t, cached, false, "the row read before the demotion must not be cached")
+
+	user, err = uInteractor.findAuthUser(ctx, stored.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, user.Role, permissions.UserRole)
+}
+
+func TestRevokeUserSessionsRejectsTokensOfTheSameSecond(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	user := &models.User{ID: 121, UserName: "JohnHall", Role: "admin"}
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	uInteractor := &userInteractor{
+		refreshTokenRepo: refreshTokenRepoMock,
+		revocationStore:  repository.NewInMemoryRevocationStore(),
+		keySet:           newTestKeySet(t, "signing_key"),
+		expireDuration:   10,
+		userCache:        newUserCache(0),
+	}
+
+	tokenString, err := uInteractor.makeSignedToken(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, user.ID).Return(nil)
+	if err := uInteractor.RevokeUserSessions(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = uInteractor.ParseToken(ctx, tokenString)
+	assert.Equal(t, apperrors.Is(err, &apperrors.TokenRevokedErr), true)
+}
+
+func TestSignOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	revocationStoreMock := mocks.NewMockRevocationStore(ctrl)
+	uInteractor := &userInteractor{
+		refreshTokenRepo: refreshTokenRepoMock,
+		revocationStore:  revocationStoreMock,
+	}
+
+	expiresAt := time.Now().Add(time.Minute).Truncate(time.Second)
+	claims := &AuthClaims{User: &models.User{ID: 121}}
+	claims.ID = "jti"
+	claims.ExpiresAt = jwt.NewNumericDate(expiresAt)
+
+	revocationStoreMock.EXPECT().RevokeToken(ctx, "jti", uint(121), expiresAt).Return(nil)
+	refreshTokenRepoMock.EXPECT().FindRefreshTokenByHash(ctx, hashRefreshToken("refresh_token")).
+		Return(&models.RefreshToken{ID: 1, UserID: 121, FamilyID: "family"}, nil)
+	refreshTokenRepoMock.EXPECT().RevokeRefreshTokenFamily(ctx, "family").Return(nil)
+
+	if err := uInteractor.SignOut(ctx, claims, "refresh_token"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+package interactor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+)
+
+// cursorCodec turns cursors into opaque tokens, "<payload>.<mac>" in
+// unpadded base64url, where the payload is the JSON of the cursor and the mac
+// its HMAC-SHA256. Clients can't read the rows of a cursor they made up.
+type cursorCodec struct {
+	key []byte
+}
+
+func (cc cursorCodec) encode(cursor *models.Cursor) (string, error) {
+	payload, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(cc.mac(payload)), nil
+}
+
+func (cc cursorCodec) decode(token string) (*models.Cursor, error) {
+	encodedPayload, encodedMAC, found := strings.Cut(token, ".")
+	if !found {
+		return nil, errors.New("the cursor isn't \"<payload>.<mac>\"")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, err
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(mac, cc.mac(payload)) {
+		return nil, errors.New("the cursor signature doesn't match")
+	}
+
+	var cursor models.Cursor
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+func (cc cursorCodec) mac(payload []byte) []byte {
+	h := hmac.New(sha256.New, cc.key)
+	h.Write(payload)
+	return h.Sum(nil)
+}
@@ -0,0 +1,103 @@
+package interactor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+)
+
+// purgeBatchSize is how many deleted users PurgeDeletedSigners looks up at
+// once.
+const purgeBatchSize = 100
+
+// The DELETED_USERNAMES policies.
+const (
+	ReserveDeletedUserNames = "reserve"
+	FreeDeletedUserNames    = "free"
+)
+
+// DeletionOptions decide what happens to the usernames of deleted users.
+type DeletionOptions struct {
+	// ReleaseUserNames frees the usernames of deleted users for new users,
+	// otherwise they stay taken until the users are purged. A restored user
+	// takes the username back, unless somebody has taken it meanwhile.
+	ReleaseUserNames bool
+}
+
+func (uI *userInteractor) releaseUserName(ctx context.Context, id uint) error {
+	if !uI.deletion.ReleaseUserNames {
+		return nil
+	}
+	if err := uI.userRepo.ReleaseUserName(ctx, id); err != nil {
+		return apperrors.CanNotDeleteUserErr.AppendMessage(err)
+	}
+	return nil
+}
+
+func (uI *userInteractor) FindDeletedSigners(ctx context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+	pagination, users, err := uI.userRepo.FindDeletedUsers(ctx, pagination)
+	if err != nil {
+		return nil, nil, apperrors.PaginationErr.AppendMessage(err)
+	}
+	return pagination, users, nil
+}
+
+// RestoreSigner returns UserNotFoundErr when the user isn't deleted, and
+// UsernameTakenErr when the freed username has been taken.
+func (uI *userInteractor) RestoreSigner(ctx context.Context, id uint) (*models.User, error) {
+	var user *models.User
+	err := uI.withinTransaction(ctx, &apperrors.CanNotRestoreUserErr, func(ctx context.Context) error {
+		var err error
+		user, err = uI.userRepo.RestoreUser(ctx, id)
+		if err != nil {
+			if errors.Is(err, &apperrors.UserNotFoundErr) || errors.Is(err, &apperrors.UsernameTakenErr) {
+				return err
+			}
+			return apperrors.CanNotRestoreUserErr.AppendMessage(err)
+		}
+		return uI.audit(ctx, models.AuditActionUserRestore, id, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	uI.userCache.invalidate(id)
+	return user, nil
+}
+
+// PurgeSigner deletes a deleted user for good, it returns UserNotFoundErr
+// when the user isn't deleted.
+func (uI *userInteractor) PurgeSigner(ctx context.Context, id uint) error {
+	return uI.withinTransaction(ctx, &apperrors.CanNotPurgeUserErr, func(ctx context.Context) error {
+		if err := uI.userRepo.PurgeUser(ctx, id); err != nil {
+			if errors.Is(err, &apperrors.UserNotFoundErr) {
+				return err
+			}
+			return apperrors.CanNotPurgeUserErr.AppendMessage(err)
+		}
+		return uI.audit(ctx, models.AuditActionUserPurge, id, nil)
+	})
+}
+
+// PurgeDeletedSigners purges the users deleted before deletedBefore, each in
+// a transaction of its own, and returns how many it has purged.
+func (uI *userInteractor) PurgeDeletedSigners(ctx context.Context, deletedBefore time.Time) (int, error) {
+	purged := 0
+	for {
+		ids, err := uI.userRepo.FindUserIDsDeletedBefore(ctx, deletedBefore, purgeBatchSize)
+		if err != nil {
+			return purged, apperrors.CanNotPurgeUserErr.AppendMessage(err)
+		}
+		for _, id := range ids {
+			if err := uI.PurgeSigner(ctx, id); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(ids) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package interactor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/gen/mocks"
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"github.com/golang/mock/gomock"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestDeleteSignerReleasesUserName(t *testing.T) {
+	testTable := []struct {
+		scenario string
+		options  DeletionOptions
+	}{
+		{"usernames are reserved", DeletionOptions{}},
+		{"usernames are freed", DeletionOptions{ReleaseUserNames: true}},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+			revocationStoreMock := mocks.NewMockRevocationStore(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:         userRepoMock,
+				refreshTokenRepo: refreshTokenRepoMock,
+				auditRepo:        newTestAuditRepository(ctrl),
+				transactor:       newTestTransactor(ctrl),
+				revocationStore:  revocationStoreMock,
+				roles:            permissions.DefaultRoles(),
+				deletion:         testCase.options,
+			}
+
+			userRepoMock.EXPECT().FindOneUserByID(ctx, uint(121)).Return(&models.User{ID: 121, Role: permissions.UserRole}, nil)
+			userRepoMock.EXPECT().DeleteUserByID(ctx, 121).Return(nil)
+			if testCase.options.ReleaseUserNames {
+				userRepoMock.EXPECT().ReleaseUserName(ctx, uint(121)).Return(nil)
+			}
+			revocationStoreMock.EXPECT().RevokeUserTokens(ctx, uint(121), gomock.Any()).Return(nil)
+			refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, uint(121)).Return(nil)
+
+			if err := uInteractor.DeleteSignerByID(ctx, 121); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestRestoreSigner(t *testing.T) {
+	testTable := []struct {
+		scenario      string
+		restoreError  error
+		expectedError *apperrors.AppError
+	}{
+		{"user is restored", nil, nil},
+		{"user isn't deleted", apperrors.UserNotFoundErr.AppendMessage(errors.New("record not found")), &apperrors.UserNotFoundErr},
+		{"username is taken", apperrors.UsernameTakenErr.AppendMessage(errors.New("duplicate")), &apperrors.UsernameTakenErr},
+		{"can't restore the user", errors.New("db is down"), &apperrors.CanNotRestoreUserErr},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:   userRepoMock,
+				auditRepo:  newTestAuditRepository(ctrl),
+				transactor: newTestTransactor(ctrl),
+			}
+
+			if testCase.restoreError != nil {
+				userRepoMock.EXPECT().RestoreUser(ctx, uint(7)).Return(nil, testCase.restoreError)
+			} else {
+				userRepoMock.EXPECT().RestoreUser(ctx, uint(7)).Return(&models.User{ID: 7, UserName: "JohnHall"}, nil)
+			}
+
+			user, err := uInteractor.RestoreSigner(ctx, 7)
+			if testCase.expectedError != nil {
+				assert.Equal(t, apperrors.Is(err, testCase.expectedError), true)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, user.ID, uint(7))
+		})
+	}
+}
+
+func TestPurgeDeletedSigners(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo:   userRepoMock,
+		auditRepo:  newTestAuditRepository(ctrl),
+		transactor: newTestTransactor(ctrl),
+	}
+
+	deletedBefore := time.Now().AddDate(0, 0, -30)
+	fullBatch := make([]uint, purgeBatchSize)
+	for i := range fullBatch {
+		fullBatch[i] = uint(i + 1)
+	}
+	gomock.InOrder(
+		userRepoMock.EXPECT().FindUserIDsDeletedBefore(ctx, deletedBefore, purgeBatchSize).Return(fullBatch, nil),
+		userRepoMock.EXPECT().FindUserIDsDeletedBefore(ctx, deletedBefore, purgeBatchSize).Return([]uint{purgeBatchSize + 1}, nil),
+	)
+	userRepoMock.EXPECT().PurgeUser(ctx, gomock.Any()).Return(nil).Times(purgeBatchSize + 1)
+
+	purged, err := uInteractor.PurgeDeletedSigners(ctx, deletedBefore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, purged, purgeBatchSize+1)
+
+	t.Run("stops at the first failure", func(t *testing.T) {
+		userRepoMock.EXPECT().FindUserIDsDeletedBefore(ctx, deletedBefore, purgeBatchSize).Return([]uint{1, 2}, nil)
+		userRepoMock.EXPECT().PurgeUser(ctx, uint(1)).Return(errors.New("db is down"))
+
+		purged, err := uInteractor.PurgeDeletedSigners(ctx, deletedBefore)
+		assert.Equal(t, purged, 0)
+		assert.Equal(t, apperrors.Is(err, &apperrors.CanNotPurgeUserErr), true)
+	})
+}
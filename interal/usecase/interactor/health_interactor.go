@@ -0,0 +1,60 @@
+package interactor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+)
+
+const (
+	HealthStatusOK      = "ok"
+	HealthStatusFailing = "failing"
+)
+
+// HealthCheck returns an error when a dependency of the service isn't usable.
+type HealthCheck func(ctx context.Context) error
+
+type HealthInteractor interface {
+	CheckReadiness(ctx context.Context) (map[string]string, error)
+}
+
+type healthInteractor struct {
+	checks map[string]HealthCheck
+}
+
+func NewHealthInteractor(checks map[string]HealthCheck) *healthInteractor {
+	return &healthInteractor{checks}
+}
+
+// CheckReadiness runs every check and returns their statuses. When any of
+// them fails it returns NotReadyErr with the statuses in its details, the
+// errors themselves are only wrapped, as they aren't meant for clients.
+func (hI *healthInteractor) CheckReadiness(ctx context.Context) (map[string]string, error) {
+	names := make([]string, 0, len(hI.checks))
+	for name := range hI.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := make(map[string]string, len(hI.checks))
+	var errs []error
+	for _, name := range names {
+		if err := hI.checks[name](ctx); err != nil {
+			statuses[name] = HealthStatusFailing
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		statuses[name] = HealthStatusOK
+	}
+
+	if len(errs) > 0 {
+		notReady := apperrors.NotReadyErr.WithDetail("checks", statuses)
+		for _, err := range errs {
+			notReady = notReady.Wrap(err)
+		}
+		return statuses, notReady
+	}
+	return statuses, nil
+}
@@ -0,0 +1,118 @@
+package interactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
+)
+
+// LockoutOptions configures sign in limits. UserLimiter limits attempts per
+// username; after Threshold failed attempts in a row the account is locked
+// for Duration, doubled with every further failure up to MaxDuration.
+// A zero Threshold disables the lockout.
+type LockoutOptions struct {
+	UserLimiter *ratelimit.Limiter
+	Threshold   int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// checkSignInAllowed rejects the attempt when the username is rate limited.
+func (uI *userInteractor) checkSignInAllowed(ctx context.Context, username string) error {
+	wait, err := uI.signInLimiter.Allow(ctx, signInLimiterKey(username))
+	if err != nil {
+		return apperrors.RateLimitErr.AppendMessage(err)
+	}
+	if wait > 0 {
+		return apperrors.TooManyAttemptsErr.WithRetryAfter(wait).WithDetail("limit", uI.signInLimiter.Limit())
+	}
+	return nil
+}
+
+// checkNotLocked rejects a locked account like a wrong password, after as
+// long a password check, so the answer doesn't tell that the username exists.
+func (uI *userInteractor) checkNotLocked(ctx context.Context, user *models.User, password string) error {
+	if uI.lockout.Threshold <= 0 {
+		return nil
+	}
+
+	attempts, err := uI.loginAttemptRepo.FindLoginAttempts(ctx, user.ID)
+	if err != nil {
+		return apperrors.DatabaseErr.AppendMessage(err)
+	}
+	if attempts.LockedUntil != nil {
+		if wait := time.Until(*attempts.LockedUntil); wait > 0 {
+			uI.passwordHasher.VerifyDummy(password)
+			return apperrors.InvalidCredentialsErr.AppendMessage(fmt.Errorf("the account is locked for %s", wait.Round(time.Second)))
+		}
+	}
+	return nil
+}
+
+// recordFailedLogin counts the failure and locks the account once there are
+// too many failures in a row. It returns the error to report for the attempt,
+// InvalidCredentialsErr whether or not the account got locked.
+func (uI *userInteractor) recordFailedLogin(ctx context.Context, user *models.User) error {
+	attempts, err := uI.loginAttemptRepo.RecordFailedLogin(ctx, user.ID)
+	if err != nil {
+		return apperrors.DatabaseErr.AppendMessage(err)
+	}
+
+	if uI.lockout.Threshold > 0 && attempts.ConsecutiveFailures >= uI.lockout.Threshold {
+		duration := uI.lockoutDuration(attempts.ConsecutiveFailures)
+		if err := uI.loginAttemptRepo.LockUser(ctx, user.ID, time.Now().Add(duration)); err != nil {
+			return apperrors.DatabaseErr.AppendMessage(err)
+		}
+		return apperrors.InvalidCredentialsErr.AppendMessage(fmt.Errorf("wrong password, the account is locked for %s", duration))
+	}
+
+	return apperrors.InvalidCredentialsErr.AppendMessage(errors.New("wrong password"))
+}
+
+// signInLimiterKey is the same for every letter case of a username, as the
+// username lookups are.
+func signInLimiterKey(username string) string {
+	return strings.ToLower(username)
+}
+
+func (uI *userInteractor) lockoutDuration(consecutiveFailures int) time.Duration {
+	duration := uI.lockout.Duration
+	for i := uI.lockout.Threshold; i < consecutiveFailures; i++ {
+		duration *= 2
+		if uI.lockout.MaxDuration > 0 && duration >= uI.lockout.MaxDuration {
+			return uI.lockout.MaxDuration
+		}
+	}
+	return duration
+}
+
+// UnlockUser lifts the lockout and the username rate limit of the user.
+func (uI *userInteractor) UnlockUser(ctx context.Context, id uint) error {
+	var user *models.User
+	err := uI.withinTransaction(ctx, &apperrors.CanNotUnlockUserErr, func(ctx context.Context) error {
+		var err error
+		user, err = uI.userRepo.FindOneUserByID(ctx, id)
+		if err != nil {
+			return userLookupErr(err)
+		}
+
+		if err := uI.loginAttemptRepo.UnlockUser(ctx, id); err != nil {
+			return apperrors.CanNotUnlockUserErr.AppendMessage(err)
+		}
+		return uI.audit(ctx, models.AuditActionUserUnlock, id, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := uI.signInLimiter.Reset(ctx, signInLimiterKey(user.UserName)); err != nil {
+		return apperrors.CanNotUnlockUserErr.AppendMessage(err)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package interactor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/gen/mocks"
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
+	"github.com/golang/mock/gomock"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestSignInLockout(t *testing.T) {
+	lockedUntil := time.Now().Add(time.Minute)
+	expiredLock := time.Now().Add(-time.Minute)
+
+	testTable := []struct {
+		scenario            string
+		inputPassword       string
+		storedAttempts      *models.LoginAttempts
+		consecutiveFailures int
+		expectLock          time.Duration
+		expectedError       error
+	}{
+		{
+			"locked account is rejected like a wrong password",
+			"1234",
+			&models.LoginAttempts{UserID: 121, ConsecutiveFailures: 3, LockedUntil: &lockedUntil},
+			0,
+			0,
+			&apperrors.InvalidCredentialsErr,
+		},
+		{
+			"failure below the threshold",
+			"4321",
+			&models.LoginAttempts{UserID: 121},
+			2,
+			0,
+			&apperrors.InvalidCredentialsErr,
+		},
+		{
+			"failure at the threshold locks the account",
+			"4321",
+			&models.LoginAttempts{UserID: 121},
+			3,
+			time.Minute,
+			&apperrors.InvalidCredentialsErr,
+		},
+		{
+			"every further failure doubles the lock",
+			"4321",
+			&models.LoginAttempts{UserID: 121, LockedUntil: &expiredLock},
+			5,
+			4 * time.Minute,
+			&apperrors.InvalidCredentialsErr,
+		},
+		{
+			"lock is capped",
+			"4321",
+			&models.LoginAttempts{UserID: 121, LockedUntil: &expiredLock},
+			10,
+			10 * time.Minute,
+			&apperrors.InvalidCredentialsErr,
+		},
+	}
+
+	passwordHasher := newTestPasswordHasher(t)
+	bcryptHash, err := passwordHasher.Hash("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:         userRepoMock,
+				loginAttemptRepo: loginAttemptRepoMock,
+				passwordHasher:   passwordHasher,
+				lockout:          LockoutOptions{Threshold: 3, Duration: time.Minute, MaxDuration: 10 * time.Minute},
+			}
+
+			userRepoMock.EXPECT().FindOneUserByUserName(ctx, "JohnHall").
+				Return(&models.User{ID: 121, UserName: "JohnHall", Password: bcryptHash}, nil)
+			loginAttemptRepoMock.EXPECT().FindLoginAttempts(ctx, uint(121)).Return(testCase.storedAttempts, nil)
+
+			if testCase.consecutiveFailures > 0 {
+				loginAttemptRepoMock.EXPECT().RecordFailedLogin(ctx, uint(121)).
+					Return(&models.LoginAttempts{UserID: 121, ConsecutiveFailures: testCase.consecutiveFailures}, nil)
+			}
+
+			var lockedFor time.Duration
+			if testCase.expectLock > 0 {
+				loginAttemptRepoMock.EXPECT().LockUser(ctx, uint(121), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uint, until time.Time) error {
+						lockedFor = time.Until(until).Round(time.Minute)
+						return nil
+					})
+			}
+
+			_, err := uInteractor.SignIn(ctx, "JohnHall", testCase.inputPassword)
+			if !apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assert.Equal(t, lockedFor, testCase.expectLock)
+
+			// a locked account answers as an unknown username does
+			assert.Equal(t, err.(*apperrors.AppError).RetryAfter, time.Duration(0))
+		})
+	}
+}
+
+func TestSignInUserLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo:       userRepoMock,
+		passwordHasher: newTestPasswordHasher(t),
+		signInLimiter:  ratelimit.NewLimiter(repository.NewInMemoryRateLimitStore(), "user", ratelimit.Rule{Capacity: 2, RefillEvery: time.Minute}),
+	}
+
+	userRepoMock.EXPECT().FindOneUserByUserName(ctx, gomock.Any()).Return(nil, &apperrors.UserNotFoundErr).Times(2)
+
+	// the usernames are looked up in any letter case, so they share a bucket
+	for _, name := range []string{"JohnHall", "johnhall"} {
+		if _, err := uInteractor.SignIn(ctx, name, "1234"); !apperrors.Is(err, &apperrors.InvalidCredentialsErr) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	_, err := uInteractor.SignIn(ctx, "JOHNHALL", "1234")
+	if !apperrors.Is(err, &apperrors.TooManyAttemptsErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, err.(*apperrors.AppError).RetryAfter > 0, true)
+}
+
+func TestSignInLoginAttemptsFailure(t *testing.T) {
+	dbErr := errors.New("connection refused")
+
+	testTable := []struct {
+		scenario string
+		setup    func(loginAttemptRepoMock *mocks.MockLoginAttemptRepository)
+		password string
+	}{
+		{
+			"reading the attempts fails",
+			func(loginAttemptRepoMock *mocks.MockLoginAttemptRepository) {
+				loginAttemptRepoMock.EXPECT().FindLoginAttempts(gomock.Any(), uint(121)).Return(nil, dbErr)
+			},
+			"1234",
+		},
+		{
+			"recording a failure fails",
+			func(loginAttemptRepoMock *mocks.MockLoginAttemptRepository) {
+				loginAttemptRepoMock.EXPECT().FindLoginAttempts(gomock.Any(), uint(121)).Return(&models.LoginAttempts{UserID: 121}, nil)
+				loginAttemptRepoMock.EXPECT().RecordFailedLogin(gomock.Any(), uint(121)).Return(nil, dbErr)
+			},
+			"4321",
+		},
+		{
+			"locking fails",
+			func(loginAttemptRepoMock *mocks.MockLoginAttemptRepository) {
+				loginAttemptRepoMock.EXPECT().FindLoginAttempts(gomock.Any(), uint(121)).Return(&models.LoginAttempts{UserID: 121}, nil)
+				loginAttemptRepoMock.EXPECT().RecordFailedLogin(gomock.Any(), uint(121)).Return(&models.LoginAttempts{UserID: 121, ConsecutiveFailures: 3}, nil)
+				loginAttemptRepoMock.EXPECT().LockUser(gomock.Any(), uint(121), gomock.Any()).Return(dbErr)
+			},
+			"4321",
+		},
+		{
+			"recording a success fails",
+			func(loginAttemptRepoMock *mocks.MockLoginAttemptRepository) {
+				loginAttemptRepoMock.EXPECT().FindLoginAttempts(gomock.Any(), uint(121)).Return(&models.LoginAttempts{UserID: 121}, nil)
+				loginAttemptRepoMock.EXPECT().RecordSuccessfulLogin(gomock.Any(), uint(121)).Return(dbErr)
+			},
+			"1234",
+		},
+	}
+
+	passwordHasher := newTestPasswordHasher(t)
+	bcryptHash, err := passwordHasher.Hash("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:         userRepoMock,
+				loginAttemptRepo: loginAttemptRepoMock,
+				passwordHasher:   passwordHasher,
+				lockout:          LockoutOptions{Threshold: 3, Duration: time.Minute},
+			}
+
+			userRepoMock.EXPECT().FindOneUserByUserName(ctx, "JohnHall").
+				Return(&models.User{ID: 121, UserName: "JohnHall", Password: bcryptHash}, nil)
+			testCase.setup(loginAttemptRepoMock)
+
+			_, err := uInteractor.SignIn(ctx, "JohnHall", testCase.password)
+			if !apperrors.Is(err, &apperrors.DatabaseErr) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assert.Equal(t, err.(*apperrors.AppError).HTTPCode, http.StatusInternalServerError)
+			assert.Equal(t, errors.Is(err, dbErr), true)
+		})
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	limiter := ratelimit.NewLimiter(repository.NewInMemoryRateLimitStore(), "user", ratelimit.Rule{Capacity: 1, RefillEvery: time.Minute})
+	uInteractor := &userInteractor{
+		userRepo:         userRepoMock,
+		loginAttemptRepo: loginAttemptRepoMock,
+		auditRepo:        auditRepoMock,
+		transactor:       newTestTransactor(ctrl),
+		signInLimiter:    limiter,
+	}
+
+	if _, err := limiter.Allow(ctx, signInLimiterKey("JohnHall")); err != nil {
+		t.Fatal(err)
+	}
+
+	userRepoMock.EXPECT().FindOneUserByID(ctx, uint(121)).Return(&models.User{ID: 121, UserName: "JohnHall"}, nil)
+	loginAttemptRepoMock.EXPECT().UnlockUser(ctx, uint(121)).Return(nil)
+	auditRepoMock.EXPECT().CreateAuditEvent(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *models.AuditEvent) error {
+			assert.Equal(t, event.Action, models.AuditActionUserUnlock)
+			assert.Equal(t, event.TargetID, uint(121))
+			return nil
+		})
+
+	if err := uInteractor.UnlockUser(ctx, 121); err != nil {
+		t.Fatal(err)
+	}
+
+	wait, err := limiter.Allow(ctx, signInLimiterKey("JohnHall"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, wait, time.Duration(0))
+}
@@ -0,0 +1,86 @@
+package interactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+)
+
+// CreateAdmin creates an admin even when there are admins already, unlike
+// BootstrapAdmin. It is meant for operators, the API never gives the admin
+// role on sign up.
+func (uI *userInteractor) CreateAdmin(ctx context.Context, user *models.User) (*models.User, error) {
+	if !uI.roles.Exists(permissions.AdminRole) {
+		return nil, apperrors.CanNotCreateUserErr.AppendMessage(fmt.Errorf("role %q isn't defined", permissions.AdminRole))
+	}
+	user.Role = permissions.AdminRole
+
+	var err error
+	user.Password, err = uI.passwordHasher.Hash(user.Password)
+	if err != nil {
+		return nil, apperrors.HashingPasswordErr.AppendMessage(err)
+	}
+
+	err = uI.withinTransaction(ctx, &apperrors.CanNotCreateUserErr, func(ctx context.Context) error {
+		var err error
+		user, err = uI.userRepo.CreateUser(ctx, user)
+		if err != nil {
+			if errors.Is(err, &apperrors.UsernameTakenErr) {
+				return err
+			}
+			return apperrors.CanNotCreateUserErr.AppendMessage(err)
+		}
+		return uI.audit(ctx, models.AuditActionAdminCreate, user.ID, userChanges(&models.User{}, user))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// FindOneSignerByUserName returns UserNotFoundErr only when there is no such
+// user, a failed lookup is a DatabaseErr.
+func (uI *userInteractor) FindOneSignerByUserName(ctx context.Context, username string) (*models.User, error) {
+	user, err := uI.userRepo.FindOneUserByUserName(ctx, username)
+	if err != nil {
+		return nil, userLookupErr(err)
+	}
+	return user, nil
+}
+
+// ResetPassword sets a new password and revokes every session of the user,
+// so whoever knew the old password is signed out as well.
+func (uI *userInteractor) ResetPassword(ctx context.Context, username, password string) (*models.User, error) {
+	passwordHash, err := uI.passwordHasher.Hash(password)
+	if err != nil {
+		return nil, apperrors.HashingPasswordErr.AppendMessage(err)
+	}
+
+	var user *models.User
+	err = uI.withinTransaction(ctx, &apperrors.CanNotResetPasswordErr, func(ctx context.Context) error {
+		var err error
+		user, err = uI.FindOneSignerByUserName(ctx, username)
+		if err != nil {
+			return err
+		}
+
+		if err := uI.userRepo.UpdateUserPassword(ctx, user.ID, passwordHash); err != nil {
+			return apperrors.CanNotResetPasswordErr.AppendMessage(err)
+		}
+		before := *user
+		user.Password = passwordHash
+		return uI.audit(ctx, models.AuditActionPasswordReset, user.ID, userChanges(&before, user))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uI.RevokeUserSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
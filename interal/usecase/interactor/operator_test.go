@@ -0,0 +1,151 @@
+package interactor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"git.foxminded.com.ua/3_REST_API/gen/mocks"
+	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
+	"github.com/golang/mock/gomock"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestCreateAdmin(t *testing.T) {
+	testTable := []struct {
+		scenario      string
+		createError   error
+		expectedError error
+	}{
+		{"admin is created", nil, nil},
+		{"username is taken", apperrors.UsernameTakenErr.AppendMessage(errors.New("duplicate")), &apperrors.UsernameTakenErr},
+		{"can't create the admin", errors.New("db is down"), &apperrors.CanNotCreateUserErr},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:       userRepoMock,
+				auditRepo:      newTestAuditRepository(ctrl),
+				transactor:     newTestTransactor(ctrl),
+				passwordHasher: newTestPasswordHasher(t),
+				roles:          permissions.DefaultRoles(),
+			}
+
+			userRepoMock.EXPECT().CreateUser(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, user *models.User) (*models.User, error) {
+				assert.Equal(t, user.Role, permissions.AdminRole)
+				ok, err := uInteractor.passwordHasher.Verify("very12difficult()Password", user.Password)
+				assert.Equal(t, err, nil)
+				assert.Equal(t, ok, true)
+				if testCase.createError != nil {
+					return nil, testCase.createError
+				}
+				user.ID = 7
+				return user, nil
+			})
+
+			admin, err := uInteractor.CreateAdmin(ctx, &models.User{UserName: "root2", Role: permissions.UserRole, Password: "very12difficult()Password"})
+			if testCase.expectedError != nil {
+				assert.Equal(t, apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)), true)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, admin.ID, uint(7))
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	revocationStoreMock := mocks.NewMockRevocationStore(ctrl)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo:         userRepoMock,
+		auditRepo:        auditRepoMock,
+		refreshTokenRepo: refreshTokenRepoMock,
+		revocationStore:  revocationStoreMock,
+		transactor:       newTestTransactor(ctrl),
+		passwordHasher:   newTestPasswordHasher(t),
+		userCache:        newUserCache(0),
+	}
+
+	userRepoMock.EXPECT().FindOneUserByUserName(ctx, "JohnHall").Return(&models.User{ID: 121, UserName: "JohnHall", Password: "old hash"}, nil)
+	userRepoMock.EXPECT().UpdateUserPassword(ctx, uint(121), gomock.Any()).DoAndReturn(func(_ context.Context, _ uint, passwordHash string) error {
+		ok, err := uInteractor.passwordHasher.Verify("New12password()", passwordHash)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, ok, true)
+		return nil
+	})
+	auditRepoMock.EXPECT().CreateAuditEvent(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, event *models.AuditEvent) error {
+			assert.Equal(t, event.Action, models.AuditActionPasswordReset)
+			assert.Equal(t, event.Changes["password"], models.AuditChange{From: "***", To: "***"})
+			return nil
+		})
+	revocationStoreMock.EXPECT().RevokeUserTokens(ctx, uint(121), gomock.Any()).Return(nil)
+	refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, uint(121)).Return(nil)
+
+	user, err := uInteractor.ResetPassword(ctx, "JohnHall", "New12password()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, user.ID, uint(121))
+
+	userRepoMock.EXPECT().FindOneUserByUserName(ctx, "nobody").Return(nil, apperrors.UserNotFoundErr.AppendMessage(errors.New("record not found")))
+	_, err = uInteractor.ResetPassword(ctx, "nobody", "New12password()")
+	assert.Equal(t, apperrors.Is(err, &apperrors.UserNotFoundErr), true)
+}
+
+func TestFindOneSignerByUserName(t *testing.T) {
+	testTable := []struct {
+		scenario      string
+		findError     error
+		expectedError error
+	}{
+		{"user is found", nil, nil},
+		{"there is no such user", apperrors.UserNotFoundErr.AppendMessage(errors.New("record not found")), &apperrors.UserNotFoundErr},
+		{"lookup fails", errors.New("connection refused"), &apperrors.DatabaseErr},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			uInteractor := &userInteractor{userRepo: userRepoMock}
+
+			var found *models.User
+			if testCase.findError == nil {
+				found = &models.User{ID: 121, UserName: "JohnHall"}
+			}
+			userRepoMock.EXPECT().FindOneUserByUserName(ctx, "JohnHall").Return(found, testCase.findError)
+
+			user, err := uInteractor.FindOneSignerByUserName(ctx, "JohnHall")
+			if testCase.expectedError != nil {
+				assert.Equal(t, apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)), true)
+				assert.Equal(t, apperrors.Is(err, &apperrors.UserNotFoundErr), testCase.expectedError == &apperrors.UserNotFoundErr)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, user.ID, uint(121))
+		})
+	}
+}
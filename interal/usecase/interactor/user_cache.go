@@ -0,0 +1,97 @@
+package interactor
+
+import (
+	"sync"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+)
+
+// userCache keeps users loaded for authenticated requests for a short time,
+// so that every restricted request doesn't hit the database while role
+// changes and deletions still take effect within the TTL. A zero TTL or a
+// nil cache disables caching.
+//
+// Every invalidate starts a new generation of the user. A user read from the
+// database is cached only when its generation hasn't changed since before the
+// read, so a read which raced with an update can't cache the old row.
+type userCache struct {
+	ttl         time.Duration
+	mu          sync.Mutex
+	users       map[uint]cachedUser
+	generations map[uint]uint64
+}
+
+type cachedUser struct {
+	user      models.User
+	expiresAt time.Time
+}
+
+func newUserCache(ttl time.Duration) *userCache {
+	return &userCache{
+		ttl:         ttl,
+		users:       map[uint]cachedUser{},
+		generations: map[uint]uint64{},
+	}
+}
+
+func (uc *userCache) get(id uint) (*models.User, bool) {
+	if uc == nil || uc.ttl <= 0 {
+		return nil, false
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	cached, ok := uc.users[id]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(cached.expiresAt) {
+		delete(uc.users, id)
+		return nil, false
+	}
+
+	user := cached.user
+	return &user, true
+}
+
+// generation is taken before the user is read from the database and passed
+// to set.
+func (uc *userCache) generation(id uint) uint64 {
+	if uc == nil {
+		return 0
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	return uc.generations[id]
+}
+
+// set drops the user when it was invalidated after generation was taken.
+func (uc *userCache) set(user *models.User, generation uint64) {
+	if uc == nil || uc.ttl <= 0 {
+		return
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	if uc.generations[user.ID] != generation {
+		return
+	}
+	uc.users[user.ID] = cachedUser{user: *user, expiresAt: time.Now().Add(uc.ttl)}
+}
+
+func (uc *userCache) invalidate(id uint) {
+	if uc == nil {
+		return
+	}
+
+	uc.mu.Lock()
+	defer uc.mu.Unlock()
+
+	delete(uc.users, id)
+	uc.generations[id]++
+}
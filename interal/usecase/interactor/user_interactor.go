@@ -2,181 +2,481 @@ package interactor
 
 import (
 	"context"
-	"crypto/sha1"
 	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
 	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/hasher"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/ratelimit"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/signer"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 type UserInteractor interface {
-	SignUp(ctx context.Context, user *models.User) (int, string, error)
-	SignIn(ctx context.Context, name, password string) (int, string, error)
+	SignUp(ctx context.Context, user *models.User) (*AuthTokens, error)
+	SignIn(ctx context.Context, name, password string) (*AuthTokens, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*AuthTokens, error)
+	ParseToken(ctx context.Context, tokenString string) (*jwt.Token, error)
+	SignOut(ctx context.Context, claims *AuthClaims, refreshToken string) error
+	RevokeUserSessions(ctx context.Context, userID uint) error
+	JWKS() signer.JWKS
 	FindOneSigner(ctx context.Context, id uint) (*models.User, error)
-	FindSigners(ctx context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error)
+	FindSigners(ctx context.Context, filter *models.UserFilter, pagination *models.Pagination) (*models.Pagination, []*models.User, error)
+	SearchSigners(ctx context.Context, query string, pagination *models.Pagination) (*models.Pagination, []*models.User, error)
 	DeleteSignerByID(ctx context.Context, id int) error
 	DeleteOwnSignIn(ctx context.Context, id int) error
 	UpdateSignersByID(ctx context.Context, id int, user *models.User) (*models.User, error)
 	UpdateOwnSignIn(ctx context.Context, id int, user *models.User) (*models.User, error)
 	RateUser(ctx context.Context, myID uint, username, rate string) (*models.User, error)
+	AssignRole(ctx context.Context, actorID, userID uint, role string) (*models.User, error)
+	BootstrapAdmin(ctx context.Context, username, password string) error
+	UnlockUser(ctx context.Context, id uint) error
+	FindDeletedSigners(ctx context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error)
+	RestoreSigner(ctx context.Context, id uint) (*models.User, error)
+	PurgeSigner(ctx context.Context, id uint) error
+	PurgeDeletedSigners(ctx context.Context, deletedBefore time.Time) (int, error)
+	CreateAdmin(ctx context.Context, user *models.User) (*models.User, error)
+	FindOneSignerByUserName(ctx context.Context, username string) (*models.User, error)
+	ResetPassword(ctx context.Context, username, password string) (*models.User, error)
 }
 
+// AuthClaims carries only the standard claims (the user ID is the subject)
+// and the role. User isn't a part of the token: ParseToken loads it from the
+// repository, so handlers always see the current role of an existing user.
 type AuthClaims struct {
 	jwt.RegisteredClaims
-	User *models.User `json:"user"`
+	Role string       `json:"role"`
+	User *models.User `json:"-"`
+}
+
+// TokenOptions configures issued tokens. Lifetimes are in seconds.
+type TokenOptions struct {
+	KeySet          *signer.KeySet
+	Issuer          string
+	Audience        string
+	TokenTTL        int
+	RefreshTokenTTL int
+	UserCacheTTL    int
+	// CursorKey signs the cursors of the user list.
+	CursorKey []byte
 }
 
 type userInteractor struct {
-	userRepo       repository.UserRepository
-	hashSalt       string
-	signingKey     []byte
-	expireDuration int
+	userRepo              repository.UserRepository
+	refreshTokenRepo      repository.RefreshTokenRepository
+	auditRepo             repository.AuditRepository
+	loginAttemptRepo      repository.LoginAttemptRepository
+	transactor            repository.Transactor
+	revocationStore       repository.RevocationStore
+	passwordHasher        hasher.PasswordHasher
+	roles                 *permissions.Roles
+	keySet                *signer.KeySet
+	issuer                string
+	audience              string
+	expireDuration        int
+	refreshExpireDuration int
+	userCache             *userCache
+	signInLimiter         *ratelimit.Limiter
+	lockout               LockoutOptions
+	cursors               cursorCodec
+	deletion              DeletionOptions
 }
 
-func NewUserInteractor(userRepo repository.UserRepository, hashSalt string, signingKey []byte, tokenTTL int) *userInteractor {
+func NewUserInteractor(userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository, auditRepo repository.AuditRepository,
+	loginAttemptRepo repository.LoginAttemptRepository, transactor repository.Transactor, revocationStore repository.RevocationStore,
+	passwordHasher hasher.PasswordHasher, roles *permissions.Roles, tokenOptions TokenOptions, lockoutOptions LockoutOptions, deletionOptions DeletionOptions) *userInteractor {
 	return &userInteractor{
-		userRepo:       userRepo,
-		hashSalt:       hashSalt,
-		signingKey:     signingKey,
-		expireDuration: tokenTTL,
+		userRepo:              userRepo,
+		refreshTokenRepo:      refreshTokenRepo,
+		auditRepo:             auditRepo,
+		loginAttemptRepo:      loginAttemptRepo,
+		transactor:            transactor,
+		revocationStore:       revocationStore,
+		passwordHasher:        passwordHasher,
+		roles:                 roles,
+		keySet:                tokenOptions.KeySet,
+		issuer:                tokenOptions.Issuer,
+		audience:              tokenOptions.Audience,
+		expireDuration:        tokenOptions.TokenTTL,
+		refreshExpireDuration: tokenOptions.RefreshTokenTTL,
+		userCache:             newUserCache(time.Second * time.Duration(tokenOptions.UserCacheTTL)),
+		signInLimiter:         lockoutOptions.UserLimiter,
+		lockout:               lockoutOptions,
+		cursors:               cursorCodec{tokenOptions.CursorKey},
+		deletion:              deletionOptions,
 	}
 }
 
-func (uI *userInteractor) SignUp(ctx context.Context, user *models.User) (int, string, error) {
+// SignUp always creates a user with the default role, other roles are given
+// only by AssignRole.
+func (uI *userInteractor) SignUp(ctx context.Context, user *models.User) (*AuthTokens, error) {
+	user.Role = permissions.UserRole
+
 	var err error
-	user.Password, err = uI.hashing(user.Password)
+	user.Password, err = uI.passwordHasher.Hash(user.Password)
 	if err != nil {
-		return 0, "", apperrors.HashingPasswordErr.AppendMessage(err)
+		return nil, apperrors.HashingPasswordErr.AppendMessage(err)
 	}
 
 	user, err = uI.userRepo.CreateUser(ctx, user)
 	if err != nil {
-		return 0, "", apperrors.CanNotCreateUserErr.AppendMessage(err)
+		if errors.Is(err, &apperrors.UsernameTakenErr) {
+			return nil, err
+		}
+		return nil, apperrors.CanNotCreateUserErr.AppendMessage(err)
 	}
 
-	token, err := uI.makeSignedToken(user)
-	if err != nil {
-		return 0, "", apperrors.CanNotCreateTokenErr.AppendMessage(err)
+	return uI.issueTokens(ctx, user)
+}
+
+// SignIn is limited per username, and an account is locked after too many
+// failed attempts in a row. Both failed and successful attempts are counted.
+// An unknown username and a wrong password both get InvalidCredentialsErr,
+// and take the same time, so usernames can't be enumerated.
+func (uI *userInteractor) SignIn(ctx context.Context, name, password string) (*AuthTokens, error) {
+	if err := uI.checkSignInAllowed(ctx, name); err != nil {
+		return nil, err
 	}
 
-	return uI.expireDuration, token, nil
-}
+	if password == "" {
+		return nil, apperrors.InvalidCredentialsErr.AppendMessage(errors.New("empty password"))
+	}
 
-func (uI *userInteractor) SignIn(ctx context.Context, name, password string) (int, string, error) {
-	password, err := uI.hashing(password)
+	user, err := uI.userRepo.FindOneUserByUserName(ctx, name)
 	if err != nil {
-		return 0, "", apperrors.HashingPasswordErr.AppendMessage(err)
+		if errors.Is(err, &apperrors.UserNotFoundErr) {
+			uI.passwordHasher.VerifyDummy(password)
+			return nil, apperrors.InvalidCredentialsErr.AppendMessage(err)
+		}
+		return nil, apperrors.CanNotSignInErr.AppendMessage(err)
 	}
 
-	user, err := uI.userRepo.FindOneUserByUserNameAndPassword(ctx, name, password)
-	if err != nil {
-		return 0, "", apperrors.UserNotFoundErr.AppendMessage(err)
+	if err := uI.checkNotLocked(ctx, user, password); err != nil {
+		return nil, err
 	}
 
-	token, err := uI.makeSignedToken(user)
+	ok, err := uI.passwordHasher.Verify(password, user.Password)
 	if err != nil {
-		return 0, "", apperrors.CanNotCreateTokenErr.AppendMessage(err)
+		return nil, apperrors.HashingPasswordErr.AppendMessage(err)
+	}
+	if !ok {
+		return nil, uI.recordFailedLogin(ctx, user)
+	}
+
+	if err := uI.loginAttemptRepo.RecordSuccessfulLogin(ctx, user.ID); err != nil {
+		return nil, apperrors.DatabaseErr.AppendMessage(err)
+	}
+
+	if uI.passwordHasher.NeedsRehash(user.Password) {
+		uI.rehashPassword(ctx, user, password)
 	}
 
-	return uI.expireDuration, token, nil
+	return uI.issueTokens(ctx, user)
 }
 
+// DeleteSignerByID doesn't delete admins: users whose role can assign roles,
+// whatever the role is named.
 func (uI *userInteractor) DeleteSignerByID(ctx context.Context, id int) error {
+	err := uI.withinTransaction(ctx, &apperrors.CanNotDeleteUserErr, func(ctx context.Context) error {
+		user, err := uI.userRepo.FindOneUserByID(ctx, uint(id))
+		if err != nil {
+			return userLookupErr(err)
+		}
+		if uI.roles.Has(user.Role, permissions.RolesAssign) {
+			return apperrors.WrongRoleErr.AppendMessage(fmt.Errorf("users with role %q can't be deleted", user.Role))
+		}
 
-	if err := uI.userRepo.DeleteUserByID(ctx, id); err != nil {
-		if err == &apperrors.WrongRoleErr {
+		if err := uI.userRepo.DeleteUserByID(ctx, id); err != nil {
+			return apperrors.CanNotDeleteUserErr.AppendMessage(err)
+		}
+		if err := uI.releaseUserName(ctx, uint(id)); err != nil {
 			return err
 		}
-		return apperrors.CanNotDeleteUserErr.AppendMessage(err)
+		return uI.audit(ctx, models.AuditActionUserDelete, uint(id), nil)
+	})
+	if err != nil {
+		return err
 	}
-	return nil
+	return uI.RevokeUserSessions(ctx, uint(id))
 }
 
 func (uI *userInteractor) DeleteOwnSignIn(ctx context.Context, id int) error {
-
-	if err := uI.userRepo.DeleteOwnUser(ctx, id); err != nil {
-		return apperrors.CanNotDeleteUserErr.AppendMessage(err)
+	err := uI.withinTransaction(ctx, &apperrors.CanNotDeleteUserErr, func(ctx context.Context) error {
+		if err := uI.userRepo.DeleteOwnUser(ctx, id); err != nil {
+			return apperrors.CanNotDeleteUserErr.AppendMessage(err)
+		}
+		if err := uI.releaseUserName(ctx, uint(id)); err != nil {
+			return err
+		}
+		return uI.audit(ctx, models.AuditActionOwnDelete, uint(id), nil)
+	})
+	if err != nil {
+		return err
 	}
-	return nil
+	return uI.RevokeUserSessions(ctx, uint(id))
 }
 
 func (uI *userInteractor) FindOneSigner(ctx context.Context, id uint) (*models.User, error) {
 	user, err := uI.userRepo.FindOneUserByID(ctx, id)
 	if err != nil {
-		return nil, apperrors.UserNotFoundErr.AppendMessage(err)
+		return nil, userLookupErr(err)
 	}
 	return user, nil
 }
 
-func (uI *userInteractor) FindSigners(ctx context.Context, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
-	pagination, users, err := uI.userRepo.FindUsers(ctx, pagination)
+// FindSigners reads the page after or before pagination.Cursor when it is
+// set. The sort is the one of the cursor, an empty pagination.Sort takes it.
+func (uI *userInteractor) FindSigners(ctx context.Context, filter *models.UserFilter, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+	if pagination.Cursor != "" {
+		cursor, err := uI.cursors.decode(pagination.Cursor)
+		if err != nil {
+			return nil, nil, apperrors.InvalidCursorErr.AppendMessage(err)
+		}
+		if pagination.Sort == "" {
+			pagination.Sort = cursor.Sort
+		}
+		if pagination.Sort != cursor.Sort {
+			return nil, nil, apperrors.InvalidCursorErr.WithDetail("sort", cursor.Sort).AppendMessage(fmt.Errorf("the cursor is for sort %q", cursor.Sort))
+		}
+		pagination.Keyset = cursor
+	}
+
+	pagination, users, err := uI.userRepo.FindUsers(ctx, filter, pagination)
 	if err != nil {
-		if err == &apperrors.WrongRoleErr {
+		if errors.Is(err, &apperrors.WrongRoleErr) || errors.Is(err, &apperrors.InvalidCursorErr) {
 			return nil, nil, err
 		}
 		return nil, nil, apperrors.PaginationErr.AppendMessage(err)
 	}
 
+	if pagination.NextKeyset != nil {
+		if pagination.NextCursor, err = uI.cursors.encode(pagination.NextKeyset); err != nil {
+			return nil, nil, apperrors.PaginationErr.AppendMessage(err)
+		}
+	}
+	if pagination.PrevKeyset != nil {
+		if pagination.PrevCursor, err = uI.cursors.encode(pagination.PrevKeyset); err != nil {
+			return nil, nil, apperrors.PaginationErr.AppendMessage(err)
+		}
+	}
 	return pagination, users, nil
 }
 
-func (uI *userInteractor) UpdateSignersByID(ctx context.Context, id int, user *models.User) (*models.User, error) {
-	user, err := uI.userRepo.UpdateUserByID(ctx, id, user)
+// SearchSigners ranks the users by how well they match query.
+func (uI *userInteractor) SearchSigners(ctx context.Context, query string, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+	pagination, users, err := uI.userRepo.SearchUsers(ctx, query, pagination)
 	if err != nil {
-		return nil, apperrors.CanNotUpdateErr.AppendMessage(err)
+		return nil, nil, apperrors.PaginationErr.AppendMessage(err)
 	}
+	return pagination, users, nil
+}
 
-	return user, nil
+func (uI *userInteractor) UpdateSignersByID(ctx context.Context, id int, user *models.User) (*models.User, error) {
+	user.Role = ""
+
+	return uI.updateUser(ctx, id, models.AuditActionUserUpdate, func(ctx context.Context) (*models.User, error) {
+		return uI.userRepo.UpdateUserByID(ctx, id, user)
+	})
 }
 
 func (uI *userInteractor) UpdateOwnSignIn(ctx context.Context, id int, user *models.User) (*models.User, error) {
-	user, err := uI.userRepo.UpdateOwnUser(ctx, id, user)
+	user.Role = ""
+
+	if user.Password != "" {
+		var err error
+		user.Password, err = uI.passwordHasher.Hash(user.Password)
+		if err != nil {
+			return nil, apperrors.HashingPasswordErr.AppendMessage(err)
+		}
+	}
+
+	return uI.updateUser(ctx, id, models.AuditActionOwnUpdate, func(ctx context.Context) (*models.User, error) {
+		return uI.userRepo.UpdateOwnUser(ctx, id, user)
+	})
+}
+
+// updateUser runs the update and audits the changed fields in one transaction.
+// The cached user is dropped after the commit. A read which got the old row
+// before the commit isn't cached then, see userCache.
+func (uI *userInteractor) updateUser(ctx context.Context, id int, action string, update func(ctx context.Context) (*models.User, error)) (*models.User, error) {
+	var updated *models.User
+	err := uI.withinTransaction(ctx, &apperrors.CanNotUpdateErr, func(ctx context.Context) error {
+		before, err := uI.userRepo.FindOneUserByID(ctx, uint(id))
+		if err != nil {
+			return userLookupErr(err)
+		}
+
+		updated, err = update(ctx)
+		if err != nil {
+			return apperrors.CanNotUpdateErr.AppendMessage(err)
+		}
+		return uI.audit(ctx, action, uint(id), userChanges(before, updated))
+	})
 	if err != nil {
-		return nil, apperrors.CanNotUpdateErr.AppendMessage(err)
+		return nil, err
 	}
+	uI.userCache.invalidate(uint(id))
 
-	return user, nil
+	return updated, nil
 }
 
 func (uI *userInteractor) RateUser(ctx context.Context, myID uint, username, rate string) (*models.User, error) {
-	user, err := uI.userRepo.RateUserByUsername(ctx, myID, username, rate)
+	var user *models.User
+	err := uI.withinTransaction(ctx, &apperrors.CanNotUpdateErr, func(ctx context.Context) error {
+		before, err := uI.userRepo.FindOneUserByUserName(ctx, username)
+		if err != nil {
+			return userLookupErr(err)
+		}
+		// Usernames match regardless of case, so compare the IDs.
+		if before.ID == myID {
+			return &apperrors.CanNotRateYorself
+		}
+
+		user, err = uI.userRepo.RateUserByUsername(ctx, myID, username, rate)
+		if err != nil {
+			return err
+		}
+
+		changes := userChanges(before, user)
+		changes["rate"] = models.AuditChange{To: rate}
+		return uI.audit(ctx, models.AuditActionUserRate, user.ID, changes)
+	})
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
-func (uI *userInteractor) hashing(password string) (string, error) {
-	if password == "" {
-		return "", errors.New("empty pasword field")
+// AssignRole is the only way to change a role. Every change is recorded with
+// the actor, and nobody can change their own role.
+func (uI *userInteractor) AssignRole(ctx context.Context, actorID, userID uint, role string) (*models.User, error) {
+	if actorID == userID {
+		return nil, &apperrors.CanNotChangeOwnRoleErr
 	}
-	pwd := sha1.New()
-	if _, err := pwd.Write([]byte(password)); err != nil {
-		return "", err
+	if !uI.roles.Exists(role) {
+		return nil, apperrors.UnknownRoleErr.AppendMessage(fmt.Errorf("role %q", role))
 	}
 
-	if uI.hashSalt == "" {
-		return "", errors.New("empty hashSalt field")
+	var user *models.User
+	err := uI.withinTransaction(ctx, &apperrors.CanNotAssignRoleErr, func(ctx context.Context) error {
+		before, err := uI.userRepo.FindOneUserByID(ctx, userID)
+		if err != nil {
+			return userLookupErr(err)
+		}
+
+		if err := uI.userRepo.UpdateUserRole(ctx, userID, role); err != nil {
+			return apperrors.CanNotAssignRoleErr.AppendMessage(err)
+		}
+
+		after := *before
+		after.Role = role
+		user = &after
+		return uI.audit(ctx, models.AuditActionRoleAssign, userID, userChanges(before, user))
+	})
+	if err != nil {
+		return nil, err
 	}
-	if _, err := pwd.Write([]byte(uI.hashSalt)); err != nil {
-		return "", err
+	uI.userCache.invalidate(userID)
+
+	return user, nil
+}
+
+// BootstrapAdmin creates the first admin when there is none yet. It does
+// nothing once any admin exists, so it is safe to run on every start.
+func (uI *userInteractor) BootstrapAdmin(ctx context.Context, username, password string) error {
+	if !uI.roles.Exists(permissions.AdminRole) {
+		return apperrors.CanNotBootstrapAdminErr.AppendMessage(fmt.Errorf("role %q isn't defined", permissions.AdminRole))
+	}
+
+	count, err := uI.userRepo.CountUsersByRole(ctx, permissions.AdminRole)
+	if err != nil {
+		return apperrors.CanNotBootstrapAdminErr.AppendMessage(err)
+	}
+	if count > 0 {
+		return nil
 	}
 
-	return fmt.Sprintf("%x", pwd.Sum(nil)), nil
+	passwordHash, err := uI.passwordHasher.Hash(password)
+	if err != nil {
+		return apperrors.HashingPasswordErr.AppendMessage(err)
+	}
+
+	return uI.withinTransaction(ctx, &apperrors.CanNotBootstrapAdminErr, func(ctx context.Context) error {
+		admin, err := uI.userRepo.CreateUser(ctx, &models.User{
+			UserName: username,
+			Role:     permissions.AdminRole,
+			Rating:   1,
+			Password: passwordHash,
+		})
+		if err != nil {
+			return apperrors.CanNotBootstrapAdminErr.AppendMessage(err)
+		}
+		return uI.audit(ctx, models.AuditActionAdminBootstrap, admin.ID, userChanges(&models.User{}, admin))
+	})
+}
+
+// withinTransaction runs fn in one transaction. fn returns app errors, txErr
+// reports failures of the transaction itself (begin/commit).
+func (uI *userInteractor) withinTransaction(ctx context.Context, txErr *apperrors.AppError, fn func(ctx context.Context) error) error {
+	err := uI.transactor.WithinTransaction(ctx, fn)
+	if err == nil {
+		return nil
+	}
+	if _, ok := apperrors.As(err); ok {
+		return err
+	}
+	return txErr.Wrap(err)
+}
+
+// userLookupErr keeps the UserNotFoundErr of a missing user, any other failure
+// of a user lookup is a DatabaseErr.
+func userLookupErr(err error) error {
+	if errors.Is(err, &apperrors.UserNotFoundErr) {
+		return err
+	}
+	return apperrors.DatabaseErr.AppendMessage(err)
+}
+
+// rehashPassword upgrades an outdated hash (legacy SHA-1 or old parameters)
+// after a successful sign in. It never fails the sign in: if the upgrade
+// doesn't work out the old hash still stays valid and it is retried next time.
+func (uI *userInteractor) rehashPassword(ctx context.Context, user *models.User, password string) {
+	passwordHash, err := uI.passwordHasher.Hash(password)
+	if err != nil {
+		return
+	}
+
+	if err := uI.userRepo.UpdateUserPassword(ctx, user.ID, passwordHash); err != nil {
+		return
+	}
+	user.Password = passwordHash
 }
 
 func (uI *userInteractor) makeSignedToken(user *models.User) (string, error) {
+	jti, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := AuthClaims{
-		User: user,
+		Role: user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Second * (time.Duration(uI.expireDuration)))),
+			ID:        jti,
+			Subject:   strconv.FormatUint(uint64(user.ID), 10),
+			Issuer:    uI.issuer,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Second * (time.Duration(uI.expireDuration)))),
 		},
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if uI.audience != "" {
+		claims.Audience = jwt.ClaimStrings{uI.audience}
+	}
 
-	return token.SignedString(uI.signingKey)
+	return uI.keySet.Sign(claims)
 }
@@ -5,20 +5,26 @@ import (
 	"crypto/sha1"
 	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"git.foxminded.com.ua/3_REST_API/gen/mocks"
 	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
 	"git.foxminded.com.ua/3_REST_API/interal/domain/models"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
 	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/hasher"
+	"git.foxminded.com.ua/3_REST_API/interal/usecase/signer"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/golang/mock/gomock"
 	"github.com/magiconair/properties/assert"
 )
 
 func TestSignUp(t *testing.T) {
-	now := time.Now().Truncate(time.Millisecond)
+	now := time.Now().UTC().Truncate(time.Millisecond)
 	testTable := []struct {
 		scenario      string
 		expectedUser  *models.User
@@ -44,6 +50,14 @@ func TestSignUp(t *testing.T) {
 			},
 			&apperrors.CanNotCreateUserErr,
 		},
+		{
+			"username is already taken",
+			&models.User{
+				UserName: "JohnHall",
+				Password: "1234",
+			},
+			&apperrors.UsernameTakenErr,
+		},
 		{
 			"empty password field",
 			&models.User{
@@ -57,23 +71,27 @@ func TestSignUp(t *testing.T) {
 	defer ctrl.Finish()
 
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
 	uInteractor := &userInteractor{
-		userRepo:       userRepoMock,
-		hashSalt:       "hash_salt",
-		signingKey:     []byte("signing_key"),
-		expireDuration: 1,
+		userRepo:              userRepoMock,
+		refreshTokenRepo:      refreshTokenRepoMock,
+		passwordHasher:        newTestPasswordHasher(t),
+		keySet:                newTestKeySet(t, "signing_key"),
+		expireDuration:        1,
+		refreshExpireDuration: 2,
 	}
 
 	for _, testCase := range testTable {
 		t.Run(testCase.scenario, func(t *testing.T) {
 			ctx := context.Background()
-			hashingPassword, err := uInteractor.hashing(testCase.expectedUser.Password)
-			testCase.expectedUser.Password = hashingPassword
-			if err == nil {
+			if testCase.expectedUser.Password != "" {
 				userRepoMock.EXPECT().CreateUser(ctx, testCase.expectedUser).Return(testCase.expectedUser, testCase.expectedError)
 			}
+			if testCase.expectedError == nil {
+				refreshTokenRepoMock.EXPECT().CreateRefreshToken(ctx, gomock.Any()).Return(nil)
+			}
 
-			_, tokenString, err := uInteractor.SignUp(ctx, testCase.expectedUser)
+			tokens, err := uInteractor.SignUp(ctx, testCase.expectedUser)
 			if err != nil {
 
 				if testCase.expectedError != nil && apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)) {
@@ -82,55 +100,118 @@ func TestSignUp(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			jwtToken, err := jwt.ParseWithClaims(tokenString, &AuthClaims{}, func(token *jwt.Token) (interface{}, error) {
-				return uInteractor.signingKey, nil
-			})
+			jwtToken, err := jwt.ParseWithClaims(tokens.AccessToken, &AuthClaims{}, uInteractor.keySet.Keyfunc)
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			assert.Equal(t, jwtToken.Claims.(*AuthClaims).User, testCase.expectedUser)
+			claims := jwtToken.Claims.(*AuthClaims)
+			assert.Equal(t, claims.Subject, strconv.Itoa(int(testCase.expectedUser.ID)))
+			assert.Equal(t, claims.Role, testCase.expectedUser.Role)
+			assert.Equal(t, claims.User == nil, true, "user must not be a part of the token")
 		})
 	}
 }
 
 func TestSignIn(t *testing.T) {
-	now := time.Now().Truncate(time.Millisecond)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	passwordHasher := newTestPasswordHasher(t)
+
+	bcryptHash, err := passwordHasher.Hash("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	testTable := []struct {
-		scenario      string
-		inputUserName string
-		inputPassword string
-		expectedUser  *models.User
-		expectedError error
+		scenario       string
+		inputUserName  string
+		inputPassword  string
+		storedPassword string
+		expectedUser   *models.User
+		findError      error
+		expectRehash   bool
+		expectFailure  bool
+		expectedError  error
 	}{
 		{
 			"sing ip user",
 			"JohnHall",
 			"1234",
+			bcryptHash,
 			&models.User{
 				ID:        121,
 				UserName:  "JohnHall",
 				FirstName: "John",
 				LastName:  "Hall",
-				Password:  "1234",
 				CreatedAt: &now,
 				UpdatedAt: &now,
 			},
 			nil,
+			false,
+			false,
+			nil,
+		},
+		{
+			"sing in user with a legacy sha1 hash is rehashed",
+			"JohnHall",
+			"1234",
+			legacyHashingFunc("1234", "hash_salt"),
+			&models.User{
+				ID:        121,
+				UserName:  "JohnHall",
+				FirstName: "John",
+				LastName:  "Hall",
+				CreatedAt: &now,
+				UpdatedAt: &now,
+			},
+			nil,
+			true,
+			false,
+			nil,
 		},
 		{
 			"user don't present in database",
 			"JohnHall",
 			"1234",
-			&models.User{},
-			&apperrors.UserNotFoundErr,
+			"",
+			nil,
+			apperrors.UserNotFoundErr.AppendMessage(errors.New("record not found")),
+			false,
+			false,
+			&apperrors.InvalidCredentialsErr,
+		},
+		{
+			"database is unavailable",
+			"JohnHall",
+			"1234",
+			"",
+			nil,
+			errors.New("connection refused"),
+			false,
+			false,
+			&apperrors.CanNotSignInErr,
+		},
+		{
+			"wrong password",
+			"JohnHall",
+			"4321",
+			bcryptHash,
+			&models.User{ID: 121, UserName: "JohnHall"},
+			nil,
+			false,
+			true,
+			&apperrors.InvalidCredentialsErr,
 		},
 		{
 			"empty password field",
 			"JohnHall",
 			"",
-			&models.User{},
-			&apperrors.HashingPasswordErr,
+			bcryptHash,
+			&models.User{ID: 121, UserName: "JohnHall"},
+			nil,
+			false,
+			false,
+			&apperrors.InvalidCredentialsErr,
 		},
 	}
 
@@ -138,21 +219,48 @@ func TestSignIn(t *testing.T) {
 	defer ctrl.Finish()
 
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
 	uInteractor := &userInteractor{
-		userRepo:       userRepoMock,
-		hashSalt:       "hash_salt",
-		signingKey:     []byte("signing_key"),
-		expireDuration: 1,
+		userRepo:              userRepoMock,
+		refreshTokenRepo:      refreshTokenRepoMock,
+		loginAttemptRepo:      loginAttemptRepoMock,
+		passwordHasher:        passwordHasher,
+		keySet:                newTestKeySet(t, "signing_key"),
+		expireDuration:        600,
+		refreshExpireDuration: 1200,
 	}
 
 	for _, testCase := range testTable {
 		t.Run(testCase.scenario, func(t *testing.T) {
 			ctx := context.Background()
-			hashingPassword, err := uInteractor.hashing(testCase.inputPassword)
-			if err == nil {
-				userRepoMock.EXPECT().FindOneUserByUserNameAndPassword(ctx, testCase.inputUserName, hashingPassword).Return(testCase.expectedUser, testCase.expectedError)
+			if testCase.expectedUser != nil {
+				testCase.expectedUser.Password = testCase.storedPassword
 			}
-			_, token, err := uInteractor.SignIn(ctx, testCase.inputUserName, testCase.inputPassword)
+			if testCase.inputPassword != "" {
+				userRepoMock.EXPECT().FindOneUserByUserName(ctx, testCase.inputUserName).Return(testCase.expectedUser, testCase.findError)
+			}
+
+			var rehashedPassword string
+			if testCase.expectRehash {
+				userRepoMock.EXPECT().UpdateUserPassword(ctx, testCase.expectedUser.ID, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uint, passwordHash string) error {
+						rehashedPassword = passwordHash
+						return nil
+					})
+			}
+
+			if testCase.expectFailure {
+				loginAttemptRepoMock.EXPECT().RecordFailedLogin(ctx, testCase.expectedUser.ID).
+					Return(&models.LoginAttempts{UserID: testCase.expectedUser.ID, FailedAttempts: 1, ConsecutiveFailures: 1}, nil)
+			}
+
+			if testCase.expectedError == nil {
+				loginAttemptRepoMock.EXPECT().RecordSuccessfulLogin(ctx, testCase.expectedUser.ID).Return(nil)
+				refreshTokenRepoMock.EXPECT().CreateRefreshToken(ctx, gomock.Any()).Return(nil)
+			}
+
+			tokens, err := uInteractor.SignIn(ctx, testCase.inputUserName, testCase.inputPassword)
 			if err != nil {
 
 				if testCase.expectedError != nil && apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)) {
@@ -162,14 +270,24 @@ func TestSignIn(t *testing.T) {
 				t.Fatal(err)
 			}
 
-			jwtToken, err := jwt.ParseWithClaims(token, &AuthClaims{}, func(token *jwt.Token) (interface{}, error) {
-				return uInteractor.signingKey, nil
-			})
+			if testCase.expectRehash {
+				assert.Equal(t, passwordHasher.NeedsRehash(rehashedPassword), false)
+				ok, err := passwordHasher.Verify(testCase.inputPassword, rehashedPassword)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assert.Equal(t, ok, true)
+			}
+
+			jwtToken, err := jwt.ParseWithClaims(tokens.AccessToken, &AuthClaims{}, uInteractor.keySet.Keyfunc)
 			if err != nil {
 				t.Fatal(err)
 			}
 
-			assert.Equal(t, jwtToken.Claims.(*AuthClaims).User, testCase.expectedUser)
+			claims := jwtToken.Claims.(*AuthClaims)
+			assert.Equal(t, claims.Subject, strconv.Itoa(int(testCase.expectedUser.ID)))
+			assert.Equal(t, claims.Role, testCase.expectedUser.Role)
+			assert.Equal(t, claims.User == nil, true, "user must not be a part of the token")
 		})
 	}
 }
@@ -179,6 +297,7 @@ func TestDeleteSigner(t *testing.T) {
 	testTable := []struct {
 		scenario      string
 		expectedUser  *models.User
+		deleteError   error
 		expectedError error
 	}{
 		{
@@ -186,6 +305,7 @@ func TestDeleteSigner(t *testing.T) {
 			&models.User{
 				ID:        121,
 				UserName:  "JohnHall",
+				Role:      "user",
 				FirstName: "John",
 				LastName:  "Hall",
 				Password:  "1231",
@@ -193,29 +313,63 @@ func TestDeleteSigner(t *testing.T) {
 				UpdatedAt: &now,
 			},
 			nil,
+			nil,
 		},
 		{
 			"can not delete user",
-			&models.User{},
+			&models.User{ID: 122, Role: "user"},
+			errors.New("db is down"),
 			&apperrors.CanNotDeleteUserErr,
 		},
+		{
+			"admin can not be deleted",
+			&models.User{ID: 1, Role: "admin"},
+			nil,
+			&apperrors.WrongRoleErr,
+		},
+		{
+			"custom role with the admin permissions can not be deleted",
+			&models.User{ID: 2, Role: "superuser"},
+			nil,
+			&apperrors.WrongRoleErr,
+		},
+	}
+
+	roles, err := permissions.NewRoles(permissions.DefaultRolePermissions + ";superuser=*")
+	if err != nil {
+		t.Fatal(err)
 	}
 
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	revocationStoreMock := mocks.NewMockRevocationStore(ctrl)
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
 	uInteractor := &userInteractor{
-		userRepo:       userRepoMock,
-		hashSalt:       "",
-		signingKey:     nil,
-		expireDuration: 0,
+		userRepo:         userRepoMock,
+		refreshTokenRepo: refreshTokenRepoMock,
+		auditRepo:        auditRepoMock,
+		transactor:       newTestTransactor(ctrl),
+		revocationStore:  revocationStoreMock,
+		roles:            roles,
+		passwordHasher:   nil,
+		expireDuration:   0,
 	}
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			ctx := context.Background()
-			userRepoMock.EXPECT().DeleteUserByID(ctx, int(tc.expectedUser.ID)).Return(tc.expectedError)
+			userRepoMock.EXPECT().FindOneUserByID(ctx, tc.expectedUser.ID).Return(tc.expectedUser, nil)
+			if !apperrors.Is(tc.expectedError, &apperrors.WrongRoleErr) {
+				userRepoMock.EXPECT().DeleteUserByID(ctx, int(tc.expectedUser.ID)).Return(tc.deleteError)
+			}
+			if tc.expectedError == nil {
+				auditRepoMock.EXPECT().CreateAuditEvent(ctx, &models.AuditEvent{TargetID: tc.expectedUser.ID, Action: models.AuditActionUserDelete}).Return(nil)
+				revocationStoreMock.EXPECT().RevokeUserTokens(ctx, tc.expectedUser.ID, gomock.Any()).Return(nil)
+				refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, tc.expectedUser.ID).Return(nil)
+			}
 			err := uInteractor.DeleteSignerByID(ctx, int(tc.expectedUser.ID))
 			if err != nil {
 
@@ -225,6 +379,9 @@ func TestDeleteSigner(t *testing.T) {
 
 				t.Fatal(err)
 			}
+			if tc.expectedError != nil {
+				t.Fatalf("expected %v", tc.expectedError)
+			}
 
 		})
 	}
@@ -262,17 +419,28 @@ func TestDeleteOwnSignIn(t *testing.T) {
 	defer ctrl.Finish()
 
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	revocationStoreMock := mocks.NewMockRevocationStore(ctrl)
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
 	uInteractor := &userInteractor{
-		userRepo:       userRepoMock,
-		hashSalt:       "",
-		signingKey:     nil,
-		expireDuration: 0,
+		userRepo:         userRepoMock,
+		refreshTokenRepo: refreshTokenRepoMock,
+		auditRepo:        auditRepoMock,
+		transactor:       newTestTransactor(ctrl),
+		revocationStore:  revocationStoreMock,
+		passwordHasher:   nil,
+		expireDuration:   0,
 	}
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			ctx := context.Background()
 			userRepoMock.EXPECT().DeleteOwnUser(ctx, int(tc.expectedUser.ID)).Return(tc.expectedError)
+			if tc.expectedError == nil {
+				auditRepoMock.EXPECT().CreateAuditEvent(ctx, &models.AuditEvent{TargetID: tc.expectedUser.ID, Action: models.AuditActionOwnDelete}).Return(nil)
+				revocationStoreMock.EXPECT().RevokeUserTokens(ctx, tc.expectedUser.ID, gomock.Any()).Return(nil)
+				refreshTokenRepoMock.EXPECT().RevokeUserRefreshTokens(ctx, tc.expectedUser.ID).Return(nil)
+			}
 			err := uInteractor.DeleteOwnSignIn(ctx, int(tc.expectedUser.ID))
 			if err != nil {
 
@@ -321,8 +489,7 @@ func TestFindOneSigner(t *testing.T) {
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
 	uInteractor := &userInteractor{
 		userRepo:       userRepoMock,
-		hashSalt:       "",
-		signingKey:     nil,
+		passwordHasher: nil,
 		expireDuration: 0,
 	}
 
@@ -372,16 +539,16 @@ func TestFindSigners(t *testing.T) {
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
 	uInteractor := &userInteractor{
 		userRepo:       userRepoMock,
-		hashSalt:       "",
-		signingKey:     nil,
+		passwordHasher: nil,
 		expireDuration: 0,
 	}
 
 	for _, testCase := range testTable {
 		t.Run(testCase.scenario, func(t *testing.T) {
 			ctx := context.Background()
-			userRepoMock.EXPECT().FindUsers(ctx, testCase.expectedPagination).Return(testCase.expectedPagination, testCase.expectedUsers, testCase.expectedError)
-			pagination, users, err := uInteractor.FindSigners(ctx, testCase.expectedPagination)
+			filter := &models.UserFilter{Role: "user"}
+			userRepoMock.EXPECT().FindUsers(ctx, filter, testCase.expectedPagination).Return(testCase.expectedPagination, testCase.expectedUsers, testCase.expectedError)
+			pagination, users, err := uInteractor.FindSigners(ctx, filter, testCase.expectedPagination)
 			if err != nil {
 
 				if testCase.expectedError != nil && apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)) {
@@ -402,38 +569,94 @@ func TestNewUserInteractor(t *testing.T) {
 	defer ctrl.Finish()
 
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	refreshTokenRepoMock := mocks.NewMockRefreshTokenRepository(ctrl)
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	loginAttemptRepoMock := mocks.NewMockLoginAttemptRepository(ctrl)
+	transactorMock := mocks.NewMockTransactor(ctrl)
+	revocationStoreMock := mocks.NewMockRevocationStore(ctrl)
+	passwordHasher := newTestPasswordHasher(t)
+	roles := permissions.DefaultRoles()
+	lockoutOptions := LockoutOptions{Threshold: 5, Duration: time.Minute, MaxDuration: time.Hour}
+	tokenOptions := TokenOptions{
+		KeySet:          newTestKeySet(t, "signing_key"),
+		Issuer:          "issuer",
+		Audience:        "audience",
+		TokenTTL:        1,
+		RefreshTokenTTL: 2,
+		UserCacheTTL:    3,
+	}
 	testTable := []struct {
-		scenario                string
-		inputUserRepository     repository.UserRepository
-		inputHashSalt           string
-		inputSigningKey         []byte
-		InputExpireDuration     int
-		expectedUserInterfactor *userInteractor
+		scenario                   string
+		inputUserRepository        repository.UserRepository
+		inputRefreshTokenRepostory repository.RefreshTokenRepository
+		inputAuditRepository       repository.AuditRepository
+		inputLoginAttemptRepo      repository.LoginAttemptRepository
+		inputTransactor            repository.Transactor
+		inputRevocationStore       repository.RevocationStore
+		inputPasswordHasher        hasher.PasswordHasher
+		inputRoles                 *permissions.Roles
+		inputTokenOptions          TokenOptions
+		inputLockoutOptions        LockoutOptions
+		expectedUserInterfactor    *userInteractor
 	}{
 		{
 			"userInterfactor successfully created ",
 			userRepoMock,
-			"hash_aslt",
-			[]byte("signing_key"),
-			1,
+			refreshTokenRepoMock,
+			auditRepoMock,
+			loginAttemptRepoMock,
+			transactorMock,
+			revocationStoreMock,
+			passwordHasher,
+			roles,
+			tokenOptions,
+			lockoutOptions,
 			&userInteractor{
-				userRepo:       userRepoMock,
-				hashSalt:       "hash_aslt",
-				signingKey:     []byte("signing_key"),
-				expireDuration: 1,
+				userRepo:              userRepoMock,
+				refreshTokenRepo:      refreshTokenRepoMock,
+				auditRepo:             auditRepoMock,
+				loginAttemptRepo:      loginAttemptRepoMock,
+				transactor:            transactorMock,
+				revocationStore:       revocationStoreMock,
+				passwordHasher:        passwordHasher,
+				roles:                 roles,
+				keySet:                newTestKeySet(t, "signing_key"),
+				issuer:                "issuer",
+				audience:              "audience",
+				expireDuration:        1,
+				refreshExpireDuration: 2,
+				userCache:             newUserCache(3 * time.Second),
+				lockout:               lockoutOptions,
 			},
 		},
 		{
 			"userRepository is absent",
 			nil,
-			"hash_aslt",
-			[]byte("signing_key"),
-			1,
+			refreshTokenRepoMock,
+			auditRepoMock,
+			loginAttemptRepoMock,
+			transactorMock,
+			revocationStoreMock,
+			passwordHasher,
+			roles,
+			tokenOptions,
+			lockoutOptions,
 			&userInteractor{
-				userRepo:       nil,
-				hashSalt:       "hash_aslt",
-				signingKey:     []byte("signing_key"),
-				expireDuration: 1,
+				userRepo:              nil,
+				refreshTokenRepo:      refreshTokenRepoMock,
+				auditRepo:             auditRepoMock,
+				loginAttemptRepo:      loginAttemptRepoMock,
+				transactor:            transactorMock,
+				revocationStore:       revocationStoreMock,
+				passwordHasher:        passwordHasher,
+				roles:                 roles,
+				keySet:                newTestKeySet(t, "signing_key"),
+				issuer:                "issuer",
+				audience:              "audience",
+				expireDuration:        1,
+				refreshExpireDuration: 2,
+				userCache:             newUserCache(3 * time.Second),
+				lockout:               lockoutOptions,
 			},
 		},
 	}
@@ -441,7 +664,8 @@ func TestNewUserInteractor(t *testing.T) {
 	for _, testCase := range testTable {
 		t.Run(testCase.scenario, func(t *testing.T) {
 
-			ui := NewUserInteractor(testCase.inputUserRepository, testCase.inputHashSalt, testCase.inputSigningKey, testCase.InputExpireDuration)
+			ui := NewUserInteractor(testCase.inputUserRepository, testCase.inputRefreshTokenRepostory, testCase.inputAuditRepository, testCase.inputLoginAttemptRepo,
+				testCase.inputTransactor, testCase.inputRevocationStore, testCase.inputPasswordHasher, testCase.inputRoles, testCase.inputTokenOptions, testCase.inputLockoutOptions, DeletionOptions{})
 			assert.Equal(t, ui, testCase.expectedUserInterfactor)
 
 		})
@@ -450,71 +674,6 @@ func TestNewUserInteractor(t *testing.T) {
 
 }
 
-func TestHashing(t *testing.T) {
-	ctrl := gomock.NewController(t)
-	defer ctrl.Finish()
-
-	userRepoMock := mocks.NewMockUserRepository(ctrl)
-	uInteractor := &userInteractor{
-		userRepo:       userRepoMock,
-		hashSalt:       "hash_aslt",
-		signingKey:     []byte("signing_key"),
-		expireDuration: 0,
-	}
-
-	testingHashingFunc := func(password string) string {
-		pwd := sha1.New()
-		pwd.Write([]byte(password))
-		pwd.Write([]byte(uInteractor.hashSalt))
-		return fmt.Sprintf("%x", pwd.Sum(nil))
-	}
-
-	testTable := []struct {
-		scenario               string
-		inputPassword          string
-		expectedHashedPassword string
-		expectedError          error
-	}{
-		{
-			"hashed password successfully created",
-			"password",
-			testingHashingFunc("password"),
-			nil,
-		},
-		{
-			"empty password field",
-			"",
-			testingHashingFunc(""),
-			errors.New("empty pasword field"),
-		},
-		{
-			"empty hash salt field",
-			"password",
-			testingHashingFunc("password"),
-			errors.New("empty hashSalt field"),
-		},
-	}
-
-	for _, testCase := range testTable {
-		t.Run(testCase.scenario, func(t *testing.T) {
-
-			if testCase.scenario == "empty hash salt field" {
-				uInteractor.hashSalt = ""
-			}
-
-			hashedPassword, err := uInteractor.hashing(testCase.inputPassword)
-			if err != nil {
-				assert.Equal(t, err, testCase.expectedError)
-				return
-			}
-
-			assert.Equal(t, hashedPassword, testCase.expectedHashedPassword)
-		})
-
-	}
-
-}
-
 func TestUpdateSignersByID(t *testing.T) {
 	now := time.Now()
 
@@ -562,14 +721,16 @@ func TestUpdateSignersByID(t *testing.T) {
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
 	uInteractor := &userInteractor{
 		userRepo:       userRepoMock,
-		hashSalt:       "",
-		signingKey:     nil,
+		auditRepo:      newTestAuditRepository(ctrl),
+		transactor:     newTestTransactor(ctrl),
+		passwordHasher: nil,
 		expireDuration: 0,
 	}
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			ctx := context.Background()
+			userRepoMock.EXPECT().FindOneUserByID(ctx, tc.expectedUser.ID).Return(&models.User{ID: tc.expectedUser.ID, UserName: "JohnHall"}, nil)
 			userRepoMock.EXPECT().UpdateUserByID(ctx, int(tc.expectedUser.ID), tc.expectedUser).Return(tc.expectedUser, tc.expectedError)
 			_, err := uInteractor.UpdateSignersByID(ctx, int(tc.expectedUser.ID), tc.expectedUser)
 			if err != nil {
@@ -633,14 +794,16 @@ func TestUpdateOwnSignIn(t *testing.T) {
 	userRepoMock := mocks.NewMockUserRepository(ctrl)
 	uInteractor := &userInteractor{
 		userRepo:       userRepoMock,
-		hashSalt:       "",
-		signingKey:     nil,
+		auditRepo:      newTestAuditRepository(ctrl),
+		transactor:     newTestTransactor(ctrl),
+		passwordHasher: newTestPasswordHasher(t),
 		expireDuration: 0,
 	}
 
 	for _, tc := range testTable {
 		t.Run(tc.scenario, func(t *testing.T) {
 			ctx := context.Background()
+			userRepoMock.EXPECT().FindOneUserByID(ctx, tc.expectedUser.ID).Return(&models.User{ID: tc.expectedUser.ID, UserName: "JohnHall"}, nil)
 			userRepoMock.EXPECT().UpdateOwnUser(ctx, int(tc.expectedUser.ID), tc.expectedUser).Return(tc.expectedUser, tc.expectedError)
 			_, err := uInteractor.UpdateOwnSignIn(ctx, int(tc.expectedUser.ID), tc.expectedUser)
 			if err != nil {
@@ -722,11 +885,13 @@ func TestRateSigner(t *testing.T) {
 			userRepoMock := mocks.NewMockUserRepository(ctrl)
 			uInteractor := &userInteractor{
 				userRepo:       userRepoMock,
-				hashSalt:       "",
-				signingKey:     nil,
+				auditRepo:      newTestAuditRepository(ctrl),
+				transactor:     newTestTransactor(ctrl),
+				passwordHasher: nil,
 				expireDuration: 0,
 			}
 
+			userRepoMock.EXPECT().FindOneUserByUserName(tt.args.ctx, tt.args.username).Return(&models.User{ID: 122, UserName: tt.args.username}, nil)
 			userRepoMock.EXPECT().RateUserByUsername(tt.args.ctx, tt.args.userWhoRatedID, tt.args.username, tt.args.rate).Return(tt.want, tt.wantErr)
 
 			_, err := uInteractor.RateUser(tt.args.ctx, tt.args.userWhoRatedID, tt.args.username, tt.args.rate)
@@ -742,3 +907,346 @@ func TestRateSigner(t *testing.T) {
 		})
 	}
 }
+
+func TestRateUserRejectsOwnUsernameInAnotherCase(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	ctx := context.Background()
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo:   userRepoMock,
+		auditRepo:  newTestAuditRepository(ctrl),
+		transactor: newTestTransactor(ctrl),
+	}
+
+	userRepoMock.EXPECT().FindOneUserByUserName(ctx, "johnhall").Return(&models.User{ID: 121, UserName: "JohnHall"}, nil)
+
+	_, err := uInteractor.RateUser(ctx, 121, "johnhall", "up")
+
+	assert.Equal(t, apperrors.Is(err, &apperrors.CanNotRateYorself), true)
+}
+
+func TestUserLookupFailures(t *testing.T) {
+	operations := []struct {
+		name   string
+		lookup func(userRepoMock *mocks.MockUserRepository, err error)
+		run    func(ctx context.Context, uInteractor *userInteractor) error
+	}{
+		{
+			"find",
+			func(userRepoMock *mocks.MockUserRepository, err error) {
+				userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), uint(121)).Return(nil, err)
+			},
+			func(ctx context.Context, uInteractor *userInteractor) error {
+				_, err := uInteractor.FindOneSigner(ctx, 121)
+				return err
+			},
+		},
+		{
+			"delete",
+			func(userRepoMock *mocks.MockUserRepository, err error) {
+				userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), uint(121)).Return(nil, err)
+			},
+			func(ctx context.Context, uInteractor *userInteractor) error {
+				return uInteractor.DeleteSignerByID(ctx, 121)
+			},
+		},
+		{
+			"update",
+			func(userRepoMock *mocks.MockUserRepository, err error) {
+				userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), uint(121)).Return(nil, err)
+			},
+			func(ctx context.Context, uInteractor *userInteractor) error {
+				_, err := uInteractor.UpdateSignersByID(ctx, 121, &models.User{FirstName: "Jack"})
+				return err
+			},
+		},
+		{
+			"rate",
+			func(userRepoMock *mocks.MockUserRepository, err error) {
+				userRepoMock.EXPECT().FindOneUserByUserName(gomock.Any(), "JohnHall").Return(nil, err)
+			},
+			func(ctx context.Context, uInteractor *userInteractor) error {
+				_, err := uInteractor.RateUser(ctx, 1, "JohnHall", "up")
+				return err
+			},
+		},
+		{
+			"assign role",
+			func(userRepoMock *mocks.MockUserRepository, err error) {
+				userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), uint(121)).Return(nil, err)
+			},
+			func(ctx context.Context, uInteractor *userInteractor) error {
+				_, err := uInteractor.AssignRole(ctx, 1, 121, permissions.ModeratorRole)
+				return err
+			},
+		},
+		{
+			"unlock",
+			func(userRepoMock *mocks.MockUserRepository, err error) {
+				userRepoMock.EXPECT().FindOneUserByID(gomock.Any(), uint(121)).Return(nil, err)
+			},
+			func(ctx context.Context, uInteractor *userInteractor) error {
+				return uInteractor.UnlockUser(ctx, 121)
+			},
+		},
+	}
+
+	failures := []struct {
+		name          string
+		lookupError   error
+		expectedError *apperrors.AppError
+		httpCode      int
+	}{
+		{"user is missing", apperrors.UserNotFoundErr.AppendMessage(errors.New("record not found")), &apperrors.UserNotFoundErr, http.StatusBadRequest},
+		{"database is down", errors.New("connection refused"), &apperrors.DatabaseErr, http.StatusInternalServerError},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, operation := range operations {
+		for _, failure := range failures {
+			t.Run(operation.name+": "+failure.name, func(t *testing.T) {
+				ctx := context.Background()
+				userRepoMock := mocks.NewMockUserRepository(ctrl)
+				uInteractor := &userInteractor{
+					userRepo:   userRepoMock,
+					transactor: newTestTransactor(ctrl),
+					roles:      permissions.DefaultRoles(),
+					userCache:  newUserCache(0),
+				}
+
+				operation.lookup(userRepoMock, failure.lookupError)
+
+				err := operation.run(ctx, uInteractor)
+				if !apperrors.Is(err, failure.expectedError) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				assert.Equal(t, err.(*apperrors.AppError).HTTPCode, failure.httpCode)
+
+				// the outer sentinel matches the cause, which stays in the chain
+				assert.Equal(t, errors.Is(err, failure.lookupError), true)
+				assert.Equal(t, errors.Is(err, &apperrors.UserNotFoundErr), failure.expectedError == &apperrors.UserNotFoundErr)
+				for _, unrelated := range []*apperrors.AppError{&apperrors.CanNotDeleteUserErr, &apperrors.CanNotUpdateErr, &apperrors.CanNotAssignRoleErr, &apperrors.CanNotUnlockUserErr} {
+					assert.Equal(t, errors.Is(err, unrelated), false, unrelated.Code)
+				}
+			})
+		}
+	}
+}
+
+func TestAssignRole(t *testing.T) {
+	user := &models.User{ID: 121, UserName: "JohnHall", Role: "user"}
+
+	testTable := []struct {
+		scenario      string
+		actorID       uint
+		userID        uint
+		role          string
+		repoError     error
+		expectedError error
+	}{
+		{"role successfully assigned", 1, 121, "moderator", nil, nil},
+		{"admin tries to change own role", 121, 121, "user", nil, &apperrors.CanNotChangeOwnRoleErr},
+		{"unknown role", 1, 121, "superadmin", nil, &apperrors.UnknownRoleErr},
+		{"role can't be updated", 1, 121, "moderator", errors.New("db is down"), &apperrors.CanNotAssignRoleErr},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:   userRepoMock,
+				auditRepo:  auditRepoMock,
+				transactor: newTestTransactor(ctrl),
+				roles:      permissions.DefaultRoles(),
+				userCache:  newUserCache(time.Minute),
+			}
+			uInteractor.userCache.set(user, uInteractor.userCache.generation(user.ID))
+
+			if testCase.actorID != testCase.userID && testCase.role != "superadmin" {
+				userRepoMock.EXPECT().FindOneUserByID(ctx, testCase.userID).Return(user, nil)
+				userRepoMock.EXPECT().UpdateUserRole(ctx, testCase.userID, testCase.role).Return(testCase.repoError)
+			}
+			if testCase.expectedError == nil {
+				auditRepoMock.EXPECT().CreateAuditEvent(ctx, &models.AuditEvent{
+					TargetID: testCase.userID,
+					Action:   models.AuditActionRoleAssign,
+					Changes:  models.AuditChanges{"role": {From: "user", To: testCase.role}},
+				}).Return(nil)
+			}
+
+			assignedUser, err := uInteractor.AssignRole(ctx, testCase.actorID, testCase.userID, testCase.role)
+			if err != nil {
+				if testCase.expectedError != nil && apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)) {
+					return
+				}
+				t.Fatal(err)
+			}
+			if testCase.expectedError != nil {
+				t.Fatalf("expected error %v", testCase.expectedError)
+			}
+
+			assert.Equal(t, assignedUser.Role, testCase.role)
+			_, cached := uInteractor.userCache.get(user.ID)
+			assert.Equal(t, cached, false, "the cached user must be dropped after a role change")
+		})
+	}
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	testTable := []struct {
+		scenario      string
+		adminCount    int64
+		countError    error
+		createAdmin   bool
+		expectedError error
+	}{
+		{"first admin is created", 0, nil, true, nil},
+		{"admin already exists", 1, nil, false, nil},
+		{"can't count admins", 0, errors.New("db is down"), false, &apperrors.CanNotBootstrapAdminErr},
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			ctx := context.Background()
+			userRepoMock := mocks.NewMockUserRepository(ctrl)
+			uInteractor := &userInteractor{
+				userRepo:       userRepoMock,
+				auditRepo:      newTestAuditRepository(ctrl),
+				transactor:     newTestTransactor(ctrl),
+				passwordHasher: newTestPasswordHasher(t),
+				roles:          permissions.DefaultRoles(),
+			}
+
+			userRepoMock.EXPECT().CountUsersByRole(ctx, permissions.AdminRole).Return(testCase.adminCount, testCase.countError)
+			if testCase.createAdmin {
+				userRepoMock.EXPECT().CreateUser(ctx, gomock.Any()).DoAndReturn(func(ctx context.Context, user *models.User) (*models.User, error) {
+					assert.Equal(t, user.UserName, "root")
+					assert.Equal(t, user.Role, permissions.AdminRole)
+					ok, err := uInteractor.passwordHasher.Verify("very12difficult()Password", user.Password)
+					assert.Equal(t, err, nil)
+					assert.Equal(t, ok, true)
+					return user, nil
+				})
+			}
+
+			err := uInteractor.BootstrapAdmin(ctx, "root", "very12difficult()Password")
+			if err != nil {
+				if testCase.expectedError != nil && apperrors.Is(err, testCase.expectedError.(*apperrors.AppError)) {
+					return
+				}
+				t.Fatal(err)
+			}
+			if testCase.expectedError != nil {
+				t.Fatalf("expected error %v", testCase.expectedError)
+			}
+		})
+	}
+}
+
+// newTestTransactor runs the transaction function right away.
+func newTestTransactor(ctrl *gomock.Controller) repository.Transactor {
+	transactorMock := mocks.NewMockTransactor(ctrl)
+	transactorMock.EXPECT().WithinTransaction(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		}).AnyTimes()
+	return transactorMock
+}
+
+func newTestAuditRepository(ctrl *gomock.Controller) repository.AuditRepository {
+	auditRepoMock := mocks.NewMockAuditRepository(ctrl)
+	auditRepoMock.EXPECT().CreateAuditEvent(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+	return auditRepoMock
+}
+
+func newTestPasswordHasher(t *testing.T) hasher.PasswordHasher {
+	passwordHasher, err := hasher.NewPasswordHasher(hasher.BcryptAlgorithm, "hash_salt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return passwordHasher
+}
+
+func newTestKeySet(t *testing.T, secret string) *signer.KeySet {
+	keySet, err := signer.NewKeySet(signer.NewHMACKey("test", []byte(secret)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keySet
+}
+
+func legacyHashingFunc(password, salt string) string {
+	pwd := sha1.New()
+	pwd.Write([]byte(password))
+	pwd.Write([]byte(salt))
+	return fmt.Sprintf("%x", pwd.Sum(nil))
+}
+
+func TestFindSignersByCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userRepoMock := mocks.NewMockUserRepository(ctrl)
+	uInteractor := &userInteractor{
+		userRepo: userRepoMock,
+		cursors:  cursorCodec{[]byte("cursor key")},
+	}
+	ctx := context.Background()
+	filter := &models.UserFilter{}
+
+	cursor := &models.Cursor{Sort: "rating desc", Values: []string{"3"}, ID: 7}
+	token, err := uInteractor.cursors.encode(cursor)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("the sort of the cursor", func(t *testing.T) {
+		next := &models.Cursor{Sort: "rating desc", Values: []string{"1"}, ID: 9}
+		userRepoMock.EXPECT().FindUsers(ctx, filter, gomock.Any()).DoAndReturn(
+			func(_ context.Context, _ *models.UserFilter, pagination *models.Pagination) (*models.Pagination, []*models.User, error) {
+				assert.Equal(t, pagination.Sort, "rating desc")
+				assert.Equal(t, pagination.Keyset, cursor)
+				pagination.NextKeyset = next
+				return pagination, nil, nil
+			})
+
+		pagination, _, err := uInteractor.FindSigners(ctx, filter, &models.Pagination{Limit: 5, Cursor: token})
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := uInteractor.cursors.decode(pagination.NextCursor)
+		assert.Equal(t, err, nil)
+		assert.Equal(t, decoded, next)
+		assert.Equal(t, pagination.PrevCursor, "")
+	})
+
+	for _, testCase := range []struct {
+		scenario   string
+		pagination *models.Pagination
+	}{
+		{"changed cursor", &models.Pagination{Limit: 5, Cursor: "e30" + token[strings.Index(token, "."):]}},
+		{"another key", &models.Pagination{Limit: 5, Cursor: func() string {
+			token, _ := cursorCodec{[]byte("another key")}.encode(cursor)
+			return token
+		}()}},
+		{"not a cursor", &models.Pagination{Limit: 5, Cursor: "page-2"}},
+		{"another sort", &models.Pagination{Limit: 5, Cursor: token, Sort: "id asc"}},
+	} {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			_, _, err := uInteractor.FindSigners(ctx, filter, testCase.pagination)
+			assert.Equal(t, apperrors.Is(err, &apperrors.InvalidCursorErr), true)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+)
+
+// Rule is a token bucket: up to Capacity attempts at once and one more
+// every RefillEvery. A zero Capacity disables the limit.
+type Rule struct {
+	Capacity    int
+	RefillEvery time.Duration
+}
+
+// PerMinute allows n attempts in a burst, refilled evenly during a minute.
+func PerMinute(n int) Rule {
+	if n <= 0 {
+		return Rule{}
+	}
+	return Rule{Capacity: n, RefillEvery: time.Minute / time.Duration(n)}
+}
+
+// Limiter applies one Rule to many keys (IPs, usernames) kept in the store.
+// A nil Limiter allows everything.
+type Limiter struct {
+	store  repository.RateLimitStore
+	rule   Rule
+	prefix string
+}
+
+// NewLimiter creates a Limiter, prefix separates its keys from the keys of
+// other limiters in the same store.
+func NewLimiter(store repository.RateLimitStore, prefix string, rule Rule) *Limiter {
+	return &Limiter{store: store, rule: rule, prefix: prefix}
+}
+
+// Allow takes an attempt for key. When no attempts are left it returns how
+// long to wait before the next one.
+func (l *Limiter) Allow(ctx context.Context, key string) (time.Duration, error) {
+	if l == nil || l.rule.Capacity <= 0 {
+		return 0, nil
+	}
+	return l.store.TakeToken(ctx, l.prefix+":"+key, l.rule.Capacity, l.rule.RefillEvery)
+}
+
+// Limit is the number of attempts allowed at once.
+func (l *Limiter) Limit() int {
+	if l == nil {
+		return 0
+	}
+	return l.rule.Capacity
+}
+
+// Reset gives all attempts back to key.
+func (l *Limiter) Reset(ctx context.Context, key string) error {
+	if l == nil || l.rule.Capacity <= 0 {
+		return nil
+	}
+	return l.store.ResetBucket(ctx, l.prefix+":"+key)
+}
@@ -0,0 +1,68 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.foxminded.com.ua/3_REST_API/interal/interface/repository"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewInMemoryRateLimitStore()
+	ipLimiter := NewLimiter(store, "ip", Rule{Capacity: 2, RefillEvery: time.Minute})
+	userLimiter := NewLimiter(store, "user", Rule{Capacity: 1, RefillEvery: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		wait, err := ipLimiter.Allow(ctx, "127.0.0.1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, wait, time.Duration(0))
+	}
+
+	wait, err := ipLimiter.Allow(ctx, "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, wait > 0 && wait <= time.Minute, true)
+
+	wait, err = userLimiter.Allow(ctx, "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, wait, time.Duration(0), "limiters with other prefixes don't share buckets")
+
+	if err := ipLimiter.Reset(ctx, "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	wait, err = ipLimiter.Allow(ctx, "127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, wait, time.Duration(0))
+}
+
+func TestDisabledLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	var nilLimiter *Limiter
+	disabled := NewLimiter(repository.NewInMemoryRateLimitStore(), "ip", PerMinute(0))
+
+	for i := 0; i < 100; i++ {
+		for _, limiter := range []*Limiter{nilLimiter, disabled} {
+			wait, err := limiter.Allow(ctx, "127.0.0.1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, wait, time.Duration(0))
+		}
+	}
+}
+
+func TestPerMinute(t *testing.T) {
+	assert.Equal(t, PerMinute(20), Rule{Capacity: 20, RefillEvery: 3 * time.Second})
+	assert.Equal(t, PerMinute(-1), Rule{})
+}
@@ -0,0 +1,91 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Key is a single JWT key. SigningKey is nil for keys which are only kept to
+// verify tokens signed before a key rotation.
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	SigningKey interface{}
+	VerifyKey  interface{}
+}
+
+func NewHMACKey(id string, secret []byte) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodHS256, SigningKey: secret, VerifyKey: secret}
+}
+
+func NewRSAKey(id string, privateKey *rsa.PrivateKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodRS256, SigningKey: privateKey, VerifyKey: &privateKey.PublicKey}
+}
+
+func NewRSAPublicKey(id string, publicKey *rsa.PublicKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodRS256, VerifyKey: publicKey}
+}
+
+func NewEdDSAKey(id string, privateKey ed25519.PrivateKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodEdDSA, SigningKey: privateKey, VerifyKey: privateKey.Public()}
+}
+
+func NewEdDSAPublicKey(id string, publicKey ed25519.PublicKey) *Key {
+	return &Key{ID: id, Method: jwt.SigningMethodEdDSA, VerifyKey: publicKey}
+}
+
+// KeySet signs tokens with its active key and verifies tokens signed by any of
+// its keys, which is what allows to rotate keys: a new key becomes active and
+// the previous one stays in the set until all its tokens are expired.
+type KeySet struct {
+	active *Key
+	keys   map[string]*Key
+}
+
+func NewKeySet(active *Key, verificationKeys ...*Key) (*KeySet, error) {
+	if active == nil || active.SigningKey == nil {
+		return nil, errors.New("active key must be able to sign tokens")
+	}
+
+	keySet := &KeySet{active: active, keys: map[string]*Key{}}
+	for _, key := range append([]*Key{active}, verificationKeys...) {
+		if _, ok := keySet.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %q", key.ID)
+		}
+		keySet.keys[key.ID] = key
+	}
+
+	return keySet, nil
+}
+
+// Sign signs the claims with the active key and puts its id into the kid header.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	if ks.active.ID != "" {
+		token.Header["kid"] = ks.active.ID
+	}
+
+	return token.SignedString(ks.active.SigningKey)
+}
+
+// Keyfunc is a jwt.Keyfunc which picks the verification key by the kid header.
+// Tokens without kid (issued before kid was introduced) are checked with the
+// active key.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key := ks.active
+	if kid, ok := token.Header["kid"].(string); ok {
+		if key, ok = ks.keys[kid]; !ok {
+			return nil, fmt.Errorf("unexpected jwt key id=%v", kid)
+		}
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected jwt signing method=%v", token.Header["alg"])
+	}
+
+	return key.VerifyKey, nil
+}
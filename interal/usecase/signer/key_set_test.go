@@ -0,0 +1,197 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/magiconair/properties/assert"
+)
+
+func TestKeySetSignAndVerify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testTable := []struct {
+		scenario    string
+		key         *Key
+		expectedAlg string
+	}{
+		{"HS256 key", NewHMACKey("hmac", []byte("signing_key")), HS256},
+		{"RS256 key", NewRSAKey("rsa", rsaKey), RS256},
+		{"EdDSA key", NewEdDSAKey("ed", edKey), EdDSA},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			keySet, err := NewKeySet(testCase.key)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			tokenString, err := keySet.Sign(jwt.RegisteredClaims{Subject: "1"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, keySet.Keyfunc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, token.Header["kid"], testCase.key.ID)
+			assert.Equal(t, token.Method.Alg(), testCase.expectedAlg)
+		})
+	}
+}
+
+func TestKeySetRotation(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldKeySet, err := NewKeySet(NewEdDSAKey("old", oldKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := oldKeySet.Sign(jwt.RegisteredClaims{Subject: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotatedKeySet, err := NewKeySet(NewEdDSAKey("new", newKey), NewEdDSAPublicKey("old", oldKey.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.Parse(oldToken, rotatedKeySet.Keyfunc)
+	assert.Equal(t, err, nil, "token of the previous key must stay valid")
+
+	newKeySet, err := NewKeySet(NewEdDSAKey("new", newKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = jwt.Parse(oldToken, newKeySet.Keyfunc)
+	assert.Equal(t, err != nil, true, "token of a removed key must be rejected")
+
+	jwks := rotatedKeySet.JWKS()
+	assert.Equal(t, len(jwks.Keys), 2)
+	assert.Equal(t, jwks.Keys[0].Kid, "new")
+	assert.Equal(t, jwks.Keys[0].Kty, "OKP")
+	assert.Equal(t, jwks.Keys[1].Kid, "old")
+}
+
+func TestKeySetRejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet, err := NewKeySet(NewRSAKey("rsa", rsaKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forgedToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "1"})
+	forgedToken.Header["kid"] = "rsa"
+	tokenString, err := forgedToken.SignedString(publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = jwt.Parse(tokenString, keySet.Keyfunc)
+	assert.Equal(t, err != nil, true)
+}
+
+func TestJWKSHidesHMACSecret(t *testing.T) {
+	keySet, err := NewKeySet(NewHMACKey("hmac", []byte("signing_key")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, len(keySet.JWKS().Keys), 0)
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPrivateFile := writePEM(t, dir, "rsa.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	edPublicKey, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPrivateDER, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPrivateFile := writePEM(t, dir, "ed.pem", "PRIVATE KEY", edPrivateDER)
+	edPublicDER, err := x509.MarshalPKIXPublicKey(edPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edPublicFile := writePEM(t, dir, "ed.pub.pem", "PUBLIC KEY", edPublicDER)
+
+	testTable := []struct {
+		scenario         string
+		method           string
+		keyID            string
+		secret           string
+		privateKeyFile   string
+		verificationKeys string
+		expectedJWKS     int
+		expectedError    bool
+	}{
+		{"HS256 secret", HS256, "", "signing_key", "", "", 0, false},
+		{"HS256 without secret", HS256, "", "", "", "", 0, true},
+		{"RS256 key with previous EdDSA key", RS256, "rsa-2", "", rsaPrivateFile, "ed-1:" + edPublicFile, 2, false},
+		{"EdDSA key", EdDSA, "ed-1", "", edPrivateFile, "", 1, false},
+		{"key type doesn't match method", EdDSA, "ed-1", "", rsaPrivateFile, "", 0, true},
+		{"missing key file", RS256, "rsa-2", "", filepath.Join(dir, "missing.pem"), "", 0, true},
+		{"malformed verification keys", RS256, "rsa-2", "", rsaPrivateFile, edPublicFile, 0, true},
+		{"unknown method", "ES256", "es", "", "", "", 0, true},
+	}
+
+	for _, testCase := range testTable {
+		t.Run(testCase.scenario, func(t *testing.T) {
+			keySet, err := LoadKeySet(testCase.method, testCase.keyID, testCase.secret, testCase.privateKeyFile, testCase.verificationKeys)
+			if testCase.expectedError {
+				assert.Equal(t, err != nil, true)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, len(keySet.JWKS().Keys), testCase.expectedJWKS)
+		})
+	}
+}
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
@@ -0,0 +1,139 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+	EdDSA = "EdDSA"
+)
+
+// LoadKeySet builds a KeySet from configuration. For HS256 the secret is used,
+// for RS256 and EdDSA the active private key is read from a PEM file.
+// verificationKeys is a comma separated list of "kid:path/to/public.pem" of
+// the previous keys which must still be accepted.
+func LoadKeySet(method, keyID, secret, privateKeyFile, verificationKeys string) (*KeySet, error) {
+	var active *Key
+	switch method {
+	case HS256, "":
+		if secret == "" {
+			return nil, errors.New("empty signing key")
+		}
+		active = NewHMACKey(keyID, []byte(secret))
+	case RS256, EdDSA:
+		if keyID == "" {
+			return nil, errors.New("empty key id")
+		}
+
+		privateKey, err := readPrivateKey(privateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		switch key := privateKey.(type) {
+		case *rsa.PrivateKey:
+			if method != RS256 {
+				return nil, fmt.Errorf("%s key expected in %s", method, privateKeyFile)
+			}
+			active = NewRSAKey(keyID, key)
+		case ed25519.PrivateKey:
+			if method != EdDSA {
+				return nil, fmt.Errorf("%s key expected in %s", method, privateKeyFile)
+			}
+			active = NewEdDSAKey(keyID, key)
+		default:
+			return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+		}
+	default:
+		return nil, fmt.Errorf("unknown signing method %q", method)
+	}
+
+	var keys []*Key
+	for _, entry := range strings.Split(verificationKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, path, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("verification key %q must look like kid:path", entry)
+		}
+
+		key, err := readPublicKey(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return NewKeySet(active, keys...)
+}
+
+func readPrivateKey(path string) (interface{}, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+func readPublicKey(kid, path string) (*Key, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicKey interface{}
+	switch block.Type {
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		publicKey = cert.PublicKey
+	case "RSA PUBLIC KEY":
+		if publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	default:
+		if publicKey, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	}
+
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		return NewRSAPublicKey(kid, key), nil
+	case ed25519.PublicKey:
+		return NewEdDSAPublicKey(kid, key), nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T in %s", publicKey, path)
+	}
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+	return block, nil
+}
@@ -1,20 +1,32 @@
 package validator
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"regexp"
+	"strings"
 
 	"git.foxminded.com.ua/3_REST_API/interal/apperrors"
+	"git.foxminded.com.ua/3_REST_API/interal/domain/permissions"
 	"github.com/go-playground/validator/v10"
 )
 
 type CustomValidator struct {
-	Validator *validator.Validate
+	validator *validator.Validate
+	roles     *permissions.Roles
 }
 
-func (cv *CustomValidator) Validate(i interface{}) error {
+// NewCustomValidator registers the custom rules once, Validate only reads
+// them and is safe for concurrent use. The default roles are used for the
+// "role" tag when roles is nil.
+func NewCustomValidator(roles *permissions.Roles) (*CustomValidator, error) {
+	if roles == nil {
+		roles = permissions.DefaultRoles()
+	}
+	validate := validator.New()
 
-	err := cv.Validator.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+	err := validate.RegisterValidation("password", func(fl validator.FieldLevel) bool {
 		for _, test := range []string{`.{7,}`, `[\p{Lu}]`, `[\p{Ll}]`, "[0-9]", `[^\\d\\w]`} {
 			t, _ := regexp.MatchString(test, fl.Field().String())
 			if !t {
@@ -24,12 +36,64 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 		return true
 	})
 	if err != nil {
-		return apperrors.ValidatorInitializeErr.AppendMessage(err)
+		return nil, apperrors.ValidatorInitializeErr.AppendMessage(err)
 	}
 
-	if err := cv.Validator.Struct(i); err != nil {
-		return apperrors.ValidatorErr.AppendMessage(
-			fmt.Errorf("password must containe at least 7 letters,  1 number, 1 upper case, 1 special character.  err: %v", err))
+	err = validate.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+		return roles.Exists(fl.Field().String())
+	})
+	if err != nil {
+		return nil, apperrors.ValidatorInitializeErr.AppendMessage(err)
+	}
+
+	validate.RegisterTagNameFunc(jsonFieldName)
+
+	return &CustomValidator{validator: validate, roles: roles}, nil
+}
+
+func (cv *CustomValidator) Validate(i interface{}) error {
+	if err := cv.validator.Struct(i); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return apperrors.ValidatorErr.AppendMessage(err)
+		}
+
+		fields := make([]apperrors.FieldError, 0, len(validationErrs))
+		for _, validationErr := range validationErrs {
+			fields = append(fields, apperrors.FieldError{
+				Field:   validationErr.Field(),
+				Rule:    validationErr.Tag(),
+				Param:   validationErr.Param(),
+				Message: fieldErrorMessage(validationErr, cv.roles),
+			})
+		}
+		return apperrors.ValidatorErr.WithFields(fields...).AppendMessage(err)
 	}
 	return nil
 }
+
+// jsonFieldName names fields as the client sends them.
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+func fieldErrorMessage(validationErr validator.FieldError, roles *permissions.Roles) string {
+	switch validationErr.Tag() {
+	case "required":
+		return "the field is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters long", validationErr.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters long", validationErr.Param())
+	case "password":
+		return "password must containe at least 7 letters,  1 number, 1 upper case, 1 special character"
+	case "role":
+		return fmt.Sprintf("unknown role %q, known roles: %v", validationErr.Value(), roles.Names())
+	default:
+		return fmt.Sprintf("doesn't satisfy the %q rule", validationErr.Tag())
+	}
+}